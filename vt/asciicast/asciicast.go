@@ -0,0 +1,159 @@
+// Package asciicast 以 asciicast v2 格式记录和重放虚拟终端会话。
+//
+// asciicast v2 文件的第一行是 JSON 格式的头部，其后每一行是一个事件，格式为
+// [时间, 类型, 数据]。参见 https://docs.asciinema.org/manual/asciicast/v2/。
+package asciicast
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Version 是支持的 asciicast 格式版本。
+const Version = 2
+
+// Header 是 asciicast 文件的头部。
+type Header struct {
+	// Version 是格式版本，总是 2。
+	Version int `json:"version"`
+	// Width 和 Height 是终端的初始列数和行数。
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Timestamp 是记录开始时间的 Unix 时间戳。
+	Timestamp int64 `json:"timestamp,omitempty"`
+	// IdleTimeLimit 是重放时事件之间的最大间隔秒数。零表示没有限制。
+	IdleTimeLimit float64 `json:"idle_time_limit,omitempty"`
+	// Title 是记录的标题。
+	Title string `json:"title,omitempty"`
+	// Env 是记录时的环境变量，例如 SHELL 和 TERM。
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// EventType 是事件的类型。
+type EventType string
+
+// 事件类型。
+const (
+	// OutputEvent 是写入终端的输出。
+	OutputEvent EventType = "o"
+	// InputEvent 是发送到终端输入的数据。
+	InputEvent EventType = "i"
+	// ResizeEvent 是终端大小的改变，数据的格式为 "列数x行数"。
+	ResizeEvent EventType = "r"
+	// MarkerEvent 是一个标记，数据是标记的标签。
+	MarkerEvent EventType = "m"
+)
+
+// Event 是记录中的一个事件。
+type Event struct {
+	// Time 是事件相对于记录开始的时间。
+	Time time.Duration
+	// Type 是事件的类型。
+	Type EventType
+	// Data 是事件的数据。
+	Data string
+}
+
+// MarshalJSON 将事件编码为 [时间, 类型, 数据] 数组。
+func (e Event) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	b := strconv.AppendFloat([]byte{'['}, e.Time.Seconds(), 'f', 6, 64)
+	b = append(b, `, "`...)
+	b = append(b, e.Type...)
+	b = append(b, `", `...)
+	b = append(b, data...)
+	return append(b, ']'), nil
+}
+
+// UnmarshalJSON 从 [时间, 类型, 数据] 数组解码事件。
+func (e *Event) UnmarshalJSON(b []byte) error {
+	var v [3]json.RawMessage
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err //nolint:wrapcheck
+	}
+	var secs float64
+	if err := json.Unmarshal(v[0], &secs); err != nil {
+		return fmt.Errorf("asciicast: invalid event time: %w", err)
+	}
+	if err := json.Unmarshal(v[1], &e.Type); err != nil {
+		return fmt.Errorf("asciicast: invalid event type: %w", err)
+	}
+	if err := json.Unmarshal(v[2], &e.Data); err != nil {
+		return fmt.Errorf("asciicast: invalid event data: %w", err)
+	}
+	e.Time = time.Duration(secs * float64(time.Second))
+	return nil
+}
+
+// Size 返回调整大小事件的列数和行数。对于其他类型的事件或无效的数据，
+// 返回 false。
+func (e Event) Size() (width, height int, ok bool) {
+	if e.Type != ResizeEvent {
+		return 0, 0, false
+	}
+	if _, err := fmt.Sscanf(e.Data, "%dx%d", &width, &height); err != nil {
+		return 0, 0, false
+	}
+	return width, height, true
+}
+
+// Cast 是一个解码后的 asciicast 记录。
+type Cast struct {
+	Header Header
+	Events []Event
+}
+
+// Duration 返回记录的时长，即最后一个事件的时间。
+func (c *Cast) Duration() time.Duration {
+	if len(c.Events) == 0 {
+		return 0
+	}
+	return c.Events[len(c.Events)-1].Time
+}
+
+// ErrUnsupportedVersion 在记录的格式版本不受支持时返回。
+var ErrUnsupportedVersion = errors.New("asciicast: unsupported version")
+
+// Decode 从 r 读取一个 asciicast v2 记录。
+func Decode(r io.Reader) (*Cast, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 64*1024*1024)
+
+	var c Cast
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			return nil, fmt.Errorf("asciicast: reading header: %w", err)
+		}
+		return nil, fmt.Errorf("asciicast: reading header: %w", io.ErrUnexpectedEOF)
+	}
+	if err := json.Unmarshal(sc.Bytes(), &c.Header); err != nil {
+		return nil, fmt.Errorf("asciicast: invalid header: %w", err)
+	}
+	if c.Header.Version != Version {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, c.Header.Version)
+	}
+
+	for line := 2; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var ev Event
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			return nil, fmt.Errorf("asciicast: line %d: %w", line, err)
+		}
+		c.Events = append(c.Events, ev)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("asciicast: reading events: %w", err)
+	}
+
+	return &c, nil
+}
@@ -0,0 +1,190 @@
+package asciicast
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/purpose168/charm-experimental-packages-cn/vt"
+)
+
+// setClock makes rec use a clock that starts at start and advances by step on
+// every event.
+func setClock(rec *Recorder, start time.Time, step time.Duration) {
+	rec.start = start
+	rec.now = func() time.Time {
+		start = start.Add(step)
+		return start
+	}
+}
+
+func TestRecordAndDecode(t *testing.T) {
+	var buf bytes.Buffer
+	rec, err := NewRecorder(&buf, Header{Width: 10, Height: 2, Title: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setClock(rec, time.Unix(1700000000, 0), time.Second)
+
+	emu := NewEmulator(vt.NewEmulator(10, 2), rec)
+	emu.RecordInput = true
+	go func() {
+		b := make([]byte, 32)
+		for {
+			if _, err := emu.Read(b); err != nil {
+				return
+			}
+		}
+	}()
+	defer emu.Close() //nolint:errcheck
+
+	_, _ = emu.WriteString("hello \"world\"")
+	emu.Resize(20, 3)
+	_, _ = emu.InputPipe().Write([]byte("ls\r"))
+	_ = rec.Marker("done")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		`[1.000000, "o", "hello \"world\""]`,
+		`[2.000000, "r", "20x3"]`,
+		`[3.000000, "i", "ls\r"]`,
+		`[4.000000, "m", "done"]`,
+	}
+	if len(lines) != len(want)+1 {
+		t.Fatalf("recorded %d lines, want %d:\n%s", len(lines), len(want)+1, buf.String())
+	}
+	if !strings.Contains(lines[0], `"version":2`) || !strings.Contains(lines[0], `"title":"test"`) {
+		t.Errorf("header = %s", lines[0])
+	}
+	for i, w := range want {
+		if lines[i+1] != w {
+			t.Errorf("event %d = %s, want %s", i, lines[i+1], w)
+		}
+	}
+
+	c, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Header.Width != 10 || c.Header.Height != 2 || len(c.Events) != 4 {
+		t.Fatalf("decoded %+v", c)
+	}
+	if ev := c.Events[1]; ev.Time != 2*time.Second {
+		t.Errorf("event time = %v", ev.Time)
+	}
+	if w, h, ok := c.Events[1].Size(); !ok || w != 20 || h != 3 {
+		t.Errorf("Size() = %d, %d, %v", w, h, ok)
+	}
+	if c.Duration() != 4*time.Second {
+		t.Errorf("Duration() = %v", c.Duration())
+	}
+}
+
+func TestDecodeUnsupportedVersion(t *testing.T) {
+	_, err := Decode(strings.NewReader(`{"version":1,"width":80,"height":24}`))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+const testCast = `{"version": 2, "width": 10, "height": 2}
+[0.5, "o", "one"]
+[1.0, "o", "\r\ntwo"]
+[1.5, "r", "12x2"]
+[2.0, "o", "\u001b[Hthree"]
+[2.5, "o", "\u001b[c"]
+`
+
+func TestPlayerSeek(t *testing.T) {
+	c, err := Decode(strings.NewReader(testCast))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewPlayer(c)
+	defer p.Close()
+
+	p.Seek(1200 * time.Millisecond)
+	if got, want := p.String(), "one\ntwo"; got != want {
+		t.Errorf("at 1.2s = %q, want %q", got, want)
+	}
+
+	p.Seek(3 * time.Second)
+	if got, want := p.String(), "three\ntwo"; got != want {
+		t.Errorf("at 3s = %q, want %q", got, want)
+	}
+	if w := p.Emulator().Width(); w != 12 {
+		t.Errorf("width after resize = %d", w)
+	}
+	if !p.Done() {
+		t.Error("expected all events to be applied")
+	}
+
+	// Seeking backwards replays from the start.
+	p.Seek(600 * time.Millisecond)
+	if got, want := p.String(), "one\n"; got != want {
+		t.Errorf("at 0.6s = %q, want %q", got, want)
+	}
+	if w := p.Emulator().Width(); w != 10 {
+		t.Errorf("width after seeking back = %d", w)
+	}
+}
+
+func TestPlayerPlay(t *testing.T) {
+	c, err := Decode(strings.NewReader(testCast))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewPlayer(c)
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.Play(ctx, 100); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.String(), "three\ntwo"; got != want {
+		t.Errorf("after Play = %q, want %q", got, want)
+	}
+
+	p.Seek(0)
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if err := p.Play(ctx, 1); err == nil {
+		t.Error("expected Play to stop when the context is canceled")
+	}
+}
+
+func TestRecordSplitUTF8(t *testing.T) {
+	var buf bytes.Buffer
+	rec, err := NewRecorder(&buf, Header{Width: 10, Height: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setClock(rec, time.Unix(1700000000, 0), time.Second)
+
+	// The characters are split across writes.
+	b := []byte("a你好")
+	_ = rec.Output(b[:2])
+	_ = rec.Output(b[2:6])
+	_ = rec.Output(b[6:])
+	// Incomplete sequences left at the end are flushed on close.
+	_ = rec.Output(b[1:2])
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, ev := range c.Events {
+		got = append(got, ev.Data)
+	}
+	if want := []string{"a", "你", "好", "\ufffd"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
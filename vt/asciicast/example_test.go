@@ -0,0 +1,30 @@
+package asciicast_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/purpose168/charm-experimental-packages-cn/vt/asciicast"
+)
+
+func ExamplePlayer_Play() {
+	c, err := asciicast.Decode(strings.NewReader(`{"version": 2, "width": 10, "height": 2}
+[0.1, "o", "hello"]
+[0.2, "o", "\r\nworld"]
+`))
+	if err != nil {
+		panic(err)
+	}
+
+	p := asciicast.NewPlayer(c)
+	defer p.Close() //nolint:errcheck
+
+	if err := p.Play(context.Background(), 100); err != nil {
+		panic(err)
+	}
+	fmt.Println(p.String())
+	// Output:
+	// hello
+	// world
+}
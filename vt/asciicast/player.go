@@ -0,0 +1,153 @@
+package asciicast
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/purpose168/charm-experimental-packages-cn/vt"
+)
+
+// Player 将记录重放到一个新的模拟器中。
+//
+// 输出和调整大小事件会应用到模拟器；输入和标记事件会被跳过。模拟器对查询的
+// 回复会被丢弃。Player 不能被多个 goroutine 同时使用。
+type Player struct {
+	cast *Cast
+	emu  *vt.Emulator
+	// next 是下一个要应用的事件的索引。
+	next int
+	// now 是当前的播放时间。
+	now time.Duration
+	// drained 在丢弃模拟器回复的 goroutine 退出时关闭。
+	drained chan struct{}
+}
+
+// NewPlayer 创建一个重放 c 的播放器。模拟器的初始大小来自记录的头部。使用
+// 完毕后必须调用 [Player.Close] 来释放模拟器和后台 goroutine。
+func NewPlayer(c *Cast) *Player {
+	p := &Player{cast: c}
+	p.reset()
+	return p
+}
+
+// Close 关闭播放器的模拟器，并等待丢弃模拟器回复的 goroutine 退出。
+func (p *Player) Close() error {
+	err := p.emu.Close()
+	<-p.drained
+	return err //nolint:wrapcheck
+}
+
+// Emulator 返回播放器的模拟器。在向后定位后，播放器会创建一个新的模拟器，
+// 因此不应保留返回的模拟器。
+func (p *Player) Emulator() *vt.Emulator {
+	return p.emu
+}
+
+// Time 返回当前的播放时间。
+func (p *Player) Time() time.Duration {
+	return p.now
+}
+
+// Done 报告是否已经应用了所有事件。
+func (p *Player) Done() bool {
+	return p.next >= len(p.cast.Events)
+}
+
+// Render 返回当前屏幕的渲染结果，参见 [vt.Emulator.Render]。
+func (p *Player) Render() string {
+	return p.emu.Render()
+}
+
+// String 返回当前屏幕的文本，参见 [vt.Emulator.String]。
+func (p *Player) String() string {
+	return p.emu.String()
+}
+
+// Step 应用下一个事件并返回它。如果没有更多的事件，则返回 false。
+func (p *Player) Step() (Event, bool) {
+	if p.Done() {
+		return Event{}, false
+	}
+	ev := p.cast.Events[p.next]
+	p.next++
+	p.now = max(p.now, ev.Time)
+	p.apply(ev)
+	return ev, true
+}
+
+// Seek 将播放位置移动到时间 t，立即应用 t 之前（包含）的所有事件。向后定位
+// 会从头开始重放记录。
+func (p *Player) Seek(t time.Duration) {
+	if t < p.now {
+		p.reset()
+	}
+	for !p.Done() && p.cast.Events[p.next].Time <= t {
+		p.Step()
+	}
+	p.now = max(t, 0)
+}
+
+// Play 从当前位置按记录的时间重放剩余的事件，直到结束或 ctx 被取消。speed
+// 是播放速度的倍数，例如 2 表示两倍速；小于或等于零的值表示 1。事件之间的
+// 间隔受头部的 IdleTimeLimit 限制。Play 返回后播放器仍然可用，不再需要时
+// 应调用 [Player.Close]。
+func (p *Player) Play(ctx context.Context, speed float64) error {
+	if speed <= 0 {
+		speed = 1
+	}
+	limit := time.Duration(p.cast.Header.IdleTimeLimit * float64(time.Second))
+
+	timer := time.NewTimer(0)
+	timer.Stop()
+	defer timer.Stop()
+
+	for !p.Done() {
+		delay := p.cast.Events[p.next].Time - p.now
+		if limit > 0 {
+			delay = min(delay, limit)
+		}
+		if delay > 0 {
+			timer.Reset(time.Duration(float64(delay) / speed))
+			select {
+			case <-ctx.Done():
+				return ctx.Err() //nolint:wrapcheck
+			case <-timer.C:
+			}
+		}
+		p.Step()
+	}
+	return nil
+}
+
+// apply 将一个事件应用到模拟器。
+func (p *Player) apply(ev Event) {
+	switch ev.Type {
+	case OutputEvent:
+		_, _ = p.emu.WriteString(ev.Data)
+	case ResizeEvent:
+		if w, h, ok := ev.Size(); ok {
+			p.emu.Resize(w, h)
+		}
+	}
+}
+
+// reset 创建一个新的模拟器并将播放位置移动到开头。
+func (p *Player) reset() {
+	if p.emu != nil {
+		// 关闭模拟器会结束读取，等待 goroutine 退出后再替换模拟器。
+		_ = p.emu.Close()
+		<-p.drained
+	}
+	hdr := p.cast.Header
+	p.emu = vt.NewEmulator(max(1, hdr.Width), max(1, hdr.Height))
+	p.next, p.now = 0, 0
+
+	// 丢弃模拟器对查询的回复，以免写入阻塞。
+	emu, drained := p.emu, make(chan struct{})
+	p.drained = drained
+	go func() {
+		defer close(drained)
+		_, _ = io.Copy(io.Discard, emu)
+	}()
+}
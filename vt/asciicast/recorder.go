@@ -0,0 +1,222 @@
+package asciicast
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/purpose168/charm-experimental-packages-cn/vt"
+)
+
+// Recorder 将事件以 asciicast v2 格式写入 io.Writer。它可以被多个 goroutine
+// 同时使用。
+type Recorder struct {
+	mu    sync.Mutex
+	w     io.Writer
+	start time.Time
+	err   error
+
+	// output 和 input 是上一次写入末尾不完整的 UTF-8 序列，它们会与下一次
+	// 写入的数据一起记录。
+	output, input []byte
+
+	// now 返回当前时间，用于测试。
+	now func() time.Time
+}
+
+// NewRecorder 创建一个记录器并将头部写入 w。头部的版本总是 2；如果时间戳为
+// 零，则使用当前时间。事件的时间相对于记录器的创建时间。
+func NewRecorder(w io.Writer, hdr Header) (*Recorder, error) {
+	r := &Recorder{w: w, now: time.Now}
+	r.start = r.now()
+
+	hdr.Version = Version
+	if hdr.Timestamp == 0 {
+		hdr.Timestamp = r.start.Unix()
+	}
+	b, err := json.Marshal(hdr)
+	if err != nil {
+		return nil, fmt.Errorf("asciicast: encoding header: %w", err)
+	}
+	if _, err := w.Write(append(b, '\n')); err != nil {
+		return nil, fmt.Errorf("asciicast: writing header: %w", err)
+	}
+
+	return r, nil
+}
+
+// Output 记录写入终端的输出。末尾不完整的 UTF-8 序列会被保留，与下一次的
+// 输出一起记录，因此被分成多次读取的字符不会损坏。
+func (r *Recorder) Output(data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.recordStream(OutputEvent, &r.output, data)
+}
+
+// Input 记录发送到终端输入的数据。与 [Recorder.Output] 一样，末尾不完整的
+// UTF-8 序列会与下一次的输入一起记录。
+func (r *Recorder) Input(data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.recordStream(InputEvent, &r.input, data)
+}
+
+// Resize 记录终端大小的改变。
+func (r *Recorder) Resize(width, height int) error {
+	return r.record(ResizeEvent, fmt.Sprintf("%dx%d", width, height))
+}
+
+// Marker 记录一个带有给定标签的标记。
+func (r *Recorder) Marker(label string) error {
+	return r.record(MarkerEvent, label)
+}
+
+// Err 返回写入事件时遇到的第一个错误。
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close 记录保留的不完整 UTF-8 序列，并返回写入事件时遇到的第一个错误。它
+// 不会关闭底层的 io.Writer。
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.output) > 0 {
+		r.writeEvent(OutputEvent, string(r.output)) //nolint:errcheck
+		r.output = nil
+	}
+	if len(r.input) > 0 {
+		r.writeEvent(InputEvent, string(r.input)) //nolint:errcheck
+		r.input = nil
+	}
+	return r.err
+}
+
+// record 写入一个事件。
+func (r *Recorder) record(typ EventType, data string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.writeEvent(typ, data)
+}
+
+// recordStream 将 pending 和 data 连接起来，记录其中完整的部分，并将末尾不
+// 完整的 UTF-8 序列保留在 pending 中。调用者必须持有 r.mu。
+func (r *Recorder) recordStream(typ EventType, pending *[]byte, data []byte) error {
+	buf := append(*pending, data...)
+	n := completeUTF8(buf)
+	*pending = append([]byte(nil), buf[n:]...)
+	if n == 0 {
+		return r.err
+	}
+	return r.writeEvent(typ, string(buf[:n]))
+}
+
+// completeUTF8 返回 p 去掉末尾不完整的 UTF-8 序列后的长度。无效的字节不算
+// 作不完整的序列。
+func completeUTF8(p []byte) int {
+	for i := len(p) - 1; i >= 0 && i > len(p)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(p[i]) {
+			if utf8.FullRune(p[i:]) {
+				break
+			}
+			return i
+		}
+	}
+	return len(p)
+}
+
+// writeEvent 写入一个事件。遇到错误后，之后的事件都会被丢弃。调用者必须持有
+// r.mu。
+func (r *Recorder) writeEvent(typ EventType, data string) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	ev := Event{Time: r.now().Sub(r.start), Type: typ, Data: data}
+	b, err := ev.MarshalJSON()
+	if err == nil {
+		_, err = r.w.Write(append(b, '\n'))
+	}
+	if err != nil {
+		r.err = fmt.Errorf("asciicast: writing event: %w", err)
+	}
+	return r.err
+}
+
+// Emulator 包装一个 [vt.Emulator]，并记录写入它的输出和大小的改变。如果
+// RecordInput 为 true，通过 [Emulator.InputPipe] 发送的输入也会被记录。
+type Emulator struct {
+	*vt.Emulator
+
+	// RecordInput 控制是否记录通过 InputPipe 发送的输入。
+	RecordInput bool
+
+	rec *Recorder
+}
+
+// NewEmulator 返回一个将 emu 的输出记录到 rec 的模拟器。
+func NewEmulator(emu *vt.Emulator, rec *Recorder) *Emulator {
+	return &Emulator{Emulator: emu, rec: rec}
+}
+
+// Recorder 返回模拟器使用的记录器。
+func (e *Emulator) Recorder() *Recorder {
+	return e.rec
+}
+
+// Write 将数据写入模拟器并记录实际写入的部分。
+func (e *Emulator) Write(p []byte) (int, error) {
+	n, err := e.Emulator.Write(p)
+	if n > 0 {
+		_ = e.rec.Output(p[:n])
+	}
+	return n, err //nolint:wrapcheck
+}
+
+// WriteString 将字符串写入模拟器并记录实际写入的部分。
+func (e *Emulator) WriteString(s string) (int, error) {
+	return e.Write([]byte(s))
+}
+
+// Close 关闭模拟器，并记录记录器中保留的不完整 UTF-8 序列。
+func (e *Emulator) Close() error {
+	err := e.Emulator.Close()
+	if rerr := e.rec.Close(); err == nil {
+		err = rerr
+	}
+	return err //nolint:wrapcheck
+}
+
+// Resize 调整模拟器的大小并记录一个调整大小事件。
+func (e *Emulator) Resize(width, height int) {
+	e.Emulator.Resize(width, height)
+	_ = e.rec.Resize(width, height)
+}
+
+// InputPipe 返回模拟器的输入管道。当 RecordInput 为 true 时，写入的数据也会
+// 被记录为输入事件。
+func (e *Emulator) InputPipe() io.Writer {
+	if !e.RecordInput {
+		return e.Emulator.InputPipe()
+	}
+	return inputWriter{e}
+}
+
+// inputWriter 将数据写入模拟器的输入管道并记录它。
+type inputWriter struct {
+	e *Emulator
+}
+
+// Write 实现 io.Writer。
+func (w inputWriter) Write(p []byte) (int, error) {
+	n, err := w.e.Emulator.InputPipe().Write(p)
+	if n > 0 {
+		_ = w.e.rec.Input(p[:n])
+	}
+	return n, err //nolint:wrapcheck
+}
@@ -47,4 +47,23 @@ type Callbacks struct {
 
 	// DisableMode 回调。当设置时，此函数在模式禁用时被调用。
 	DisableMode func(mode ansi.Mode)
+
+	// Damage 回调。当设置时，此函数在每次写入后以发生的顺序被调用，每个
+	// 合并后的损坏调用一次。参见 [Emulator.DrainDamage]。
+	Damage func(d Damage)
+
+	// Notify 回调。当设置时，此函数在应用程序通过 OSC 9、OSC 777 或 OSC 99
+	// 请求桌面通知时被调用。
+	Notify func(n Notification)
+
+	// CloseNotification 回调。当设置时，此函数在应用程序通过 OSC 99 请求关闭
+	// 给定 ID 的通知时被调用。
+	CloseNotification func(id string)
+
+	// Progress 回调。当设置时，此函数在应用程序通过 OSC 9;4 报告进度时被调用。
+	Progress func(p Progress)
+
+	// Clipboard 是 OSC 52 使用的剪贴板后端。当设置时，应用程序可以按照
+	// [ClipboardPolicy] 读写剪贴板。
+	Clipboard Clipboard
 }
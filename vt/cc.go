@@ -25,9 +25,8 @@ func (e *Emulator) linefeed() {
 func (e *Emulator) index() {
 	x, y := e.scr.CursorPosition()
 	scroll := e.scr.ScrollRegion()
-	// XXX: 当我们添加滚动回退时处理它。
 	if y == scroll.Max.Y-1 && x >= scroll.Min.X && x < scroll.Max.X {
-		e.scr.ScrollUp(1)
+		e.scrollUp(1)
 	} else if y < scroll.Max.Y-1 || !uv.Pos(x, y).In(scroll) {
 		e.scr.moveCursor(0, 1)
 	}
@@ -45,7 +44,7 @@ func (e *Emulator) reverseIndex() {
 	x, y := e.scr.CursorPosition()
 	scroll := e.scr.ScrollRegion()
 	if y == scroll.Min.Y && x >= scroll.Min.X && x < scroll.Max.X {
-		e.scr.ScrollDown(1)
+		e.scrollDown(1)
 	} else {
 		e.scr.moveCursor(0, -1)
 	}
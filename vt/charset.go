@@ -1,13 +1,18 @@
 package vt
 
+import "maps"
+
 // CharSet 表示字符集指示符。
 // 这可以用于为 G0 或 G1 等选择字符集。
+//
+// 键是字符在 GL 中的位置（0x20 到 0x7F）。调用到 GR 的字符集使用相同的键，
+// 即 GR 中的字符减去 0x80。
 type CharSet map[byte]string
 
 // 字符集。
 var (
 	UK = CharSet{
-		'$': "£", // U+00A3 英镑符号
+		'#': "£", // U+00A3 英镑符号
 	}
 	SpecialDrawing = CharSet{
 		'`': "◆", // U+25C6 黑色钻石
@@ -42,4 +47,172 @@ var (
 		'}': "£", // U+00A3 英镑符号
 		'~': "·", // U+00B7 中点
 	}
+
+	// DECSupplemental 是 DEC 补充图形字符集。它与 ISO Latin-1 补充字符集基本
+	// 相同，但有少数字符不同，一些位置未定义。
+	DECSupplemental = func() CharSet {
+		cs := latin1Range(0x21, 0x7e)
+		for _, c := range []byte{0x24, 0x26, 0x2c, 0x2d, 0x2e, 0x2f, 0x34, 0x38, 0x3e, 0x50, 0x5e, 0x70, 0x7e} {
+			delete(cs, c) // 未定义
+		}
+		cs[0x28] = "¤" // U+00A4 货币符号
+		cs[0x57] = "Œ" // U+0152 拉丁大写连字 OE
+		cs[0x5d] = "Ÿ" // U+0178 带分音符的拉丁大写字母 Y
+		cs[0x77] = "œ" // U+0153 拉丁小写连字 oe
+		cs[0x7d] = "ÿ" // U+00FF 带分音符的拉丁小写字母 y
+		return cs
+	}()
+
+	// DECTechnical 是 DEC 技术字符集，包含数学符号、希腊字母和用于拼接大符号
+	// 的片段。
+	DECTechnical = CharSet{
+		'!': "⎷", '"': "┌", '#': "─", '$': "⌠", '%': "⌡", '&': "│", '\'': "⎡",
+		'(': "⎣", ')': "⎤", '*': "⎦", '+': "⎧", ',': "⎩", '-': "⎫", '.': "⎭",
+		'/': "⎨", '0': "⎬", '<': "≤", '=': "≠", '>': "≥", '?': "∫", '@': "∴",
+		'A': "∝", 'B': "∞", 'C': "÷", 'D': "Δ", 'E': "∇", 'F': "Φ", 'G': "Γ",
+		'H': "∼", 'I': "≃", 'J': "Θ", 'K': "×", 'L': "Λ", 'M': "⇔", 'N': "⇒",
+		'O': "≡", 'P': "Π", 'Q': "Ψ", 'S': "Σ", 'V': "√", 'W': "Ω", 'X': "Ξ",
+		'Y': "Υ", 'Z': "⊂", '[': "⊃", '\\': "∩", ']': "∪", '^': "∧", '_': "∨",
+		'`': "¬", 'a': "α", 'b': "β", 'c': "χ", 'd': "δ", 'e': "ε", 'f': "φ",
+		'g': "γ", 'h': "η", 'i': "ι", 'j': "θ", 'k': "κ", 'l': "λ", 'n': "ν",
+		'o': "∂", 'p': "π", 'q': "ψ", 'r': "ρ", 's': "σ", 't': "τ", 'v': "ƒ",
+		'w': "ω", 'x': "ξ", 'y': "υ", 'z': "ζ", '{': "←", '|': "↑", '}': "→",
+		'~': "↓",
+	}
+
+	// Latin1Supplemental 是 ISO Latin-1 补充字符集，一个 96 字符集。
+	Latin1Supplemental = latin1Range(0x20, 0x7f)
 )
+
+// 国家替换字符集 (NRCS)。它们用各国的字符替换 US-ASCII 中的少数字符。
+var (
+	Dutch           = nrcs("£", "¾", "ĳ", "½", "|", "", "", "", "¨", "ƒ", "¼", "´")
+	Finnish         = nrcs("", "", "Ä", "Ö", "Å", "Ü", "", "é", "ä", "ö", "å", "ü")
+	French          = nrcs("£", "à", "°", "ç", "§", "", "", "", "é", "ù", "è", "¨")
+	FrenchCanadian  = nrcs("", "à", "â", "ç", "ê", "î", "", "ô", "é", "ù", "è", "û")
+	German          = nrcs("", "§", "Ä", "Ö", "Ü", "", "", "", "ä", "ö", "ü", "ß")
+	Italian         = nrcs("£", "§", "°", "ç", "é", "", "", "ù", "à", "ò", "è", "ì")
+	NorwegianDanish = nrcs("", "Ä", "Æ", "Ø", "Å", "Ü", "", "ä", "æ", "ø", "å", "ü")
+	Portuguese      = nrcs("", "", "Ã", "Ç", "Õ", "", "", "", "ã", "ç", "õ", "")
+	Spanish         = nrcs("£", "§", "¡", "Ñ", "¿", "", "", "", "°", "ñ", "ç", "")
+	Swedish         = nrcs("", "É", "Ä", "Ö", "Å", "Ü", "", "é", "ä", "ö", "å", "ü")
+	Swiss           = nrcs("ù", "à", "é", "ç", "ê", "î", "è", "ô", "ä", "ö", "ü", "û")
+)
+
+// nrcsPositions 是国家替换字符集替换的 US-ASCII 字符。
+const nrcsPositions = "#@[\\]^_`{|}~"
+
+// nrcs 返回一个国家替换字符集，它依次用 replacements 替换 [nrcsPositions]
+// 中的字符。空字符串表示该字符保持不变。
+func nrcs(replacements ...string) CharSet {
+	cs := CharSet{}
+	for i, r := range replacements {
+		if r != "" {
+			cs[nrcsPositions[i]] = r
+		}
+	}
+	return cs
+}
+
+// latin1Range 返回一个字符集，它将 from 到 to（包含）之间的位置映射到 ISO
+// Latin-1 中 GR 的对应字符。
+func latin1Range(from, to byte) CharSet {
+	cs := CharSet{}
+	for c := from; c <= to; c++ {
+		cs[c] = string(rune(c) + 0x80)
+	}
+	return cs
+}
+
+// charsetDesignation 是可以通过 SCS 序列指定的字符集。
+type charsetDesignation struct {
+	// designator 是 SCS 序列中选择 G0 到 G3 的中间字节之后的字节，例如 "A"
+	// 或 "%5"。
+	designator string
+	// is96 报告这是否是一个 96 字符集。96 字符集只能通过 ESC - . / 指定给
+	// G1 到 G3。
+	is96 bool
+	set  CharSet
+}
+
+// charsetDesignations 列出可以通过 SCS 序列指定的字符集。一个字符集可以有多个
+// 指示符，第一个用于报告。USASCII 是默认字符集，用 nil 表示。
+var charsetDesignations = []charsetDesignation{
+	{"B", false, nil},
+	{"A", false, UK},
+	{"0", false, SpecialDrawing},
+	{"%5", false, DECSupplemental},
+	{"<", false, DECSupplemental},
+	{">", false, DECTechnical},
+	{"4", false, Dutch},
+	{"C", false, Finnish},
+	{"5", false, Finnish},
+	{"R", false, French},
+	{"f", false, French},
+	{"Q", false, FrenchCanadian},
+	{"9", false, FrenchCanadian},
+	{"K", false, German},
+	{"Y", false, Italian},
+	{"E", false, NorwegianDanish},
+	{"6", false, NorwegianDanish},
+	{"`", false, NorwegianDanish},
+	{"%6", false, Portuguese},
+	{"Z", false, Spanish},
+	{"H", false, Swedish},
+	{"7", false, Swedish},
+	{"=", false, Swiss},
+	{"A", true, Latin1Supplemental},
+}
+
+// charsetDesignator 返回字符集 cs 的指示符，以及它是否是一个 96 字符集。
+// USASCII 和未知的字符集返回 "B"。
+func charsetDesignator(cs CharSet) (designator string, is96 bool) {
+	if cs != nil {
+		for _, d := range charsetDesignations {
+			if d.set != nil && maps.Equal(cs, d.set) {
+				return d.designator, d.is96
+			}
+		}
+	}
+	return "B", false
+}
+
+// charsetByDesignator 返回由指示符 designator 指定的字符集。is96 报告指示符
+// 是否用于 96 字符集。如果字符集未知，则返回 false。
+func charsetByDesignator(designator string, is96 bool) (CharSet, bool) {
+	for _, d := range charsetDesignations {
+		if d.designator == designator && d.is96 == is96 {
+			return d.set, true
+		}
+	}
+	return nil, false
+}
+
+// designateCharset 处理 SCS 序列，将字符集指定给 G0 到 G3。inters 是序列的
+// 所有中间字节，第一个选择 G0 到 G3 以及字符集的大小，其余的和 final 组成
+// 字符集的指示符。如果序列不是有效的 SCS 序列或字符集未知，则返回 false。
+func (e *Emulator) designateCharset(inters []byte, final byte) bool {
+	if len(inters) == 0 {
+		return false
+	}
+
+	var (
+		g    int
+		is96 bool
+	)
+	switch c := inters[0]; c {
+	case '(', ')', '*', '+':
+		g = int(c - '(')
+	case '-', '.', '/':
+		g, is96 = int(c-'-')+1, true
+	default:
+		return false
+	}
+
+	cs, ok := charsetByDesignator(string(inters[1:])+string(final), is96)
+	if !ok {
+		return false
+	}
+	e.charsets[g] = cs
+	return true
+}
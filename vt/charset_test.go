@@ -0,0 +1,53 @@
+package vt
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCharsets(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"uk", "\x1b(A#", "£"},
+		{"shift out and in", "\x1b)0a\x0eq\x0fq", "a─q"},
+		{"nrcs", "\x1b(K[\\]{|}~", "ÄÖÜäöüß"},
+		{"two intermediates", "\x1b(%6[\\]", "ÃÇÕ"},
+		{"single shift dec supplemental", "\x1b*%5\x1bN×é", "Œé"},
+		{"utf-8 latin-1 text", "\x1b*0ñé", "ñé"},
+		{"dec technical", "\x1b+>\x1boDp\x0fp", "Δπp"},
+		{"96-character set", "\x1b-A\x0eA\x0fA", "ÁA"},
+		{"locking shift right is report-only under utf-8", "\x1b)0\x1b~ñ\x1b}ñ", "ññ"},
+		{"single shift", "\x1b*0\x1bNqq", "─q"},
+		{"unknown designator", "\x1b(0\x1b(Xq", "─"},
+		{"dec supplemental in gl", "\x1b(%5q", "ñ"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			term := newTestTerminal(t, 10, 1)
+			term.WriteString(tc.input)
+			if got := termText(term)[0]; got != tc.want+strings.Repeat(" ", 10-utf8.RuneCountInString(tc.want)) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCursorInformationReport(t *testing.T) {
+	term := newTestTerminal(t, 10, 5)
+	term.WriteString("\x1b[1;4m\x1b)0\x1b*%5\x1b/A\x1bn\x1b|\x1b[2;3H")
+
+	got := readInput(t, term, func() { term.WriteString("\x1b[1$w") })
+	if want := "\x1bP1$u2;3;1;C;@;@;2;3;H;B0%5A\x1b\\"; got != want {
+		t.Errorf("DECCIR: got %q, want %q", got, want)
+	}
+
+	term.WriteString("\x1bc\x1b[?6h\x1bO")
+	got = readInput(t, term, func() { term.WriteString("\x1b[1$w") })
+	if want := "\x1bP1$u1;1;1;@;@;E;0;2;@;BBBB\x1b\\"; got != want {
+		t.Errorf("DECCIR after reset: got %q, want %q", got, want)
+	}
+}
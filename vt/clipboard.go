@@ -0,0 +1,135 @@
+package vt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+
+	"github.com/purpose168/charm-experimental-packages-cn/ansi"
+)
+
+// 剪贴板选择。
+const (
+	// ClipboardSystem 是系统剪贴板。
+	ClipboardSystem = ansi.SystemClipboard
+	// ClipboardPrimary 是主选择。
+	ClipboardPrimary = ansi.PrimaryClipboard
+	// ClipboardSelect 是 xterm 的选择缓冲区。
+	ClipboardSelect = 's'
+)
+
+// DefaultClipboardMaxSize 是默认允许的剪贴板数据的最大大小（以字节为单位）。
+const DefaultClipboardMaxSize = 1024 * 1024
+
+// Clipboard 表示终端通过 OSC 52 访问的剪贴板后端。
+type Clipboard interface {
+	// SetClipboard 将数据写入给定的剪贴板选择。空数据表示清除该选择。
+	SetClipboard(selection byte, data string) error
+
+	// Clipboard 返回给定剪贴板选择的内容。
+	Clipboard(selection byte) (string, error)
+}
+
+// ClipboardPolicy 控制应用程序如何通过 OSC 52 访问剪贴板。
+type ClipboardPolicy struct {
+	// AllowWrite 允许应用程序写入剪贴板。
+	AllowWrite bool
+
+	// AllowRead 允许应用程序读取剪贴板。读取的内容会发送到终端输入，因此
+	// 默认禁用。
+	AllowRead bool
+
+	// MaxSize 是允许写入的数据的最大大小（以字节为单位）。零表示使用
+	// [DefaultClipboardMaxSize]，负值表示没有限制。
+	MaxSize int
+}
+
+// DefaultClipboardPolicy 是新建模拟器的默认剪贴板策略：允许写入，禁止读取。
+var DefaultClipboardPolicy = ClipboardPolicy{
+	AllowWrite: true,
+	MaxSize:    DefaultClipboardMaxSize,
+}
+
+// ClipboardPolicy 返回当前的剪贴板策略。
+func (e *Emulator) ClipboardPolicy() ClipboardPolicy {
+	return e.clipboardPolicy
+}
+
+// SetClipboardPolicy 设置剪贴板策略。剪贴板后端通过 [Callbacks.Clipboard]
+// 设置。
+func (e *Emulator) SetClipboardPolicy(p ClipboardPolicy) {
+	e.clipboardPolicy = p
+}
+
+// handleClipboard 处理 OSC 52 剪贴板序列。
+//
+//	OSC 52 ; Pc ; Pd ST
+//
+// 其中 Pc 是剪贴板选择，Pd 是 base64 编码的数据，或者是请求剪贴板内容的
+// "?"。只支持 c、p 和 s 选择，省略 Pc 时使用系统剪贴板。
+func (e *Emulator) handleClipboard(cmd int, data []byte) {
+	parts := bytes.SplitN(data, []byte{';'}, 3)
+	if len(parts) != 3 || cmd != 52 {
+		// Invalid, ignore
+		return
+	}
+
+	cb := e.cb.Clipboard
+	if cb == nil {
+		return
+	}
+
+	sel := byte(ClipboardSystem)
+	if pc := parts[1]; len(pc) > 0 {
+		i := bytes.IndexFunc(pc, func(r rune) bool {
+			return r == ClipboardSystem || r == ClipboardPrimary || r == ClipboardSelect
+		})
+		if i < 0 {
+			// Unsupported selection, ignore
+			return
+		}
+		sel = pc[i]
+	}
+
+	policy := e.clipboardPolicy
+	pd := parts[2]
+	if string(pd) == "?" {
+		if !policy.AllowRead {
+			return
+		}
+		content, err := cb.Clipboard(sel)
+		if err != nil {
+			e.logf("读取剪贴板失败: %v", err)
+			return
+		}
+		_, _ = io.WriteString(e.pw, ansi.SetClipboard(sel, content))
+		return
+	}
+
+	if !policy.AllowWrite {
+		return
+	}
+
+	maxSize := policy.MaxSize
+	if maxSize == 0 {
+		maxSize = DefaultClipboardMaxSize
+	}
+	if maxSize > 0 && base64.StdEncoding.DecodedLen(len(pd)) > maxSize+2 {
+		e.logf("剪贴板数据过大: %d 字节", len(pd))
+		return
+	}
+
+	// 与 xterm 一样，无效的 base64 数据会清除选择。
+	content, err := base64.StdEncoding.DecodeString(string(pd))
+	if err != nil {
+		content = nil
+	}
+	if maxSize > 0 && len(content) > maxSize {
+		e.logf("剪贴板数据过大: %d 字节", len(content))
+		return
+	}
+
+	if err := cb.SetClipboard(sel, string(content)); err != nil {
+		e.logf("写入剪贴板失败: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package vt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/purpose168/charm-experimental-packages-cn/ansi"
+)
+
+type testClipboard map[byte]string
+
+func (c testClipboard) SetClipboard(selection byte, data string) error {
+	c[selection] = data
+	return nil
+}
+
+func (c testClipboard) Clipboard(selection byte) (string, error) {
+	return c[selection], nil
+}
+
+func TestClipboard(t *testing.T) {
+	clip := testClipboard{}
+	term := newTestTerminal(t, 10, 2)
+	term.SetCallbacks(Callbacks{Clipboard: clip})
+
+	term.WriteString(ansi.SetSystemClipboard("hello"))
+	term.WriteString(ansi.SetPrimaryClipboard("world"))
+	term.WriteString("\x1b]52;;ZGVmYXVsdA==\x07")
+	if clip['c'] != "default" || clip['p'] != "world" {
+		t.Errorf("clipboard = %v", clip)
+	}
+
+	// Reads are denied by default.
+	term.WriteString(ansi.RequestClipboard('p') + ansi.SetSystemClipboard("after"))
+	if clip['c'] != "after" {
+		t.Errorf("clipboard = %v", clip)
+	}
+
+	term.SetClipboardPolicy(ClipboardPolicy{AllowWrite: true, AllowRead: true, MaxSize: 8})
+	got := readInput(t, term, func() { term.WriteString(ansi.RequestClipboard('p')) })
+	if want := ansi.SetClipboard('p', "world"); got != want {
+		t.Errorf("query reply = %q, want %q", got, want)
+	}
+
+	// Payloads over the size limit are dropped.
+	term.WriteString(ansi.SetSystemClipboard(strings.Repeat("x", 9)))
+	if clip['c'] != "after" {
+		t.Errorf("oversized payload was written: %q", clip['c'])
+	}
+
+	// Writes can be denied too.
+	term.SetClipboardPolicy(ClipboardPolicy{})
+	term.WriteString(ansi.SetSystemClipboard("denied"))
+	if clip['c'] != "after" {
+		t.Errorf("denied write was written: %q", clip['c'])
+	}
+}
@@ -0,0 +1,166 @@
+package colorscheme
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// base16ANSI 是 base16 方案的颜色到 16 个 ANSI 颜色的映射，与 base16-shell
+// 相同。明亮颜色重复使用普通颜色。
+var base16ANSI = [16]string{
+	"00", "08", "0B", "0A", "0D", "0E", "0C", "05",
+	"03", "08", "0B", "0A", "0D", "0E", "0C", "07",
+}
+
+// base24ANSI 是 base24 方案的颜色到 16 个 ANSI 颜色的映射。base24 为明亮
+// 颜色提供了单独的 base12 到 base17。
+var base24ANSI = [16]string{
+	"00", "08", "0B", "0A", "0D", "0E", "0C", "05",
+	"03", "12", "14", "13", "16", "17", "15", "07",
+}
+
+// errNoBase16Colors 在 YAML 文件中没有 base16 颜色时返回。
+var errNoBase16Colors = errors.New("colorscheme: no base16 colors")
+
+// decodeBase16 读取 base16 或 base24 的 YAML 方案。它只理解方案使用的简单
+// "键: 值" 行，包括 tinted-theming 格式中嵌套在 palette 下的颜色。颜色可以
+// 带或不带 "#"。
+func decodeBase16(r io.Reader) (*Scheme, error) {
+	var s Scheme
+	colors := map[string]color.Color{}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), yamlScalar(value)
+
+		switch key {
+		case "scheme", "name":
+			s.Name = value
+			continue
+		case "author":
+			s.Author = value
+			continue
+		}
+		base, ok := strings.CutPrefix(key, "base")
+		if !ok || len(base) != 2 || value == "" {
+			continue
+		}
+		if value[0] != '#' {
+			value = "#" + value
+		}
+		c, err := parseColor(value)
+		if err != nil {
+			return nil, fmt.Errorf("colorscheme: line %d: %w", n, err)
+		}
+		colors[strings.ToUpper(base)] = c
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("colorscheme: %w", err)
+	}
+	if len(colors) == 0 {
+		return nil, errNoBase16Colors
+	}
+
+	mapping := base16ANSI
+	for _, k := range base24ANSI[9:15] {
+		if colors[k] != nil {
+			mapping = base24ANSI
+			break
+		}
+	}
+	for i, k := range mapping {
+		s.ANSI[i] = colors[k]
+	}
+	s.Foreground = colors["05"]
+	s.Background = colors["00"]
+	s.Cursor = colors["05"]
+	s.CursorText = colors["00"]
+	s.SelectionForeground = colors["05"]
+	s.SelectionBackground = colors["02"]
+	return &s, nil
+}
+
+// yamlScalar 返回 YAML 标量值，去掉引号和行尾注释。
+func yamlScalar(v string) string {
+	v = strings.TrimSpace(v)
+	if v != "" && (v[0] == '"' || v[0] == '\'') {
+		if end := strings.IndexByte(v[1:], v[0]); end >= 0 {
+			return v[1 : end+1]
+		}
+		return v[1:]
+	}
+	if i := strings.Index(v, " #"); i >= 0 {
+		v = v[:i]
+	}
+	return strings.TrimSpace(v)
+}
+
+// base16Color 是 base16 方案中的一个颜色，key 是 "base" 之后的十六进制数字。
+type base16Color struct {
+	key string
+	c   color.Color
+}
+
+// encodeBase16 写入 base16 YAML 方案，如果 base24 为 true，则写入 base24 方案。
+// 配色方案中没有对应颜色的 base 颜色使用最接近的 ANSI 颜色。
+func encodeBase16(w io.Writer, s *Scheme, base24 bool) error {
+	or := func(c, fallback color.Color) color.Color {
+		if c != nil {
+			return c
+		}
+		return fallback
+	}
+	colors := []base16Color{
+		{"00", or(s.Background, s.ANSI[0])},
+		{"01", s.ANSI[0]},
+		{"02", or(s.SelectionBackground, s.ANSI[8])},
+		{"03", s.ANSI[8]},
+		{"04", s.ANSI[7]},
+		{"05", or(s.Foreground, s.ANSI[7])},
+		{"06", s.ANSI[7]},
+		{"07", s.ANSI[15]},
+		{"08", s.ANSI[1]},
+		{"09", s.ANSI[3]},
+		{"0A", s.ANSI[3]},
+		{"0B", s.ANSI[2]},
+		{"0C", s.ANSI[6]},
+		{"0D", s.ANSI[4]},
+		{"0E", s.ANSI[5]},
+		{"0F", s.ANSI[1]},
+	}
+	if base24 {
+		colors = append(colors, []base16Color{
+			{"10", or(s.Background, s.ANSI[0])},
+			{"11", or(s.Background, s.ANSI[0])},
+			{"12", s.ANSI[9]},
+			{"13", s.ANSI[11]},
+			{"14", s.ANSI[10]},
+			{"15", s.ANSI[14]},
+			{"16", s.ANSI[12]},
+			{"17", s.ANSI[13]},
+		}...)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "scheme: %q\n", s.Name)
+	fmt.Fprintf(&b, "author: %q\n", s.Author)
+	for _, c := range colors {
+		if c.c != nil {
+			fmt.Fprintf(&b, "base%s: %q\n", c.key, hexColor(c.c)[1:])
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err //nolint:wrapcheck
+}
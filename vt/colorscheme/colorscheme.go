@@ -0,0 +1,191 @@
+// Package colorscheme 读取和写入常见格式的终端配色方案，并将它们应用到虚拟
+// 终端模拟器的调色板。
+//
+// 支持的格式有 kitty 的 .conf 颜色配置、base16 和 base24 的 YAML 方案、
+// iTerm2 的 .itermcolors 文件、Windows Terminal 的 JSON 配色方案对象以及
+// Xresources。
+package colorscheme
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/purpose168/charm-experimental-packages-cn/ansi"
+)
+
+// Scheme 是一个终端配色方案。nil 表示该颜色未设置。
+type Scheme struct {
+	// Name 是配色方案的名称。
+	Name string
+	// Author 是配色方案的作者。
+	Author string
+
+	// Foreground 和 Background 是默认的前景色和背景色。
+	Foreground, Background color.Color
+	// Cursor 是光标的颜色，CursorText 是光标下文本的颜色。
+	Cursor, CursorText color.Color
+	// SelectionForeground 和 SelectionBackground 是选中文本的颜色。
+	SelectionForeground, SelectionBackground color.Color
+
+	// ANSI 是 16 个 ANSI 颜色，前 8 个是普通颜色，后 8 个是明亮颜色。
+	ANSI [16]color.Color
+}
+
+// Target 是可以应用配色方案的调色板。vt 包的 Emulator 和 SafeEmulator 实现
+// 了这个接口。
+type Target interface {
+	SetDefaultForegroundColor(c color.Color)
+	SetDefaultBackgroundColor(c color.Color)
+	SetDefaultCursorColor(c color.Color)
+	SetDefaultIndexedColor(i int, c color.Color)
+}
+
+// Apply 将配色方案应用到 t。默认的前景色、背景色、光标颜色和前 16 个索引颜色
+// 被替换，配色方案中未设置的颜色恢复为 t 的内置颜色。应用程序设置的颜色仍然
+// 优先，重置它们会恢复为配色方案的颜色。选中文本和光标下文本的颜色由嵌入者
+// 自行使用。
+func (s *Scheme) Apply(t Target) {
+	t.SetDefaultForegroundColor(s.Foreground)
+	t.SetDefaultBackgroundColor(s.Background)
+	t.SetDefaultCursorColor(s.Cursor)
+	for i, c := range s.ANSI {
+		t.SetDefaultIndexedColor(i, c)
+	}
+}
+
+// Format 是配色方案的文件格式。
+type Format int
+
+// 配色方案格式。
+const (
+	// FormatKitty 是 kitty 的 .conf 颜色配置。
+	FormatKitty Format = iota + 1
+	// FormatBase16 是 base16 的 YAML 方案。读取时它也接受 base24 方案。
+	FormatBase16
+	// FormatBase24 是 base24 的 YAML 方案。
+	FormatBase24
+	// FormatITerm2 是 iTerm2 的 .itermcolors 属性列表。
+	FormatITerm2
+	// FormatWindowsTerminal 是 Windows Terminal 的 JSON 配色方案对象。
+	FormatWindowsTerminal
+	// FormatXresources 是 X 资源文件。
+	FormatXresources
+)
+
+// String 返回格式的名称。
+func (f Format) String() string {
+	switch f {
+	case FormatKitty:
+		return "kitty"
+	case FormatBase16:
+		return "base16"
+	case FormatBase24:
+		return "base24"
+	case FormatITerm2:
+		return "iterm2"
+	case FormatWindowsTerminal:
+		return "windows-terminal"
+	case FormatXresources:
+		return "xresources"
+	default:
+		return fmt.Sprintf("Format(%d)", int(f))
+	}
+}
+
+// ErrUnknownFormat 在配色方案的格式未知时返回。
+var ErrUnknownFormat = errors.New("colorscheme: unknown format")
+
+// FormatForPath 根据文件名返回配色方案的格式。如果无法识别，返回 false。
+func FormatForPath(path string) (Format, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".conf":
+		return FormatKitty, true
+	case ".yaml", ".yml":
+		return FormatBase16, true
+	case ".itermcolors":
+		return FormatITerm2, true
+	case ".json":
+		return FormatWindowsTerminal, true
+	case ".xresources", ".xdefaults", ".ad":
+		return FormatXresources, true
+	}
+	return 0, false
+}
+
+// Load 读取文件 path 中的配色方案，格式由 [FormatForPath] 决定。
+func Load(path string) (*Scheme, error) {
+	f, ok := FormatForPath(path)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	defer file.Close() //nolint:errcheck
+	return Decode(file, f)
+}
+
+// Decode 从 r 读取格式为 f 的配色方案。
+func Decode(r io.Reader, f Format) (*Scheme, error) {
+	switch f {
+	case FormatKitty:
+		return decodeKitty(r)
+	case FormatBase16, FormatBase24:
+		return decodeBase16(r)
+	case FormatITerm2:
+		return decodeITerm2(r)
+	case FormatWindowsTerminal:
+		return decodeWindowsTerminal(r)
+	case FormatXresources:
+		return decodeXresources(r)
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnknownFormat, f)
+	}
+}
+
+// Encode 将配色方案 s 以格式 f 写入 w。格式不支持的颜色会被省略。
+func Encode(w io.Writer, s *Scheme, f Format) error {
+	switch f {
+	case FormatKitty:
+		return encodeKitty(w, s)
+	case FormatBase16:
+		return encodeBase16(w, s, false)
+	case FormatBase24:
+		return encodeBase16(w, s, true)
+	case FormatITerm2:
+		return encodeITerm2(w, s)
+	case FormatWindowsTerminal:
+		return encodeWindowsTerminal(w, s)
+	case FormatXresources:
+		return encodeXresources(w, s)
+	default:
+		return fmt.Errorf("%w: %v", ErrUnknownFormat, f)
+	}
+}
+
+// parseColor 解析 "#rgb"、"#rrggbb" 或 X11 的 "rgb:rr/gg/bb" 格式的颜色。
+func parseColor(s string) (color.Color, error) {
+	c := ansi.XParseColor(s)
+	if c == nil {
+		return nil, fmt.Errorf("invalid color %q", s)
+	}
+	return rgb(c), nil
+}
+
+// rgb 将颜色转换为不透明的 [color.RGBA]。
+func rgb(c color.Color) color.RGBA {
+	r, g, b, _ := c.RGBA()
+	return color.RGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: 0xff} //nolint:gosec
+}
+
+// hexColor 以 "#rrggbb" 格式返回颜色。
+func hexColor(c color.Color) string {
+	v := rgb(c)
+	return fmt.Sprintf("#%02x%02x%02x", v.R, v.G, v.B)
+}
@@ -0,0 +1,224 @@
+package colorscheme
+
+import (
+	"errors"
+	"image/color"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/purpose168/charm-experimental-packages-cn/vt"
+)
+
+func hex(s string) color.Color {
+	c, err := parseColor(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// testScheme returns a scheme with every color set.
+func testScheme() *Scheme {
+	s := &Scheme{
+		Name:                "Test",
+		Author:              "Someone",
+		Foreground:          hex("#c0c0c0"),
+		Background:          hex("#101010"),
+		Cursor:              hex("#f0f0f0"),
+		CursorText:          hex("#202020"),
+		SelectionForeground: hex("#fefefe"),
+		SelectionBackground: hex("#303030"),
+	}
+	for i := range s.ANSI {
+		s.ANSI[i] = color.RGBA{R: uint8(i * 16), G: uint8(255 - i*16), B: uint8(i * 7), A: 0xff}
+	}
+	return s
+}
+
+func TestRoundTrip(t *testing.T) {
+	cases := []struct {
+		format Format
+		// clear removes the colors the format can't represent.
+		clear func(s *Scheme)
+	}{
+		{FormatKitty, func(*Scheme) {}},
+		{FormatITerm2, func(s *Scheme) { s.Name, s.Author = "", "" }},
+		{FormatWindowsTerminal, func(s *Scheme) { s.Author, s.CursorText, s.SelectionForeground = "", nil, nil }},
+		{FormatXresources, func(s *Scheme) { s.Name, s.Author = "", "" }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.format.String(), func(t *testing.T) {
+			want := testScheme()
+			tc.clear(want)
+
+			var b strings.Builder
+			if err := Encode(&b, testScheme(), tc.format); err != nil {
+				t.Fatal(err)
+			}
+			got, err := Decode(strings.NewReader(b.String()), tc.format)
+			if err != nil {
+				t.Fatalf("decode %q: %v", b.String(), err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestBase16(t *testing.T) {
+	const base24 = `# A base24 scheme
+scheme: "Test"
+author: 'Someone' # comment
+base00: "101010"
+base02: "#303030"
+base05: "c0c0c0"
+base07: "ffffff"
+base08: "aa0000"
+base12: "ff0000"
+`
+	s, err := Decode(strings.NewReader(base24), FormatBase16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "Test" || s.Author != "Someone" {
+		t.Errorf("name, author = %q, %q", s.Name, s.Author)
+	}
+	checks := []struct {
+		name      string
+		got, want color.Color
+	}{
+		{"background", s.Background, hex("#101010")},
+		{"foreground", s.Foreground, hex("#c0c0c0")},
+		{"selection", s.SelectionBackground, hex("#303030")},
+		{"red", s.ANSI[1], hex("#aa0000")},
+		{"bright red", s.ANSI[9], hex("#ff0000")},
+		{"bright white", s.ANSI[15], hex("#ffffff")},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	// Encoding as base24 and decoding again keeps every color.
+	var b strings.Builder
+	if err := Encode(&b, s, FormatBase24); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Decode(strings.NewReader(b.String()), FormatBase24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, s) {
+		t.Errorf("round trip = %+v, want %+v", again, s)
+	}
+
+	// Without base12-base17 the bright colors repeat the normal colors.
+	b.Reset()
+	if err := Encode(&b, s, FormatBase16); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.String(), "base12") {
+		t.Errorf("base16 output contains base24 colors:\n%s", b.String())
+	}
+	s16, err := Decode(strings.NewReader(b.String()), FormatBase16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s16.ANSI[9] != s.ANSI[1] {
+		t.Errorf("bright red = %v, want %v", s16.ANSI[9], s.ANSI[1])
+	}
+
+	if _, err := Decode(strings.NewReader("scheme: empty\n"), FormatBase16); !errors.Is(err, errNoBase16Colors) {
+		t.Errorf("err = %v, want %v", err, errNoBase16Colors)
+	}
+}
+
+func TestXresourcesDefines(t *testing.T) {
+	const xres = `! comment
+#define bg #101010
+#ifdef COLOR
+#endif
+URxvt*background: bg
+XTerm.vt100.foreground: rgb:c0/c0/c0
+*color1:  #a00
+`
+	s, err := Decode(strings.NewReader(xres), FormatXresources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Background != hex("#101010") || s.Foreground != hex("#c0c0c0") || s.ANSI[1] != hex("#aa0000") {
+		t.Errorf("got %+v", s)
+	}
+}
+
+func TestDecodeInvalidColor(t *testing.T) {
+	_, err := Decode(strings.NewReader("foreground #c0c0c0\nbackground blue\n"), FormatKitty)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("err = %v, want an error on line 2", err)
+	}
+}
+
+func TestFormatForPath(t *testing.T) {
+	cases := map[string]Format{
+		"theme.conf":                  FormatKitty,
+		"gruvbox.yaml":                FormatBase16,
+		"Solarized.itermcolors":       FormatITerm2,
+		"campbell.json":               FormatWindowsTerminal,
+		"/home/user/.Xresources":      FormatXresources,
+		"/home/user/colors.Xdefaults": FormatXresources,
+	}
+	for path, want := range cases {
+		if got, ok := FormatForPath(path); !ok || got != want {
+			t.Errorf("FormatForPath(%q) = %v, %v, want %v", path, got, ok, want)
+		}
+	}
+	if _, err := Load("theme.txt"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Load err = %v, want %v", err, ErrUnknownFormat)
+	}
+}
+
+func TestApply(t *testing.T) {
+	s := testScheme()
+	s.ANSI[3] = nil
+
+	term := vt.NewEmulator(10, 2)
+	term.SetDefaultIndexedColor(3, hex("#123456"))
+	s.Apply(term)
+
+	if got := term.ForegroundColor(); got != s.Foreground {
+		t.Errorf("foreground = %v, want %v", got, s.Foreground)
+	}
+	if got := term.BackgroundColor(); got != s.Background {
+		t.Errorf("background = %v, want %v", got, s.Background)
+	}
+	if got := term.CursorColor(); got != s.Cursor {
+		t.Errorf("cursor = %v, want %v", got, s.Cursor)
+	}
+	if got := term.IndexedColor(1); got != s.ANSI[1] {
+		t.Errorf("color 1 = %v, want %v", got, s.ANSI[1])
+	}
+	// Unset colors are restored to the defaults.
+	if got, want := term.IndexedColor(3), vt.NewEmulator(1, 1).IndexedColor(3); got != want {
+		t.Errorf("color 3 = %v, want default %v", got, want)
+	}
+
+	// Colors set by the application take precedence, and resetting them
+	// restores the scheme.
+	term.WriteString("\x1b]4;1;#ffffff\x07")
+	if got := term.IndexedColor(1); got == s.ANSI[1] {
+		t.Errorf("color 1 = %v after OSC 4, want the application color", got)
+	}
+	term.WriteString("\x1b]104\x07")
+	if got := term.IndexedColor(1); got != s.ANSI[1] {
+		t.Errorf("color 1 = %v after OSC 104, want %v", got, s.ANSI[1])
+	}
+	term.WriteString("\x1b]4;1;#ffffff\x07\x1bc")
+	if got := term.IndexedColor(1); got != s.ANSI[1] {
+		t.Errorf("color 1 = %v after RIS, want %v", got, s.ANSI[1])
+	}
+
+	var _ Target = vt.NewSafeEmulator(1, 1)
+}
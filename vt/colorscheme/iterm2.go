@@ -0,0 +1,196 @@
+package colorscheme
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// iterm2Color 返回 iTerm2 颜色键 key 对应的颜色字段。未知的键返回 nil。
+func (s *Scheme) iterm2Color(key string) *color.Color {
+	switch key {
+	case "Foreground Color":
+		return &s.Foreground
+	case "Background Color":
+		return &s.Background
+	case "Cursor Color":
+		return &s.Cursor
+	case "Cursor Text Color":
+		return &s.CursorText
+	case "Selected Text Color":
+		return &s.SelectionForeground
+	case "Selection Color":
+		return &s.SelectionBackground
+	}
+	var i int
+	if _, err := fmt.Sscanf(key, "Ansi %d Color", &i); err == nil && i >= 0 && i < len(s.ANSI) {
+		return &s.ANSI[i]
+	}
+	return nil
+}
+
+// errInvalidPlist 在 .itermcolors 文件不是有效的属性列表时返回。
+var errInvalidPlist = errors.New("colorscheme: invalid property list")
+
+// decodeITerm2 读取 iTerm2 的 .itermcolors 属性列表。颜色的分量被视为 sRGB，
+// 忽略颜色空间。
+func decodeITerm2(r io.Reader) (*Scheme, error) {
+	v, err := decodePlist(r)
+	if err != nil {
+		return nil, err
+	}
+	dict, ok := v.(map[string]any)
+	if !ok {
+		return nil, errInvalidPlist
+	}
+
+	var s Scheme
+	for key, v := range dict {
+		field := s.iterm2Color(key)
+		if field == nil {
+			continue
+		}
+		comps, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not a color", errInvalidPlist, key)
+		}
+		var rgb [3]uint8
+		for i, name := range []string{"Red Component", "Green Component", "Blue Component"} {
+			f, ok := comps[name].(float64)
+			if !ok {
+				return nil, fmt.Errorf("%w: %q has no %s", errInvalidPlist, key, strings.ToLower(name))
+			}
+			rgb[i] = uint8(math.Round(min(max(f, 0), 1) * 0xff))
+		}
+		*field = color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 0xff}
+	}
+	return &s, nil
+}
+
+// decodePlist 解码 XML 属性列表的根值。字典解码为 map[string]any，数组解码为
+// []any，real 和 integer 解码为 float64，true 和 false 解码为 bool，其余的
+// 值解码为字符串。
+func decodePlist(r io.Reader) (any, error) {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidPlist, err)
+		}
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local != "plist" {
+			return decodePlistValue(dec, se)
+		}
+	}
+}
+
+// decodePlistValue 解码从 start 开始的属性列表值。
+func decodePlistValue(dec *xml.Decoder, start xml.StartElement) (any, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := map[string]any{}
+		var key string
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", errInvalidPlist, err)
+			}
+			switch tok := tok.(type) {
+			case xml.StartElement:
+				if tok.Name.Local == "key" {
+					if err := dec.DecodeElement(&key, &tok); err != nil {
+						return nil, fmt.Errorf("%w: %w", errInvalidPlist, err)
+					}
+					continue
+				}
+				v, err := decodePlistValue(dec, tok)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = v
+			case xml.EndElement:
+				return dict, nil
+			}
+		}
+	case "array":
+		var arr []any
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", errInvalidPlist, err)
+			}
+			switch tok := tok.(type) {
+			case xml.StartElement:
+				v, err := decodePlistValue(dec, tok)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, v)
+			case xml.EndElement:
+				return arr, nil
+			}
+		}
+	case "true", "false":
+		if err := dec.Skip(); err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidPlist, err)
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	var text string
+	if err := dec.DecodeElement(&text, &start); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidPlist, err)
+	}
+	switch start.Name.Local {
+	case "real", "integer":
+		f, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidPlist, err)
+		}
+		return f, nil
+	}
+	return text, nil
+}
+
+// encodeITerm2 写入 iTerm2 的 .itermcolors 属性列表，键按字母顺序排列。
+func encodeITerm2(w io.Writer, s *Scheme) error {
+	keys := []string{
+		"Foreground Color", "Background Color", "Cursor Color", "Cursor Text Color",
+		"Selected Text Color", "Selection Color",
+	}
+	for i := range s.ANSI {
+		keys = append(keys, fmt.Sprintf("Ansi %d Color", i))
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	b.WriteString("<plist version=\"1.0\">\n<dict>\n")
+	for _, key := range keys {
+		c := *s.iterm2Color(key)
+		if c == nil {
+			continue
+		}
+		v := rgb(c)
+		fmt.Fprintf(&b, "\t<key>%s</key>\n\t<dict>\n", key)
+		b.WriteString("\t\t<key>Alpha Component</key>\n\t\t<real>1</real>\n")
+		for _, comp := range []struct {
+			name string
+			v    uint8
+		}{{"Blue", v.B}, {"Green", v.G}, {"Red", v.R}} {
+			fmt.Fprintf(&b, "\t\t<key>%s Component</key>\n\t\t<real>%s</real>\n",
+				comp.name, strconv.FormatFloat(float64(comp.v)/0xff, 'g', -1, 64))
+		}
+		b.WriteString("\t\t<key>Color Space</key>\n\t\t<string>sRGB</string>\n\t</dict>\n")
+	}
+	b.WriteString("</dict>\n</plist>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err //nolint:wrapcheck
+}
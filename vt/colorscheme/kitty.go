@@ -0,0 +1,115 @@
+package colorscheme
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// kittyColor 返回 kitty 颜色配置键 key 对应的颜色字段。未知的键返回 nil。
+func (s *Scheme) kittyColor(key string) *color.Color {
+	switch key {
+	case "foreground":
+		return &s.Foreground
+	case "background":
+		return &s.Background
+	case "cursor":
+		return &s.Cursor
+	case "cursor_text_color":
+		return &s.CursorText
+	case "selection_foreground":
+		return &s.SelectionForeground
+	case "selection_background":
+		return &s.SelectionBackground
+	}
+	if n, ok := strings.CutPrefix(key, "color"); ok {
+		if i, err := strconv.Atoi(n); err == nil && i >= 0 && i < len(s.ANSI) {
+			return &s.ANSI[i]
+		}
+	}
+	return nil
+}
+
+// kittyKeys 是写入 kitty 颜色配置时使用的键，不包括 ANSI 颜色。
+var kittyKeys = []string{
+	"foreground",
+	"background",
+	"cursor",
+	"cursor_text_color",
+	"selection_foreground",
+	"selection_background",
+}
+
+// decodeKitty 读取 kitty 的颜色配置。除颜色以外的选项会被忽略。名称和作者
+// 从 kitty 主题的 "## name:" 和 "## author:" 注释中读取。
+func decodeKitty(r io.Reader) (*Scheme, error) {
+	var s Scheme
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if meta, ok := strings.CutPrefix(line, "##"); ok {
+			k, v, _ := strings.Cut(meta, ":")
+			switch strings.TrimSpace(k) {
+			case "name":
+				s.Name = strings.TrimSpace(v)
+			case "author":
+				s.Author = strings.TrimSpace(v)
+			}
+			continue
+		}
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		field := s.kittyColor(fields[0])
+		if field == nil || len(fields) < 2 {
+			continue
+		}
+		switch fields[1] {
+		case "none", "foreground", "background":
+			// The color follows the cell colors.
+			continue
+		}
+		c, err := parseColor(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("colorscheme: line %d: %w", n, err)
+		}
+		*field = c
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("colorscheme: %w", err)
+	}
+	return &s, nil
+}
+
+// encodeKitty 写入 kitty 的颜色配置。
+func encodeKitty(w io.Writer, s *Scheme) error {
+	var b strings.Builder
+	if s.Name != "" {
+		fmt.Fprintf(&b, "## name: %s\n", s.Name)
+	}
+	if s.Author != "" {
+		fmt.Fprintf(&b, "## author: %s\n", s.Author)
+	}
+	if b.Len() > 0 {
+		b.WriteByte('\n')
+	}
+
+	keys := slices.Clone(kittyKeys)
+	for i := range s.ANSI {
+		keys = append(keys, "color"+strconv.Itoa(i))
+	}
+	for _, key := range keys {
+		if c := *s.kittyColor(key); c != nil {
+			fmt.Fprintf(&b, "%-20s %s\n", key, hexColor(c))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err //nolint:wrapcheck
+}
@@ -0,0 +1,103 @@
+package colorscheme
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+)
+
+// windowsTerminalScheme 是 Windows Terminal 设置中 "schemes" 数组的一个元素。
+type windowsTerminalScheme struct {
+	Name                string `json:"name,omitempty"`
+	Foreground          string `json:"foreground,omitempty"`
+	Background          string `json:"background,omitempty"`
+	CursorColor         string `json:"cursorColor,omitempty"`
+	SelectionBackground string `json:"selectionBackground,omitempty"`
+	Black               string `json:"black,omitempty"`
+	Red                 string `json:"red,omitempty"`
+	Green               string `json:"green,omitempty"`
+	Yellow              string `json:"yellow,omitempty"`
+	Blue                string `json:"blue,omitempty"`
+	Purple              string `json:"purple,omitempty"`
+	Cyan                string `json:"cyan,omitempty"`
+	White               string `json:"white,omitempty"`
+	BrightBlack         string `json:"brightBlack,omitempty"`
+	BrightRed           string `json:"brightRed,omitempty"`
+	BrightGreen         string `json:"brightGreen,omitempty"`
+	BrightYellow        string `json:"brightYellow,omitempty"`
+	BrightBlue          string `json:"brightBlue,omitempty"`
+	BrightPurple        string `json:"brightPurple,omitempty"`
+	BrightCyan          string `json:"brightCyan,omitempty"`
+	BrightWhite         string `json:"brightWhite,omitempty"`
+}
+
+// windowsTerminalField 是 Windows Terminal 配色方案的一个颜色字段及其在
+// [Scheme] 中对应的颜色。
+type windowsTerminalField struct {
+	name  string
+	value *string
+	color *color.Color
+}
+
+// fields 返回 Windows Terminal 配色方案的颜色字段。Windows Terminal 没有光标
+// 下文本和选中文本前景色的设置。
+func (ws *windowsTerminalScheme) fields(s *Scheme) []windowsTerminalField {
+	return []windowsTerminalField{
+		{"foreground", &ws.Foreground, &s.Foreground},
+		{"background", &ws.Background, &s.Background},
+		{"cursorColor", &ws.CursorColor, &s.Cursor},
+		{"selectionBackground", &ws.SelectionBackground, &s.SelectionBackground},
+		{"black", &ws.Black, &s.ANSI[0]},
+		{"red", &ws.Red, &s.ANSI[1]},
+		{"green", &ws.Green, &s.ANSI[2]},
+		{"yellow", &ws.Yellow, &s.ANSI[3]},
+		{"blue", &ws.Blue, &s.ANSI[4]},
+		{"purple", &ws.Purple, &s.ANSI[5]},
+		{"cyan", &ws.Cyan, &s.ANSI[6]},
+		{"white", &ws.White, &s.ANSI[7]},
+		{"brightBlack", &ws.BrightBlack, &s.ANSI[8]},
+		{"brightRed", &ws.BrightRed, &s.ANSI[9]},
+		{"brightGreen", &ws.BrightGreen, &s.ANSI[10]},
+		{"brightYellow", &ws.BrightYellow, &s.ANSI[11]},
+		{"brightBlue", &ws.BrightBlue, &s.ANSI[12]},
+		{"brightPurple", &ws.BrightPurple, &s.ANSI[13]},
+		{"brightCyan", &ws.BrightCyan, &s.ANSI[14]},
+		{"brightWhite", &ws.BrightWhite, &s.ANSI[15]},
+	}
+}
+
+// decodeWindowsTerminal 读取一个 Windows Terminal 的 JSON 配色方案对象。
+func decodeWindowsTerminal(r io.Reader) (*Scheme, error) {
+	var ws windowsTerminalScheme
+	if err := json.NewDecoder(r).Decode(&ws); err != nil {
+		return nil, fmt.Errorf("colorscheme: %w", err)
+	}
+
+	s := Scheme{Name: ws.Name}
+	for _, f := range ws.fields(&s) {
+		if *f.value == "" {
+			continue
+		}
+		c, err := parseColor(*f.value)
+		if err != nil {
+			return nil, fmt.Errorf("colorscheme: %s: %w", f.name, err)
+		}
+		*f.color = c
+	}
+	return &s, nil
+}
+
+// encodeWindowsTerminal 写入一个 Windows Terminal 的 JSON 配色方案对象。
+func encodeWindowsTerminal(w io.Writer, s *Scheme) error {
+	ws := windowsTerminalScheme{Name: s.Name}
+	for _, f := range ws.fields(s) {
+		if *f.color != nil {
+			*f.value = hexColor(*f.color)
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(ws) //nolint:wrapcheck
+}
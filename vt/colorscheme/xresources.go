@@ -0,0 +1,117 @@
+package colorscheme
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// xresourcesColor 返回 X 资源名称 name 对应的颜色字段。未知的名称返回 nil。
+// 名称是资源说明中最后一个 "." 或 "*" 之后的部分，例如 "*.foreground" 中的
+// "foreground"。
+func (s *Scheme) xresourcesColor(name string) *color.Color {
+	switch name {
+	case "foreground":
+		return &s.Foreground
+	case "background":
+		return &s.Background
+	case "cursorColor":
+		return &s.Cursor
+	case "cursorColor2":
+		return &s.CursorText
+	case "highlightTextColor":
+		return &s.SelectionForeground
+	case "highlightColor":
+		return &s.SelectionBackground
+	}
+	if n, ok := strings.CutPrefix(name, "color"); ok {
+		if i, err := strconv.Atoi(n); err == nil && i >= 0 && i < len(s.ANSI) {
+			return &s.ANSI[i]
+		}
+	}
+	return nil
+}
+
+// xresourcesNames 是写入 X 资源时使用的名称，不包括 ANSI 颜色。
+var xresourcesNames = []string{
+	"foreground",
+	"background",
+	"cursorColor",
+	"cursorColor2",
+	"highlightTextColor",
+	"highlightColor",
+}
+
+// decodeXresources 读取 X 资源文件中的颜色。与 xrdb 一样，它支持简单的
+// #define 宏，其他预处理指令会被忽略。颜色可以使用 "#rrggbb" 或 X11 的
+// "rgb:rr/gg/bb" 格式。
+func decodeXresources(r io.Reader) (*Scheme, error) {
+	var s Scheme
+	defines := map[string]string{}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '!' {
+			continue
+		}
+		if directive, ok := strings.CutPrefix(line, "#"); ok {
+			if def, ok := strings.CutPrefix(directive, "define"); ok {
+				if fields := strings.Fields(def); len(fields) == 2 {
+					defines[fields[0]] = fields[1]
+				}
+			}
+			continue
+		}
+
+		spec, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name := spec[strings.LastIndexAny(spec, ".*")+1:]
+		field := s.xresourcesColor(strings.TrimSpace(name))
+		if field == nil {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if v, ok := defines[value]; ok {
+			value = v
+		}
+		c, err := parseColor(value)
+		if err != nil {
+			return nil, fmt.Errorf("colorscheme: line %d: %w", n, err)
+		}
+		*field = c
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("colorscheme: %w", err)
+	}
+	return &s, nil
+}
+
+// encodeXresources 写入 X 资源，所有资源都以 "*." 开头，适用于所有程序。
+func encodeXresources(w io.Writer, s *Scheme) error {
+	var b strings.Builder
+	if s.Name != "" {
+		fmt.Fprintf(&b, "! %s\n", s.Name)
+	}
+	if s.Author != "" {
+		fmt.Fprintf(&b, "! %s\n", s.Author)
+	}
+
+	names := slices.Clone(xresourcesNames)
+	for i := range s.ANSI {
+		names = append(names, "color"+strconv.Itoa(i))
+	}
+	for _, name := range names {
+		if c := *s.xresourcesColor(name); c != nil {
+			fmt.Fprintf(&b, "*.%s: %s\n", name, hexColor(c))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err //nolint:wrapcheck
+}
@@ -42,6 +42,7 @@ func (e *Emulator) setAltScreenMode(on bool) {
 		// 已经在交替屏幕模式或正常屏幕，不执行任何操作。
 		return
 	}
+	e.sel = nil
 	if on {
 		e.scr = &e.scrs[1]
 		e.scrs[1].cur = e.scrs[0].cur
@@ -51,6 +52,7 @@ func (e *Emulator) setAltScreenMode(on bool) {
 	} else {
 		e.scr = &e.scrs[0]
 	}
+	e.damageScreen()
 	if e.cb.AltScreen != nil {
 		e.cb.AltScreen(on)
 	}
@@ -72,6 +74,7 @@ func (e *Emulator) restoreCursor() {
 // setMode 将模式设置为给定值。
 func (e *Emulator) setMode(mode ansi.Mode, setting ansi.ModeSetting) {
 	e.logf("setting mode %T(%v) to %v", mode, mode, setting)
+	old := e.modes[mode]
 	e.modes[mode] = setting
 	switch mode {
 	case ansi.TextCursorEnableMode:
@@ -92,6 +95,8 @@ func (e *Emulator) setMode(mode ansi.Mode, setting ansi.ModeSetting) {
 			e.saveCursor()
 		}
 		e.setAltScreenMode(setting.IsSet())
+	case ansi.ModeSynchronizedOutput:
+		e.setSyncMode(old, setting)
 	case ansi.InBandResizeMode:
 		if setting.IsSet() {
 			_, _ = io.WriteString(e.pw, ansi.InBandResize(e.Height(), e.Width(), 0, 0))
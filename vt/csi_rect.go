@@ -0,0 +1,232 @@
+package vt
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/purpose168/charm-experimental-packages-cn/ansi"
+)
+
+// rectArea 从第 i 个参数开始读取一个矩形区域的上、左、下、右边界（从 1 开始，
+// 包含边界）。缺少的边界默认为整个屏幕。如果设置了 [ansi.DECOM]，坐标相对于
+// 滚动区域，并被限制在滚动区域内。如果区域为空，则返回 false。
+func (e *Emulator) rectArea(params ansi.Params, i int) (uv.Rectangle, bool) {
+	bounds := e.scr.Bounds()
+	var origin uv.Position
+	if e.isModeSet(ansi.ModeOrigin) {
+		bounds = e.scr.ScrollRegion()
+		origin = bounds.Min
+	}
+
+	param := func(i, def int) int {
+		if i >= len(params) {
+			return def
+		}
+		n, _, _ := params.Param(i, def)
+		if n < 1 {
+			return def
+		}
+		return n
+	}
+	top := param(i, 1) - 1 + origin.Y
+	left := param(i+1, 1) - 1 + origin.X
+	bottom := param(i+2, bounds.Dy()) + origin.Y
+	right := param(i+3, bounds.Dx()) + origin.X
+
+	area := uv.Rect(left, top, right-left, bottom-top).Intersect(bounds)
+	return area, !area.Empty()
+}
+
+// rectAttrs 返回 DECCARA 和 DECRARA 从第 4 个参数开始的 SGR 属性。缺少属性
+// 时默认为 0。
+func rectAttrs(params ansi.Params) []int {
+	if len(params) <= 4 {
+		return []int{0}
+	}
+	attrs := make([]int, 0, len(params)-4)
+	for i := 4; i < len(params); i++ {
+		n, _, _ := params.Param(i, 0)
+		attrs = append(attrs, n)
+	}
+	return attrs
+}
+
+// fillRect 用字符 ch 和当前的笔填充矩形区域。
+// 这相当于 DECFRA。
+func (e *Emulator) fillRect(ch rune, area uv.Rectangle) {
+	if ch < 32 || (ch > 126 && ch < 160) || ch > 255 {
+		// 只能使用图形字符。
+		return
+	}
+	c := uv.Cell{
+		Content: string(ch),
+		Width:   1,
+		Style:   e.scr.cursorPen(),
+		Link:    e.scr.cursorLink(),
+	}
+	e.scr.FillArea(&c, area)
+}
+
+// eraseRect 擦除矩形区域。如果 selective 为 true，只擦除字符，保留单元格的
+// 样式；否则单元格被替换为空白单元格。由于不支持 DECSCA 保护属性，所有字符
+// 都可以被选择性擦除。
+// 这相当于 DECERA 和 DECSERA。
+func (e *Emulator) eraseRect(area uv.Rectangle, selective bool) {
+	if !selective {
+		e.scr.FillArea(e.scr.blankCell(), area)
+		return
+	}
+
+	e.scr.updateArea(area, func(c *uv.Cell) {
+		c.Content, c.Width = " ", 1
+	})
+}
+
+// copyRect 将矩形区域 src 复制到左上角位于 dst 的区域。超出屏幕的部分会被
+// 裁剪。源区域和目标区域可以重叠。
+// 这相当于 DECCRA。
+func (e *Emulator) copyRect(src uv.Rectangle, dst uv.Position) {
+	area := uv.Rect(dst.X, dst.Y, src.Dx(), src.Dy()).Intersect(e.scr.Bounds())
+	if area.Empty() {
+		return
+	}
+
+	// 先复制源区域，以处理重叠的情况。
+	cells := make([]*uv.Cell, 0, area.Dx()*area.Dy())
+	for y := range area.Dy() {
+		for x := range area.Dx() {
+			cells = append(cells, e.scr.CellAt(src.Min.X+x, src.Min.Y+y).Clone())
+		}
+	}
+	for y := range area.Dy() {
+		for x := range area.Dx() {
+			e.scr.buf.SetCell(area.Min.X+x, area.Min.Y+y, cells[y*area.Dx()+x])
+		}
+	}
+	e.scr.addDamage(RectDamage(area))
+}
+
+// changeRectAttrs 按照 SGR 参数 attrs 设置或清除区域中单元格的属性。只支持
+// 粗体、下划线、闪烁和反显；0 清除这些属性。区域的范围由 DECSACE 决定，参见
+// [Emulator.attrArea]。
+// 这相当于 DECCARA。
+func (e *Emulator) changeRectAttrs(area uv.Rectangle, attrs []int) {
+	e.updateAttrs(area, func(st *uv.Style) {
+		for _, a := range attrs {
+			switch a {
+			case 0:
+				st.Attrs &^= uv.AttrBold | uv.AttrBlink | uv.AttrReverse
+				st.Underline = uv.UnderlineNone
+			case 1:
+				st.Attrs |= uv.AttrBold
+			case 4:
+				st.Underline = uv.UnderlineSingle
+			case 5:
+				st.Attrs |= uv.AttrBlink
+			case 7:
+				st.Attrs |= uv.AttrReverse
+			case 22:
+				st.Attrs &^= uv.AttrBold | uv.AttrFaint
+			case 24:
+				st.Underline = uv.UnderlineNone
+			case 25:
+				st.Attrs &^= uv.AttrBlink
+			case 27:
+				st.Attrs &^= uv.AttrReverse
+			}
+		}
+	})
+}
+
+// reverseRectAttrs 反转区域中单元格由 SGR 参数 attrs 指定的属性。只支持粗体、
+// 下划线、闪烁和反显；0 反转所有这些属性。区域的范围由 DECSACE 决定，参见
+// [Emulator.attrArea]。
+// 这相当于 DECRARA。
+func (e *Emulator) reverseRectAttrs(area uv.Rectangle, attrs []int) {
+	e.updateAttrs(area, func(st *uv.Style) {
+		for _, a := range attrs {
+			switch a {
+			case 0:
+				st.Attrs ^= uv.AttrBold | uv.AttrBlink | uv.AttrReverse
+				toggleUnderline(st)
+			case 1:
+				st.Attrs ^= uv.AttrBold
+			case 4:
+				toggleUnderline(st)
+			case 5:
+				st.Attrs ^= uv.AttrBlink
+			case 7:
+				st.Attrs ^= uv.AttrReverse
+			}
+		}
+	})
+}
+
+// updateAttrs 对 DECSACE 决定的区域中每个单元格的样式调用 fn。
+func (e *Emulator) updateAttrs(area uv.Rectangle, fn func(*uv.Style)) {
+	for _, r := range e.attrArea(area) {
+		e.scr.updateArea(r, func(c *uv.Cell) {
+			fn(&c.Style)
+		})
+	}
+}
+
+// toggleUnderline 反转样式的下划线。
+func toggleUnderline(st *uv.Style) {
+	if st.Underline == uv.UnderlineNone {
+		st.Underline = uv.UnderlineSingle
+	} else {
+		st.Underline = uv.UnderlineNone
+	}
+}
+
+// attrArea 返回 DECCARA 和 DECRARA 影响的区域。在矩形模式下，这就是 area；
+// 在流模式下（默认），区域从第一行的左边界延伸到行尾，中间的行是整行，最后
+// 一行从行首延伸到右边界。
+// 参见 DECSACE。
+func (e *Emulator) attrArea(area uv.Rectangle) []uv.Rectangle {
+	if e.rectExtent || area.Dy() == 1 {
+		return []uv.Rectangle{area}
+	}
+	w := e.Width()
+	areas := []uv.Rectangle{uv.Rect(area.Min.X, area.Min.Y, w-area.Min.X, 1)}
+	if area.Dy() > 2 {
+		areas = append(areas, uv.Rect(0, area.Min.Y+1, w, area.Dy()-2))
+	}
+	return append(areas, uv.Rect(0, area.Max.Y-1, area.Max.X, 1))
+}
+
+// reportRectChecksum 回复矩形区域的校验和。校验和是区域中每个字符的码位及其
+// 属性之和的相反数，与 VT420 和 xterm 相同。
+// 这相当于 DECRQCRA。
+func (e *Emulator) reportRectChecksum(id int, area uv.Rectangle) {
+	var sum int
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			c := e.scr.CellAt(x, y)
+			if c == nil || c.Width == 0 {
+				continue
+			}
+			r, _ := utf8.DecodeRuneInString(c.Content)
+			if c.Content == "" {
+				r = ' '
+			}
+			sum += int(r)
+			if c.Style.Underline != uv.UnderlineNone {
+				sum += 0x10
+			}
+			if c.Style.Attrs&uv.AttrReverse != 0 {
+				sum += 0x20
+			}
+			if c.Style.Attrs&uv.AttrBlink != 0 {
+				sum += 0x40
+			}
+			if c.Style.Attrs&uv.AttrBold != 0 {
+				sum += 0x80
+			}
+		}
+	}
+	_, _ = io.WriteString(e.pw, fmt.Sprintf("\x1bP%d!~%04X\x1b\\", id, -sum&0xffff))
+}
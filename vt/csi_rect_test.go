@@ -0,0 +1,124 @@
+package vt
+
+import (
+	"reflect"
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+func TestRectFillErase(t *testing.T) {
+	term := newTestTerminal(t, 5, 3)
+	term.WriteString("\x1b[31m\x1b[36;1;2;2;4$x")
+	want := []string{" $$$ ", " $$$ ", "     "}
+	if got := termText(term); !reflect.DeepEqual(got, want) {
+		t.Fatalf("DECFRA: got %q, want %q", got, want)
+	}
+	if c := term.CellAt(1, 0); c.Style.Fg == nil {
+		t.Errorf("DECFRA: filled cell has no foreground")
+	}
+
+	term.WriteString("\x1b[1;3;2;3$z")
+	want = []string{" $ $ ", " $ $ ", "     "}
+	if got := termText(term); !reflect.DeepEqual(got, want) {
+		t.Errorf("DECERA: got %q, want %q", got, want)
+	}
+
+	// Selective erase keeps the style of the erased cells.
+	term.WriteString("\x1b[1;1;1;2${")
+	want = []string{"   $ ", " $ $ ", "     "}
+	if got := termText(term); !reflect.DeepEqual(got, want) {
+		t.Errorf("DECSERA: got %q, want %q", got, want)
+	}
+	if c := term.CellAt(1, 0); c.Style.Fg == nil {
+		t.Errorf("DECSERA: erased cell lost its style")
+	}
+
+	// Non-graphic characters are ignored.
+	term.WriteString("\x1b[10$x")
+	if got := termText(term); !reflect.DeepEqual(got, want) {
+		t.Errorf("DECFRA with a control character: got %q, want %q", got, want)
+	}
+}
+
+func TestRectCopy(t *testing.T) {
+	term := newTestTerminal(t, 6, 3)
+	term.WriteString("abc\r\ndef")
+
+	// Overlapping copy one cell to the right and down.
+	term.WriteString("\x1b[1;1;2;3;1;2;2;1$v")
+	want := []string{"abc   ", "dabc  ", " def  "}
+	if got := termText(term); !reflect.DeepEqual(got, want) {
+		t.Errorf("DECCRA: got %q, want %q", got, want)
+	}
+}
+
+func TestRectAttributes(t *testing.T) {
+	term := newTestTerminal(t, 4, 3)
+	term.WriteString("\x1b[1;1;3;4$x")
+
+	bold := func() (cells []bool) {
+		for y := range 3 {
+			for x := range 4 {
+				cells = append(cells, term.CellAt(x, y).Style.Attrs&uv.AttrBold != 0)
+			}
+		}
+		return cells
+	}
+
+	// Stream extent: from (2, 1) to the end of the line and to (3, 3).
+	term.WriteString("\x1b[1;2;3;3;1$r")
+	want := []bool{
+		false, true, true, true,
+		true, true, true, true,
+		true, true, true, false,
+	}
+	if got := bold(); !reflect.DeepEqual(got, want) {
+		t.Errorf("DECCARA stream: got %v, want %v", got, want)
+	}
+
+	// Rectangle extent reverses only the rectangle.
+	term.WriteString("\x1b[2*x\x1b[1;2;3;3;1$t")
+	want = []bool{
+		false, false, false, true,
+		true, false, false, true,
+		true, false, false, false,
+	}
+	if got := bold(); !reflect.DeepEqual(got, want) {
+		t.Errorf("DECRARA rectangle: got %v, want %v", got, want)
+	}
+
+	term.WriteString("\x1b[$r")
+	for i, b := range bold() {
+		if b {
+			t.Fatalf("DECCARA 0: cell %d is still bold", i)
+		}
+	}
+}
+
+func TestInsertDeleteColumn(t *testing.T) {
+	term := newTestTerminal(t, 5, 3)
+	term.WriteString("abcde\r\nfghij\r\nklmno")
+	term.WriteString("\x1b[1;2r\x1b[1;2H\x1b[2'}")
+	want := []string{"a  bc", "f  gh", "klmno"}
+	if got := termText(term); !reflect.DeepEqual(got, want) {
+		t.Errorf("DECIC: got %q, want %q", got, want)
+	}
+
+	term.WriteString("\x1b[1;1H\x1b[3'~")
+	want = []string{"bc   ", "gh   ", "klmno"}
+	if got := termText(term); !reflect.DeepEqual(got, want) {
+		t.Errorf("DECDC: got %q, want %q", got, want)
+	}
+}
+
+func TestRectChecksum(t *testing.T) {
+	term := newTestTerminal(t, 5, 2)
+	term.WriteString("AB\x1b[1mC")
+
+	got := readInput(t, term, func() { term.WriteString("\x1b[7;1;1;1;1;3*y") })
+	// -('A' + 'B' + 'C' + 0x80) & 0xffff
+	if want := "\x1bP7!~FEBA\x1b\\"; got != want {
+		t.Errorf("DECRQCRA: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,96 @@
+package vt
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/purpose168/charm-experimental-packages-cn/ansi"
+)
+
+// 默认的终端名称和版本，用于回复 [ansi.XTVERSION] 请求。
+const (
+	DefaultTerminalName    = "vt"
+	DefaultTerminalVersion = "1.0"
+)
+
+// unitID 是终端的单元 ID，用于回复 [ansi.DA3] 请求。
+const unitID = "00000000"
+
+// SetTerminalVersion 设置用于回复 [ansi.XTVERSION] 请求的终端名称和版本。
+// 回复的格式与 xterm 相同，为 "name(version)"；如果 version 为空，则只回复
+// 名称。
+func (e *Emulator) SetTerminalVersion(name, version string) {
+	e.termName, e.termVersion = name, version
+}
+
+// reportNameVersion 回复终端的名称和版本。这相当于 [ansi.XTVERSION]。
+func (e *Emulator) reportNameVersion() {
+	text := e.termName
+	if e.termVersion != "" {
+		text += "(" + e.termVersion + ")"
+	}
+	_, _ = io.WriteString(e.pw, "\x1bP>|"+text+"\x1b\\")
+}
+
+// reportTabStops 回复以斜杠分隔的制表位列表，列从 1 开始。
+// 这相当于 [ansi.DECTABSR]。
+func (e *Emulator) reportTabStops() {
+	stops := tabStopList(e.tabstops, e.Width())
+	for i := range stops {
+		stops[i]++
+	}
+	_, _ = io.WriteString(e.pw, ansi.DECTABSR(stops...))
+}
+
+// reportCursorInformation 回复光标信息报告，包括光标位置、笔的视觉属性、原点
+// 模式、单次移位和待换行状态，以及字符集的指定和调用状态。
+// 这相当于 DECCIR。
+func (e *Emulator) reportCursorInformation() {
+	x, y := e.scr.CursorPosition()
+	pen := e.scr.cursorPen()
+
+	rend := byte(0x40)
+	if pen.Attrs&uv.AttrReverse != 0 {
+		rend |= 8
+	}
+	if pen.Attrs&uv.AttrBlink != 0 {
+		rend |= 4
+	}
+	if pen.Underline != uv.UnderlineNone {
+		rend |= 2
+	}
+	if pen.Attrs&uv.AttrBold != 0 {
+		rend |= 1
+	}
+
+	flags := byte(0x40)
+	if e.isModeSet(ansi.ModeOrigin) {
+		flags |= 1
+	}
+	switch e.gsingle {
+	case 2:
+		flags |= 2
+	case 3:
+		flags |= 4
+	}
+	if e.atPhantom {
+		flags |= 8
+	}
+
+	// Scss 的第 n 位表示 Gn 是否是 96 字符集，Sdesig 是 G0 到 G3 的指示符。
+	css := byte(0x40)
+	var desig strings.Builder
+	for i, cs := range e.charsets {
+		d, is96 := charsetDesignator(cs)
+		if is96 {
+			css |= 1 << i
+		}
+		desig.WriteString(d)
+	}
+
+	// 我们不支持页面和字符保护属性。
+	_, _ = io.WriteString(e.pw, fmt.Sprintf("\x1bP1$u%d;%d;1;%c;%c;%c;%d;%d;%c;%s\x1b\\",
+		y+1, x+1, rend, 0x40, flags, e.gl, e.gr, css, desig.String()))
+}
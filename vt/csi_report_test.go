@@ -0,0 +1,60 @@
+package vt
+
+import (
+	"testing"
+
+	"github.com/purpose168/charm-experimental-packages-cn/ansi"
+)
+
+func TestReportTabStops(t *testing.T) {
+	term := newTestTerminal(t, 20, 2)
+	query := func() string {
+		return readInput(t, term, func() { term.WriteString(ansi.DECRQPSR(2)) })
+	}
+
+	if got, want := query(), "\x1bP2$u1/9/17\x1b\\"; got != want {
+		t.Errorf("DECTABSR = %q, want %q", got, want)
+	}
+	term.WriteString("\x1b[3g\x1b[1;5H\x1bH")
+	if got, want := query(), "\x1bP2$u5\x1b\\"; got != want {
+		t.Errorf("DECTABSR = %q, want %q", got, want)
+	}
+}
+
+func TestReportNameVersion(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	query := func() string {
+		return readInput(t, term, func() { term.WriteString(ansi.XTVERSION) })
+	}
+
+	if got, want := query(), "\x1bP>|vt(1.0)\x1b\\"; got != want {
+		t.Errorf("XTVERSION = %q, want %q", got, want)
+	}
+	term.SetTerminalVersion("myterm", "")
+	if got, want := query(), "\x1bP>|myterm\x1b\\"; got != want {
+		t.Errorf("XTVERSION = %q, want %q", got, want)
+	}
+}
+
+func TestTertiaryDeviceAttributes(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	got := readInput(t, term, func() { term.WriteString(ansi.RequestTertiaryDeviceAttributes) })
+	if want := "\x1bP!|00000000\x1b\\"; got != want {
+		t.Errorf("DA3 = %q, want %q", got, want)
+	}
+}
+
+func TestRequestModeInBandResize(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	query := func() string {
+		return readInput(t, term, func() { term.WriteString("\x1b[?2048$p") })
+	}
+
+	if got, want := query(), "\x1b[?2048;2$y"; got != want {
+		t.Errorf("DECRQM = %q, want %q", got, want)
+	}
+	readInput(t, term, func() { term.WriteString("\x1b[?2048h") })
+	if got, want := query(), "\x1b[?2048;1$y"; got != want {
+		t.Errorf("DECRQM = %q, want %q", got, want)
+	}
+}
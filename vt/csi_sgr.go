@@ -9,3 +9,40 @@ import (
 func (e *Emulator) handleSgr(params ansi.Params) {
 	uv.ReadStyle(params, &e.scr.cur.Pen)
 }
+
+// styleParser 解析以 SGR 序列编码的样式，参见 [uv.Style.String]，并缓存解析
+// 结果。
+type styleParser struct {
+	p     *ansi.Parser
+	pen   uv.Style
+	cache map[string]uv.Style
+}
+
+// newStyleParser 创建一个样式解析器。
+func newStyleParser() *styleParser {
+	sp := &styleParser{cache: map[string]uv.Style{}}
+	sp.p = ansi.NewParser()
+	sp.p.SetHandler(ansi.Handler{
+		HandleCsi: func(cmd ansi.Cmd, params ansi.Params) {
+			if cmd == 'm' {
+				uv.ReadStyle(params, &sp.pen)
+			}
+		},
+	})
+	return sp
+}
+
+// parse 解析 SGR 序列 seq。空字符串表示默认样式。
+func (sp *styleParser) parse(seq string) uv.Style {
+	if seq == "" {
+		return uv.Style{}
+	}
+	if st, ok := sp.cache[seq]; ok {
+		return st
+	}
+	sp.pen = uv.Style{}
+	sp.p.Reset()
+	sp.p.Parse([]byte(seq))
+	sp.cache[seq] = sp.pen
+	return sp.pen
+}
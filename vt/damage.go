@@ -63,9 +63,123 @@ type MoveDamage struct {
 	Src, Dst uv.Rectangle
 }
 
+// Bounds 返回损坏区域的边界，即源区域和目标区域的并集。
+func (d MoveDamage) Bounds() uv.Rectangle {
+	return d.Src.Union(d.Dst)
+}
+
 // ScrollDamage 表示一个滚动的区域。
-// 该区域按给定的增量滚动。
+// 该区域按给定的增量滚动。正的 Dy 表示内容向下移动，负的 Dy 表示内容向上
+// 移动。滚入区域的空白行会单独报告为 [RectDamage]。
 type ScrollDamage struct {
 	uv.Rectangle
 	Dx, Dy int
 }
+
+// DrainDamage 返回并清除自上次调用以来累积的损坏，按发生的顺序排列。损坏
+// 使用屏幕坐标，并且需要按顺序应用，因为 [ScrollDamage] 和 [MoveDamage]
+// 会移动之前报告的区域。相邻的单元格和矩形会被合并；当损坏过多时，它们会被
+// 合并为一个 [ScreenDamage]。
+//
+// 如果设置了 [Callbacks.Damage]，损坏会在每次写入后传递给回调，而不会留在
+// 队列中。当同步输出帧打开时，损坏会被保留到帧结束，参见
+// [Emulator.SyncFrameOpen]。
+func (e *Emulator) DrainDamage() []Damage {
+	if e.SyncFrameOpen() {
+		return nil
+	}
+	return e.damage.take()
+}
+
+// flushDamage 将累积的损坏传递给 [Callbacks.Damage] 回调。
+func (e *Emulator) flushDamage() {
+	if e.cb.Damage == nil || e.SyncFrameOpen() {
+		return
+	}
+	for _, d := range e.damage.take() {
+		e.cb.Damage(d)
+	}
+}
+
+// damageScreen 将整个当前屏幕标记为损坏。
+func (e *Emulator) damageScreen() {
+	e.damage.add(ScreenDamage{Width: e.Width(), Height: e.Height()}, e.Bounds())
+}
+
+// maxDamage 是队列中损坏的最大数量。超过时，队列会合并为一个 [ScreenDamage]。
+const maxDamage = 256
+
+// damageQueue 收集并合并屏幕的损坏。
+type damageQueue struct {
+	list []Damage
+}
+
+// add 将损坏加入队列，并尽可能与上一个损坏合并。bounds 是屏幕的边界。
+func (q *damageQueue) add(d Damage, bounds uv.Rectangle) {
+	if len(q.list) > 0 {
+		if _, ok := q.list[0].(ScreenDamage); ok {
+			// 整个屏幕都需要重绘。
+			return
+		}
+	}
+
+	switch d := d.(type) {
+	case ScreenDamage:
+		q.list = append(q.list[:0], d)
+		return
+	case CellDamage, RectDamage:
+		r := d.Bounds().Intersect(bounds)
+		if r.Empty() {
+			return
+		}
+		if r == bounds {
+			q.list = append(q.list[:0], ScreenDamage{Width: bounds.Dx(), Height: bounds.Dy()})
+			return
+		}
+		if n := len(q.list); n > 0 && isAreaDamage(q.list[n-1]) {
+			last := q.list[n-1].Bounds()
+			if r.In(last) {
+				return
+			}
+			sameRows := last.Min.Y == r.Min.Y && last.Max.Y == r.Max.Y &&
+				r.Min.X <= last.Max.X && r.Max.X >= last.Min.X
+			sameCols := last.Min.X == r.Min.X && last.Max.X == r.Max.X &&
+				r.Min.Y <= last.Max.Y && r.Max.Y >= last.Min.Y
+			if sameRows || sameCols {
+				q.list = q.list[:n-1]
+				q.add(RectDamage(last.Union(r)), bounds)
+				return
+			}
+		}
+	case ScrollDamage:
+		if n := len(q.list); n > 0 {
+			if last, ok := q.list[n-1].(ScrollDamage); ok && last.Rectangle == d.Rectangle && last.Dx == d.Dx {
+				// 连续滚动同一区域合并为一次滚动。
+				last.Dy += d.Dy
+				q.list[n-1] = last
+				return
+			}
+		}
+	}
+
+	q.list = append(q.list, d)
+	if len(q.list) > maxDamage {
+		q.list = append(q.list[:0], ScreenDamage{Width: bounds.Dx(), Height: bounds.Dy()})
+	}
+}
+
+// take 返回并清除队列中的损坏。
+func (q *damageQueue) take() []Damage {
+	list := q.list
+	q.list = nil
+	return list
+}
+
+// isAreaDamage 报告损坏是否只表示一个需要重绘的区域。
+func isAreaDamage(d Damage) bool {
+	switch d.(type) {
+	case CellDamage, RectDamage:
+		return true
+	}
+	return false
+}
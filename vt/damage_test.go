@@ -0,0 +1,171 @@
+package vt
+
+import (
+	"reflect"
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+func TestDamage(t *testing.T) {
+	cases := []struct {
+		name  string
+		setup string
+		input string
+		want  []Damage
+	}{
+		{
+			name:  "prints coalesce",
+			input: "hello",
+			want:  []Damage{RectDamage(uv.Rect(0, 0, 5, 1))},
+		},
+		{
+			name:  "full lines coalesce",
+			input: "0123456789\r\n0123456789",
+			want:  []Damage{RectDamage(uv.Rect(0, 0, 10, 2))},
+		},
+		{
+			name:  "wide cell",
+			setup: "\x1b[2;3H",
+			input: "你",
+			want:  []Damage{CellDamage{X: 2, Y: 1, Width: 2}},
+		},
+		{
+			name:  "erase line",
+			setup: "\x1b[2;3H",
+			input: "\x1b[K",
+			want:  []Damage{RectDamage(uv.Rect(2, 1, 8, 1))},
+		},
+		{
+			name:  "clear screen",
+			input: "ab\x1b[2J",
+			want:  []Damage{ScreenDamage{Width: 10, Height: 4}},
+		},
+		{
+			name:  "insert character",
+			setup: "\x1b[1;3H",
+			input: "\x1b[2@",
+			want: []Damage{
+				MoveDamage{Src: uv.Rect(2, 0, 6, 1), Dst: uv.Rect(4, 0, 6, 1)},
+				RectDamage(uv.Rect(2, 0, 2, 1)),
+			},
+		},
+		{
+			name:  "delete character",
+			setup: "\x1b[1;3H",
+			input: "\x1b[2P",
+			want: []Damage{
+				MoveDamage{Src: uv.Rect(4, 0, 6, 1), Dst: uv.Rect(2, 0, 6, 1)},
+				RectDamage(uv.Rect(8, 0, 2, 1)),
+			},
+		},
+		{
+			name:  "insert line",
+			setup: "\x1b[2;1H",
+			input: "\x1b[L",
+			want: []Damage{
+				ScrollDamage{Rectangle: uv.Rect(0, 1, 10, 3), Dy: 1},
+				RectDamage(uv.Rect(0, 1, 10, 1)),
+			},
+		},
+		{
+			name:  "scroll region",
+			setup: "\x1b[2;3r",
+			input: "\x1b[S\x1b[S",
+			want: []Damage{
+				ScrollDamage{Rectangle: uv.Rect(0, 1, 10, 2), Dy: -1},
+				RectDamage(uv.Rect(0, 2, 10, 1)),
+				ScrollDamage{Rectangle: uv.Rect(0, 1, 10, 2), Dy: -1},
+				RectDamage(uv.Rect(0, 2, 10, 1)),
+			},
+		},
+		{
+			name:  "index at the bottom",
+			setup: "\x1b[4;1H",
+			input: "\n",
+			want: []Damage{
+				ScrollDamage{Rectangle: uv.Rect(0, 0, 10, 4), Dy: -1},
+				RectDamage(uv.Rect(0, 3, 10, 1)),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			term := newTestTerminal(t, 10, 4)
+			term.WriteString(c.setup)
+			term.DrainDamage()
+			term.WriteString(c.input)
+			if got := term.DrainDamage(); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("damage = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestDamageCallbackAndSync(t *testing.T) {
+	term := newTestTerminal(t, 10, 4)
+	var got []Damage
+	term.SetCallbacks(Callbacks{Damage: func(d Damage) { got = append(got, d) }})
+
+	term.WriteString("ab")
+	if want := []Damage{RectDamage(uv.Rect(0, 0, 2, 1))}; !reflect.DeepEqual(got, want) {
+		t.Errorf("damage = %v, want %v", got, want)
+	}
+
+	got = nil
+	term.WriteString("\x1b[?2026hcd")
+	if got != nil {
+		t.Errorf("damage reported while the frame is open: %v", got)
+	}
+	term.WriteString("ef\x1b[?2026l")
+	if want := []Damage{RectDamage(uv.Rect(2, 0, 4, 1))}; !reflect.DeepEqual(got, want) {
+		t.Errorf("damage after the frame = %v, want %v", got, want)
+	}
+}
+
+func TestDamageCallbackScrollViewport(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	term.WriteString("a\r\nb\r\nc")
+	var got []Damage
+	term.SetCallbacks(Callbacks{Damage: func(d Damage) { got = append(got, d) }})
+
+	// Scrolling the viewport is reported without another write.
+	term.ScrollViewport(1)
+	if want := []Damage{ScreenDamage{Width: 10, Height: 2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("damage = %v, want %v", got, want)
+	}
+}
+
+func TestDamageCallbackScrolledBackWrite(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	term.WriteString("a\r\nb\r\nc")
+	term.ScrollViewport(1)
+
+	// While the viewport is scrolled back, output that scrolls the screen
+	// keeps the viewport in place but must still be reported once the write
+	// has been applied, not once per line feed.
+	var got []string
+	term.SetCallbacks(Callbacks{Damage: func(Damage) { got = append(got, term.String()) }})
+	term.WriteString("\r\nd\r\ne")
+	if len(got) == 0 {
+		t.Fatal("no damage reported")
+	}
+	for _, s := range got {
+		if want := term.String(); s != want {
+			t.Errorf("damage reported with screen %q, want %q", s, want)
+		}
+	}
+}
+
+func TestDamageOverflow(t *testing.T) {
+	term := newTestTerminal(t, 80, 24)
+	term.DrainDamage()
+	for i := range 300 {
+		term.WriteString("\x1b[" + string(rune('1'+i%9)) + ";" + string(rune('1'+i%7)) + "Hx\x1b[1B\x1b[5Cy")
+	}
+	got := term.DrainDamage()
+	if want := []Damage{ScreenDamage{Width: 80, Height: 24}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("damage = %v, want %v", got, want)
+	}
+}
@@ -1,6 +1,14 @@
 package vt
 
-import "github.com/purpose168/charm-experimental-packages-cn/ansi"
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/purpose168/charm-experimental-packages-cn/ansi"
+)
 
 // handleDcs 处理 DCS（设备控制字符串）转义序列。
 func (e *Emulator) handleDcs(cmd ansi.Cmd, params ansi.Params, data []byte) {
@@ -33,3 +41,81 @@ func (e *Emulator) handlePm(data []byte) {
 		e.logf("未处理的序列: PM %q", data)
 	}
 }
+
+// handleRequestStatusString 处理请求状态字符串 [ansi.DECRQSS] 序列并报告请求的
+// 设置。有效的请求以 DCS 1 $ r Pt ST 回复，其中 Pt 是设置该状态的控制序列
+// 的参数和结束字符；无效的请求以 DCS 0 $ r ST 回复。
+func (e *Emulator) handleRequestStatusString(data []byte) {
+	var report string
+	switch string(data) {
+	case "m": // SGR
+		pen := e.scr.cur.Pen
+		params := strings.TrimSuffix(strings.TrimPrefix(pen.String(), "\x1b["), "m")
+		if params == "" {
+			report = "0m"
+		} else {
+			report = "0;" + params + "m"
+		}
+	case "r": // DECSTBM
+		scroll := e.scr.ScrollRegion()
+		report = strconv.Itoa(scroll.Min.Y+1) + ";" + strconv.Itoa(scroll.Max.Y) + "r"
+	case "s": // DECSLRM
+		scroll := e.scr.ScrollRegion()
+		report = strconv.Itoa(scroll.Min.X+1) + ";" + strconv.Itoa(scroll.Max.X) + "s"
+	case " q": // DECSCUSR
+		cur := e.scr.Cursor()
+		n := int(cur.Style)*2 + 1
+		if cur.Steady {
+			n++
+		}
+		report = strconv.Itoa(n) + " q"
+	case "\"p": // DECSCL
+		// 我们报告 VT200 一致性级别和 7 位控制字符，与 [ansi.DA2] 的报告一致。
+		report = "62;1\"p"
+	default:
+		_, _ = io.WriteString(e.pw, "\x1bP0$r\x1b\\")
+		return
+	}
+
+	_, _ = io.WriteString(e.pw, "\x1bP1$r"+report+"\x1b\\")
+}
+
+// SetTermcap 设置用于回复 [ansi.XTGETTCAP] 请求的 Termcap/Terminfo 功能表。
+// 键是功能名称，例如 "Tc"、"RGB"、"Smulx" 或 "colors"，值是功能的原始值。
+// 布尔功能使用空字符串。表中不存在的功能会被报告为无效。
+func (e *Emulator) SetTermcap(caps map[string]string) {
+	e.termcap = make(map[string]string, len(caps))
+	for k, v := range caps {
+		e.termcap[k] = v
+	}
+}
+
+// handleRequestTermcap 处理请求 Termcap/Terminfo 字符串 [ansi.XTGETTCAP]
+// 序列。每个请求的功能分别回复：已知的功能以 DCS 1 + r Pt=Pv ST 回复，
+// 布尔功能省略 =Pv；未知的功能以 DCS 0 + r Pt ST 回复。名称和值都以十六进制
+// 编码。
+func (e *Emulator) handleRequestTermcap(data []byte) {
+	for _, name := range bytes.Split(data, []byte{';'}) {
+		if len(name) == 0 {
+			continue
+		}
+
+		capName, err := hex.DecodeString(string(name))
+		if err != nil {
+			_, _ = io.WriteString(e.pw, "\x1bP0+r"+string(name)+"\x1b\\")
+			continue
+		}
+
+		val, ok := e.termcap[string(capName)]
+		if !ok {
+			_, _ = io.WriteString(e.pw, "\x1bP0+r"+string(name)+"\x1b\\")
+			continue
+		}
+
+		report := strings.ToUpper(hex.EncodeToString(capName))
+		if val != "" {
+			report += "=" + strings.ToUpper(hex.EncodeToString([]byte(val)))
+		}
+		_, _ = io.WriteString(e.pw, "\x1bP1+r"+report+"\x1b\\")
+	}
+}
@@ -0,0 +1,45 @@
+package vt
+
+import "testing"
+
+func TestRequestStatusString(t *testing.T) {
+	cases := []struct {
+		name  string
+		setup string
+		req   string
+		want  string
+	}{
+		{"sgr default", "", "m", "\x1bP1$r0m\x1b\\"},
+		{"sgr", "\x1b[1;31m", "m", "\x1bP1$r0;1;31m\x1b\\"},
+		{"decstbm", "\x1b[2;4r", "r", "\x1bP1$r2;4r\x1b\\"},
+		{"decscusr", "\x1b[4 q", " q", "\x1bP1$r4 q\x1b\\"},
+		{"decscl", "", "\"p", "\x1bP1$r62;1\"p\x1b\\"},
+		{"invalid", "", "x", "\x1bP0$r\x1b\\"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			term := newTestTerminal(t, 10, 5)
+			term.WriteString(c.setup)
+			got := readInput(t, term, func() { term.WriteString("\x1bP$q" + c.req + "\x1b\\") })
+			if got != c.want {
+				t.Errorf("DECRQSS %q = %q, want %q", c.req, got, c.want)
+			}
+		})
+	}
+}
+
+func TestRequestTermcap(t *testing.T) {
+	term := newTestTerminal(t, 10, 5)
+	term.SetTermcap(map[string]string{
+		"Tc":     "",
+		"colors": "256",
+	})
+
+	// "Tc", "colors" and "xx".
+	got := readInput(t, term, func() { term.WriteString("\x1bP+q5463;636f6c6f7273;7878\x1b\\") })
+	want := "\x1bP1+r5463\x1b\\" + "\x1bP1+r636F6C6F7273=323536\x1b\\" + "\x1bP0+r7878\x1b\\"
+	if got != want {
+		t.Errorf("XTGETTCAP = %q, want %q", got, want)
+	}
+}
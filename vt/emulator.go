@@ -3,6 +3,10 @@ package vt
 import (
 	"image/color"
 	"io"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	uv "github.com/charmbracelet/ultraviolet"
 	"github.com/charmbracelet/ultraviolet/screen"
@@ -19,13 +23,28 @@ type Logger interface {
 type Emulator struct {
 	handlers
 
-	// 终端的256个索引颜色。
-	colors [256]color.Color
+	// 终端的256个索引颜色。colors 是应用程序通过 OSC 4 设置的颜色，
+	// defaultColors 是宿主设置的默认颜色，OSC 104 和 RIS 会恢复为默认颜色。
+	// nil 表示使用下一级的颜色，最终是 xterm 的默认调色板。
+	colors        [256]color.Color
+	defaultColors [256]color.Color
+	// 终端的特殊颜色，例如粗体和下划线颜色。
+	specialColors [5]color.Color
 
 	// 主屏幕和备用屏幕，以及指向当前活动屏幕的指针。
 	scrs [2]Screen
 	scr  *Screen
 
+	// 主屏幕的回滚缓冲区，以及视口向其滚动的行数。
+	scrollback   *Scrollback
+	scrollOffset int
+
+	// lineOffset 是从主屏幕顶部滚出的总行数，用于计算绝对行号。
+	lineOffset int
+
+	// commands 是通过 shell 集成标记的命令。
+	commands []Command
+
 	// 字符集
 	charsets [4]CharSet
 
@@ -63,16 +82,73 @@ type Emulator struct {
 	pr *io.PipeReader
 	pw *io.PipeWriter
 
-	// GL和GR字符集标识符。
+	// GL和GR字符集标识符。解析器将 0x80 以上的字节按 UTF-8 解码，因此 GR
+	// 不会映射任何输出；gr 只记录锁定移位的状态，由 DECCIR 报告。
 	gl, gr  int
 	gsingle int // 临时选择GL或GR
 
-	// 指示终端是否已关闭。
-	closed bool
+	// escInter 是当前 ESC 序列的所有中间字节。解析器只保留最后一个中间字节，
+	// 但一些 SCS 序列有两个中间字节。
+	escInter []byte
+
+	// 指示终端是否已关闭。它可以在其他 goroutine 调用 Read 时被设置。
+	closed atomic.Bool
 
 	// atPhantom 指示光标是否越界。
 	// 当为true时，写入字符时，光标会移动到下一行。
 	atPhantom bool
+
+	// modifyOtherKeys 是 XTerm modifyOtherKeys 的级别。
+	modifyOtherKeys int
+
+	// termcap 是用于回复 XTGETTCAP 请求的 Termcap/Terminfo 功能表。
+	termcap map[string]string
+
+	// 用于回复 XTVERSION 请求的终端名称和版本。
+	termName, termVersion string
+
+	// 单元格的像素大小，用于将图像映射到单元格。
+	cellWidth, cellHeight int
+
+	// Kitty 图形协议的状态：存储的图像及其传输顺序和总字节数、虚拟放置、
+	// 下一个自动分配的图像 ID，以及正在进行的分块传输。
+	kittyImages   map[int]*kittyImage
+	kittyOrder    []int
+	kittyStorage  int
+	kittyVirtuals []kittyVirtual
+	kittyNextID   int
+	kittyTransfer *kittyTransfer
+
+	// clipboardPolicy 控制 OSC 52 剪贴板的访问。
+	clipboardPolicy ClipboardPolicy
+
+	// 同步输出帧的开始时间和超时时间。
+	syncStart   time.Time
+	syncTimeout time.Duration
+
+	// sel 是当前的选择，如果没有选择则为 nil。wordDelims 是单词选择使用的
+	// 分隔符。
+	sel        *selection
+	wordDelims string
+
+	// rectExtent 报告 DECCARA 和 DECRARA 是否作用于矩形区域，而不是从起点到
+	// 终点的字符流，参见 DECSACE。
+	rectExtent bool
+
+	// progress 是应用程序最后报告的进度，notifications 是正在分块接收的
+	// OSC 99 通知，按开始接收的顺序排列。
+	progress      Progress
+	notifications []*pendingNotification
+
+	// highlight 是进行中的高亮鼠标跟踪，如果没有则为 nil。
+	highlight *highlightTracking
+
+	// linkPattern 匹配作为隐式超链接的纯文本 URL，如果为 nil 则不识别隐式
+	// 超链接。
+	linkPattern *regexp.Regexp
+
+	// damage 收集两个屏幕的损坏。
+	damage damageQueue
 }
 
 var _ Terminal = (*Emulator)(nil)
@@ -85,6 +161,14 @@ func NewEmulator(w, h int) *Emulator {
 	t.scr = &t.scrs[0] // 默认使用主屏幕
 	t.scrs[0].cb = &t.cb // 设置主屏幕的回调
 	t.scrs[1].cb = &t.cb // 设置备用屏幕的回调
+	t.scrs[0].damage = &t.damage // 设置主屏幕的损坏队列
+	t.scrs[1].damage = &t.damage // 设置备用屏幕的损坏队列
+	t.scrollback = NewScrollback(DefaultScrollbackSize) // 创建主屏幕的回滚缓冲区
+	t.cellWidth, t.cellHeight = DefaultCellWidth, DefaultCellHeight // 设置默认单元格大小
+	t.clipboardPolicy = DefaultClipboardPolicy // 设置默认剪贴板策略
+	t.syncTimeout = DefaultSyncTimeout // 设置默认同步输出超时时间
+	t.wordDelims = DefaultWordDelimiters // 设置默认单词分隔符
+	t.termName, t.termVersion = DefaultTerminalName, DefaultTerminalVersion // 设置默认终端名称和版本
 	t.parser = ansi.NewParser() // 创建ANSI解析器
 	t.parser.SetParamsSize(parser.MaxParamsSize) // 设置参数大小
 	t.parser.SetDataSize(1024 * 1024 * 4) // 4MB data buffer // 设置数据缓冲区大小
@@ -101,6 +185,7 @@ func NewEmulator(w, h int) *Emulator {
 	})
 	t.pr, t.pw = io.Pipe() // 创建I/O管道
 	t.resetModes() // 重置终端模式
+	t.gr = 2 // 与 VT220 和 xterm 相同，默认将 G2 调用到 GR
 	t.tabstops = uv.DefaultTabStops(w) // 设置默认制表位
 	t.registerDefaultHandlers() // 注册默认处理器
 
@@ -124,8 +209,12 @@ func (e *Emulator) SetCallbacks(cb Callbacks) {
 	e.scrs[1].cb = &e.cb
 }
 
-// Touched 返回当前屏幕缓冲区中被修改的行。
+// Touched 返回当前屏幕缓冲区中被修改的行。当同步输出帧打开时，它返回 nil，
+// 参见 [Emulator.SyncFrameOpen]。
 func (e *Emulator) Touched() []*uv.LineData {
+	if e.SyncFrameOpen() {
+		return nil
+	}
 	return e.scr.Touched()
 }
 
@@ -136,8 +225,21 @@ func (e *Emulator) String() string {
 }
 
 // Render 将终端屏幕的快照渲染为字符串，样式和链接编码为ANSI转义序列。
+// 双宽和双高的行以设置其尺寸属性的转义序列开头，并且只包含该行可见的列。
 func (e *Emulator) Render() string {
-	return e.scr.buf.Render()
+	var b strings.Builder
+	for y := range e.scr.Height() {
+		if y > 0 {
+			b.WriteByte('\n')
+		}
+		line := e.scr.buf.Line(y)
+		if attr := e.scr.LineAttr(y); attr.IsDoubleWidth() {
+			b.WriteString(attr.String())
+			line = line[:min(len(line), e.scr.lineWidth(y))]
+		}
+		b.WriteString(line.Render())
+	}
+	return b.String()
 }
 
 var _ uv.Screen = (*Emulator)(nil)
@@ -158,7 +260,9 @@ func (e *Emulator) SetCell(x, y int, c *uv.Cell) {
 	e.scr.SetCell(x, y, c)
 }
 
-// WidthMethod 返回终端使用的宽度计算方法。
+// WidthMethod 返回终端使用的宽度计算方法。如果设置了 [ansi.ModeUnicodeCore]
+// (?2027)，则为 [ansi.GraphemeWidth]，否则为 [ansi.WcWidth]。模式可以在运行
+// 时切换，切换只影响之后写入的文本。
 func (e *Emulator) WidthMethod() uv.WidthMethod {
 	if e.isModeSet(ansi.ModeUnicodeCore) {
 		return ansi.GraphemeWidth
@@ -167,17 +271,21 @@ func (e *Emulator) WidthMethod() uv.WidthMethod {
 }
 
 // Draw 实现[uv.Drawable]接口。
+// 当视口向回滚缓冲区滚动时，参见 [Emulator.SetScrollOffset]，顶部的行从回滚缓冲区绘制。
+// 双宽和双高的行的每个单元格占用两倍的列，字符之后的列留空。
 func (e *Emulator) Draw(scr uv.Screen, area uv.Rectangle) {
 	bg := uv.EmptyCell
 	bg.Style.Bg = e.BackgroundColor()
 	screen.FillArea(scr, &bg, area) // 填充背景
-	for y := range e.Touched() {
-		if y < 0 || y >= e.Height() {
-			continue
+	for y := range e.Height() {
+		line := e.viewportLine(y)
+		width, scale := e.Width(), 1
+		if attr := e.viewportLineAttr(y); attr.IsDoubleWidth() {
+			width, scale = attr.columns(width), 2
 		}
-		for x := 0; x < e.Width(); {
+		for x := 0; x < width && x < len(line); {
 			w := 1
-			cell := e.CellAt(x, y)
+			cell := line.At(x)
 			if cell != nil {
 				cell = cell.Clone()
 				if cell.Width > 1 {
@@ -189,13 +297,18 @@ func (e *Emulator) Draw(scr uv.Screen, area uv.Rectangle) {
 				if cell.Style.Fg == nil && e.fgColor != nil {
 					cell.Style.Fg = e.fgColor
 				}
-				scr.SetCell(x+area.Min.X, y+area.Min.Y, cell)
+				scr.SetCell(x*scale+area.Min.X, y+area.Min.Y, cell)
 			}
 			x += w
 		}
 	}
 }
 
+// IsWrapped 报告当前屏幕的第 y 行是否以自动换行（软换行）结束。
+func (e *Emulator) IsWrapped(y int) bool {
+	return e.scr.IsWrapped(y)
+}
+
 // Height 返回终端的高度。
 func (e *Emulator) Height() int {
 	return e.scr.Height()
@@ -213,35 +326,43 @@ func (e *Emulator) CursorPosition() uv.Position {
 }
 
 // Resize 调整终端的大小。
+// 主屏幕会按新的宽度重新换行软换行的行，并与回滚缓冲区交换行；交替屏幕只调整
+// 缓冲区的大小。调整大小会清除当前选择。
 func (e *Emulator) Resize(width int, height int) {
-	x, y := e.scr.CursorPosition()
-	if e.atPhantom {
-		if x < width-1 {
-			e.atPhantom = false
-			x++
+	e.sel = nil
+	e.reflow(width, height)
+
+	if e.scr == &e.scrs[1] {
+		x, y := e.scr.CursorPosition()
+		if e.atPhantom {
+			if x < width-1 {
+				e.atPhantom = false
+				x++
+			}
 		}
-	}
 
-	// 确保光标位置在新的边界内
-	if y < 0 {
-		y = 0
-	}
-	if y >= height {
-		y = height - 1
-	}
-	if x < 0 {
-		x = 0
-	}
-	if x >= width {
-		x = width - 1
-	}
+		// 确保光标位置在新的边界内
+		if y < 0 {
+			y = 0
+		}
+		if y >= height {
+			y = height - 1
+		}
+		if x < 0 {
+			x = 0
+		}
+		if x >= width {
+			x = width - 1
+		}
 
-	// 调整屏幕大小
-	e.scrs[0].Resize(width, height)
-	e.scrs[1].Resize(width, height)
+		e.scrs[1].Resize(width, height)
+		e.setCursor(x, y) // 设置光标位置
+	} else {
+		e.scrs[1].Resize(width, height)
+	}
 	e.tabstops = uv.DefaultTabStops(width) // 重置制表位
-
-	e.setCursor(x, y) // 设置光标位置
+	e.damageScreen()
+	e.flushDamage()
 
 	// 如果启用了带内调整大小模式，发送调整大小事件
 	if e.isModeSet(ansi.ModeInBandResize) {
@@ -251,7 +372,7 @@ func (e *Emulator) Resize(width int, height int) {
 
 // Read 从终端输入缓冲区读取数据。
 func (e *Emulator) Read(p []byte) (n int, err error) {
-	if e.closed {
+	if e.closed.Load() {
 		return 0, io.EOF
 	}
 
@@ -260,23 +381,29 @@ func (e *Emulator) Read(p []byte) (n int, err error) {
 
 // Close 关闭终端。
 func (e *Emulator) Close() error {
-	if e.closed {
+	if !e.closed.CompareAndSwap(false, true) {
 		return nil
 	}
 
-	e.closed = true
 	return e.pw.CloseWithError(io.EOF)
 }
 
 // Write 将数据写入终端输出缓冲区。
 func (e *Emulator) Write(p []byte) (n int, err error) {
-	if e.closed {
+	if e.closed.Load() {
 		return 0, io.ErrClosedPipe
 	}
 
+	e.expireSyncFrame()
 	for i := range p {
 		e.parser.Advance(p[i])
 		state := e.parser.State()
+		if state == parser.EscapeIntermediateState && p[i] >= 0x20 && p[i] <= 0x2f {
+			if e.lastState != parser.EscapeIntermediateState {
+				e.escInter = e.escInter[:0]
+			}
+			e.escInter = append(e.escInter, p[i])
+		}
 		// 如果我们转换到非utf8状态或已写入整个字节切片，则刷新字形
 		if len(e.grapheme) > 0 {
 			if (e.lastState == parser.GroundState && state != parser.Utf8State) || i == len(p)-1 {
@@ -285,6 +412,7 @@ func (e *Emulator) Write(p []byte) (n int, err error) {
 		}
 		e.lastState = state
 	}
+	e.flushDamage()
 	return len(p), nil
 }
 
@@ -413,6 +541,9 @@ func (e *Emulator) IndexedColor(i int) color.Color {
 	}
 
 	c := e.colors[i]
+	if c == nil {
+		c = e.defaultColors[i]
+	}
 	if c == nil {
 		// 返回默认颜色。
 		return ansi.IndexedColor(i) //nolint:gosec
@@ -431,6 +562,43 @@ func (e *Emulator) SetIndexedColor(i int, c color.Color) {
 	e.colors[i] = c
 }
 
+// SetDefaultIndexedColor 设置终端的默认索引颜色。与 [Emulator.SetIndexedColor]
+// 不同，默认颜色不会被 OSC 104 或 RIS 重置，而是它们恢复的颜色。nil 表示使用
+// xterm 的默认颜色。索引必须介于0和255之间。
+func (e *Emulator) SetDefaultIndexedColor(i int, c color.Color) {
+	if i < 0 || i > 255 {
+		return
+	}
+
+	e.defaultColors[i] = c
+}
+
+// 特殊颜色，用于 OSC 5 和 OSC 105。
+const (
+	SpecialColorBold      = iota // 粗体颜色
+	SpecialColorUnderline        // 下划线颜色
+	SpecialColorBlink            // 闪烁颜色
+	SpecialColorReverse          // 反显颜色
+	SpecialColorItalic           // 斜体颜色
+)
+
+// SpecialColor 返回终端的特殊颜色。如果该颜色未设置，则返回 nil。
+// 参见 [SpecialColorBold] 等常量。
+func (e *Emulator) SpecialColor(i int) color.Color {
+	if i < 0 || i >= len(e.specialColors) {
+		return nil
+	}
+	return e.specialColors[i]
+}
+
+// SetSpecialColor 设置终端的特殊颜色。nil 表示重置为默认颜色。
+func (e *Emulator) SetSpecialColor(i int, c color.Color) {
+	if i < 0 || i >= len(e.specialColors) {
+		return
+	}
+	e.specialColors[i] = c
+}
+
 // resetTabStops 将终端制表位重置为默认设置。
 func (e *Emulator) resetTabStops() {
 	e.tabstops = uv.DefaultTabStops(e.Width())
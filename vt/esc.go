@@ -29,10 +29,23 @@ func (e *Emulator) fullReset() {
 	// XXX: 我们是否在这里重置所有模式？需要调查。
 	e.resetModes()
 
-	e.gl, e.gr = 0, 1
+	e.gl, e.gr = 0, 2
 	e.gsingle = 0
 	e.charsets = [4]CharSet{}
+	clear(e.colors[:])
 	e.atPhantom = false
+	e.modifyOtherKeys = 0
+	e.rectExtent = false
+	e.highlight = nil
+	e.progress = Progress{}
+	e.notifications = nil
+	e.kittyImages = nil
+	e.kittyOrder = nil
+	e.kittyStorage = 0
+	e.kittyVirtuals = nil
+	e.kittyTransfer = nil
+	e.commands = nil
+	e.sel = nil
 	e.grapheme = e.grapheme[:0]
 	e.lastChar = 0
 	e.lastState = parser.GroundState
@@ -0,0 +1,126 @@
+package vt
+
+import (
+	"image"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+// 默认的单元格像素大小。
+const (
+	DefaultCellWidth  = 10
+	DefaultCellHeight = 20
+)
+
+// ImagePlacement 表示放置在屏幕上的图像。
+type ImagePlacement struct {
+	// Image 是解码后的图像。
+	Image image.Image
+	// Bounds 是图像覆盖的单元格矩形。当图像部分滚出屏幕时，它可能超出屏幕
+	// 边界。
+	Bounds uv.Rectangle
+	// ImageID 和 PlacementID 是 Kitty 图形协议的图像 ID 和放置 ID。对于
+	// Sixel 图像，它们为零。
+	ImageID, PlacementID int
+	// Z 是图像的 z-index。负值表示图像绘制在文本之下。
+	Z int
+}
+
+// CellSize 返回用于将图像像素映射到单元格的单元格像素大小。
+func (e *Emulator) CellSize() (width, height int) {
+	return e.cellWidth, e.cellHeight
+}
+
+// SetCellSize 设置单元格的像素大小。它决定了图像覆盖多少个单元格，以及 SGR
+// 像素鼠标编码报告的坐标。小于或等于零的值会被忽略。
+func (e *Emulator) SetCellSize(width, height int) {
+	if width > 0 {
+		e.cellWidth = width
+	}
+	if height > 0 {
+		e.cellHeight = height
+	}
+}
+
+// ImagePlacements 返回当前屏幕上可见的图像放置。矩形使用视口坐标，因此
+// 当视口向回滚缓冲区滚动时，图像会随屏幕内容一起向下移动。Kitty 图形协议
+// 的 Unicode 占位符引用的虚拟放置也会作为放置返回。
+func (e *Emulator) ImagePlacements() []ImagePlacement {
+	offset := e.scrollOffset
+	if e.scr != &e.scrs[0] {
+		offset = 0
+	}
+
+	bounds := e.scr.Bounds()
+	var placements []ImagePlacement
+	for _, p := range e.scr.images {
+		p.Bounds = p.Bounds.Add(uv.Pos(0, offset))
+		if p.Bounds.Overlaps(bounds) {
+			placements = append(placements, p)
+		}
+	}
+	return append(placements, e.placeholderPlacements()...)
+}
+
+// imageCells 返回给定像素大小的图像覆盖的列数和行数。
+func (e *Emulator) imageCells(width, height int) (cols, rows int) {
+	cols = (width + e.cellWidth - 1) / e.cellWidth
+	rows = (height + e.cellHeight - 1) / e.cellHeight
+	return max(1, cols), max(1, rows)
+}
+
+// addImage 在屏幕上添加一个图像放置。
+func (s *Screen) addImage(p ImagePlacement) {
+	s.images = append(s.images, p)
+}
+
+// clearImages 删除与给定区域重叠的 Sixel 图像放置。Sixel 图像属于它覆盖的
+// 单元格，因此擦除或覆盖这些单元格也会删除图像。Kitty 图像放置只会被
+// [Screen.eraseImages] 或删除命令删除。
+func (s *Screen) clearImages(area uv.Rectangle) {
+	s.images = deleteImages(s.images, func(p ImagePlacement) bool {
+		return p.ImageID == 0 && p.Bounds.Overlaps(area)
+	})
+}
+
+// eraseImages 删除与给定区域重叠的所有图像放置，包括 Kitty 图像放置。它用于
+// 擦除显示 (ED)。
+func (s *Screen) eraseImages(area uv.Rectangle) {
+	s.images = deleteImages(s.images, func(p ImagePlacement) bool {
+		return p.Bounds.Overlaps(area)
+	})
+}
+
+// shiftImages 将第 y 行及其下方滚动区域内的图像放置垂直移动 n 行。当 y 是
+// 滚动区域的顶部时，部分位于其上方的图像也会移动。完全移出第 y 行之上或
+// 滚动区域之下的图像会被删除。
+func (s *Screen) shiftImages(y, n int) {
+	scroll := s.scroll
+	moves := func(p ImagePlacement) bool {
+		return p.Bounds.Max.Y > y && p.Bounds.Min.Y < scroll.Max.Y &&
+			(p.Bounds.Min.Y >= y || y == scroll.Min.Y) &&
+			p.Bounds.Min.X >= scroll.Min.X && p.Bounds.Min.X < scroll.Max.X
+	}
+
+	s.images = deleteImages(s.images, func(p ImagePlacement) bool {
+		return moves(p) && (p.Bounds.Max.Y+n <= y || p.Bounds.Min.Y+n >= scroll.Max.Y)
+	})
+	for i, p := range s.images {
+		if moves(p) {
+			s.images[i].Bounds = p.Bounds.Add(uv.Pos(0, n))
+		}
+	}
+}
+
+// deleteImages 删除满足 del 的图像放置。
+func deleteImages(images []ImagePlacement, del func(ImagePlacement) bool) []ImagePlacement {
+	n := 0
+	for _, p := range images {
+		if !del(p) {
+			images[n] = p
+			n++
+		}
+	}
+	clear(images[n:])
+	return images[:n]
+}
@@ -216,6 +216,8 @@ func (e *Emulator) registerDefaultHandlers() {
 	e.registerDefaultCsiHandlers()
 	e.registerDefaultEscHandlers()
 	e.registerDefaultOscHandlers()
+	e.registerDefaultDcsHandlers()
+	e.registerDefaultApcHandlers()
 }
 
 // registerDefaultCcHandlers registers the default control character handlers.
@@ -257,6 +259,16 @@ func (e *Emulator) registerDefaultCcHandlers() {
 				e.carriageReturn()
 				return true
 			})
+		case ansi.SO: // Shift Out [ansi.SO]
+			e.registerCcHandler(i, func() bool {
+				e.gl = 1
+				return true
+			})
+		case ansi.SI: // Shift In [ansi.SI]
+			e.registerCcHandler(i, func() bool {
+				e.gl = 0
+				return true
+			})
 		}
 	}
 
@@ -272,16 +284,6 @@ func (e *Emulator) registerDefaultCcHandlers() {
 				e.reverseIndex()
 				return true
 			})
-		case ansi.SO: // Shift Out [ansi.SO]
-			e.registerCcHandler(i, func() bool {
-				e.gl = 1
-				return true
-			})
-		case ansi.SI: // Shift In [ansi.SI]
-			e.registerCcHandler(i, func() bool {
-				e.gl = 0
-				return true
-			})
 		case ansi.IND: // Index [ansi.IND]
 			e.registerCcHandler(i, func() bool {
 				e.index()
@@ -314,6 +316,18 @@ func (e *Emulator) registerDefaultOscHandlers() {
 		})
 	}
 
+	for _, cmd := range []int{
+		4,   // Set/Query palette color
+		5,   // Set/Query special color
+		104, // Reset palette color
+		105, // Reset special color
+	} {
+		e.RegisterOscHandler(cmd, func(data []byte) bool {
+			e.handlePaletteColor(cmd, data)
+			return true
+		})
+	}
+
 	e.RegisterOscHandler(7, func(data []byte) bool {
 		// Report the shell current working directory
 		// [ansi.NotifyWorkingDirectory].
@@ -327,6 +341,31 @@ func (e *Emulator) registerDefaultOscHandlers() {
 		return true
 	})
 
+	e.RegisterOscHandler(9, func(data []byte) bool {
+		// Desktop Notification [ansi.Notify] or Progress Bar
+		// [ansi.SetProgressBar]
+		e.handleNotify(9, data)
+		return true
+	})
+
+	e.RegisterOscHandler(99, func(data []byte) bool {
+		// Desktop Notification [ansi.DesktopNotification]
+		e.handleDesktopNotification(99, data)
+		return true
+	})
+
+	e.RegisterOscHandler(777, func(data []byte) bool {
+		// Desktop Notification (rxvt)
+		e.handleNotifyRxvt(777, data)
+		return true
+	})
+
+	e.RegisterOscHandler(52, func(data []byte) bool {
+		// Set/Query Clipboard [ansi.SetClipboard]
+		e.handleClipboard(52, data)
+		return true
+	})
+
 	for _, cmd := range []int{
 		10,  // Set/Query foreground color
 		11,  // Set/Query background color
@@ -340,6 +379,40 @@ func (e *Emulator) registerDefaultOscHandlers() {
 			return true
 		})
 	}
+
+	e.RegisterOscHandler(133, func(data []byte) bool {
+		// Shell Integration [ansi.FinalTerm]
+		e.handleSemanticPrompt(133, data)
+		return true
+	})
+}
+
+// registerDefaultDcsHandlers registers the default DCS escape sequence handlers.
+func (e *Emulator) registerDefaultDcsHandlers() {
+	e.RegisterDcsHandler(ansi.Command(0, '$', 'q'), func(_ ansi.Params, data []byte) bool {
+		// Request Status String [ansi.DECRQSS]
+		e.handleRequestStatusString(data)
+		return true
+	})
+
+	e.RegisterDcsHandler(ansi.Command(0, '+', 'q'), func(_ ansi.Params, data []byte) bool {
+		// Request Termcap/Terminfo String [ansi.XTGETTCAP]
+		e.handleRequestTermcap(data)
+		return true
+	})
+
+	e.RegisterDcsHandler('q', func(params ansi.Params, data []byte) bool {
+		// Sixel Graphics [ansi.SixelGraphics]
+		return e.handleSixel(params, data)
+	})
+}
+
+// registerDefaultApcHandlers registers the default APC escape sequence handlers.
+func (e *Emulator) registerDefaultApcHandlers() {
+	e.RegisterApcHandler(func(data []byte) bool {
+		// Kitty Graphics Protocol [ansi.KittyGraphics]
+		return e.handleKittyGraphics(data)
+	})
 }
 
 // registerDefaultEscHandlers registers the default ESC escape sequence handlers.
@@ -368,38 +441,78 @@ func (e *Emulator) registerDefaultEscHandlers() {
 		return true
 	})
 
-	for _, cmd := range []int{
-		ansi.Command(0, '(', 'A'), // UK G0
-		ansi.Command(0, ')', 'A'), // UK G1
-		ansi.Command(0, '*', 'A'), // UK G2
-		ansi.Command(0, '+', 'A'), // UK G3
-		ansi.Command(0, '(', 'B'), // USASCII G0
-		ansi.Command(0, ')', 'B'), // USASCII G1
-		ansi.Command(0, '*', 'B'), // USASCII G2
-		ansi.Command(0, '+', 'B'), // USASCII G3
-		ansi.Command(0, '(', '0'), // Special G0
-		ansi.Command(0, ')', '0'), // Special G1
-		ansi.Command(0, '*', '0'), // Special G2
-		ansi.Command(0, '+', '0'), // Special G3
-	} {
-		e.RegisterEscHandler(cmd, func() bool {
-			// Select Character Set [ansi.SCS]
-			c := ansi.Cmd(cmd)
-			set := c.Intermediate() - '('
-			switch c.Final() {
-			case 'A': // UK Character Set
-				e.charsets[set] = UK
-			case 'B': // USASCII Character Set
-				e.charsets[set] = nil // USASCII is the default
-			case '0': // Special Drawing Character Set
-				e.charsets[set] = SpecialDrawing
-			default:
-				return false
+	registered := map[int]bool{}
+	for _, d := range charsetDesignations {
+		// The parser only keeps the last intermediate byte, so designators
+		// with an intermediate byte are registered under it and the
+		// intermediate selecting G0-G3 is taken from the collected bytes.
+		var cmds []int
+		if len(d.designator) == 2 {
+			cmds = append(cmds, ansi.Command(0, d.designator[0], d.designator[1]))
+		} else {
+			inters := "()*+"
+			if d.is96 {
+				inters = "-./"
 			}
-			return true
-		})
+			for i := range len(inters) {
+				cmds = append(cmds, ansi.Command(0, inters[i], d.designator[0]))
+			}
+		}
+
+		for _, cmd := range cmds {
+			if registered[cmd] {
+				continue
+			}
+			registered[cmd] = true
+			e.RegisterEscHandler(cmd, func() bool {
+				// Select Character Set [ansi.SCS]
+				return e.designateCharset(e.escInter, ansi.Cmd(cmd).Final())
+			})
+		}
 	}
 
+	e.RegisterEscHandler('N', func() bool {
+		// Single Shift 2 [ansi.SS2]
+		e.gsingle = 2
+		return true
+	})
+
+	e.RegisterEscHandler('O', func() bool {
+		// Single Shift 3 [ansi.SS3]
+		e.gsingle = 3
+		return true
+	})
+
+	e.RegisterEscHandler(ansi.Command(0, '#', '3'), func() bool {
+		// Double-Height Line, Top Half (DECDHL)
+		e.setLineAttr(LineDoubleHeightTop)
+		return true
+	})
+
+	e.RegisterEscHandler(ansi.Command(0, '#', '4'), func() bool {
+		// Double-Height Line, Bottom Half (DECDHL)
+		e.setLineAttr(LineDoubleHeightBottom)
+		return true
+	})
+
+	e.RegisterEscHandler(ansi.Command(0, '#', '5'), func() bool {
+		// Single-Width Line (DECSWL)
+		e.setLineAttr(LineSingleWidth)
+		return true
+	})
+
+	e.RegisterEscHandler(ansi.Command(0, '#', '6'), func() bool {
+		// Double-Width Line (DECDWL)
+		e.setLineAttr(LineDoubleWidth)
+		return true
+	})
+
+	e.RegisterEscHandler(ansi.Command(0, '#', '8'), func() bool {
+		// Screen Alignment Pattern (DECALN)
+		e.screenAlignment()
+		return true
+	})
+
 	e.RegisterEscHandler('D', func() bool {
 		// Index [ansi.IND]
 		e.index()
@@ -438,18 +551,21 @@ func (e *Emulator) registerDefaultEscHandlers() {
 
 	e.RegisterEscHandler('|', func() bool {
 		// Locking Shift 3 Right [ansi.LS3R]
+		// Output is decoded as UTF-8, so GR is only tracked for DECCIR.
 		e.gr = 3
 		return true
 	})
 
 	e.RegisterEscHandler('}', func() bool {
 		// Locking Shift 2 Right [ansi.LS2R]
+		// Output is decoded as UTF-8, so GR is only tracked for DECCIR.
 		e.gr = 2
 		return true
 	})
 
 	e.RegisterEscHandler('~', func() bool {
 		// Locking Shift 1 Right [ansi.LS1R]
+		// Output is decoded as UTF-8, so GR is only tracked for DECCIR.
 		e.gr = 1
 		return true
 	})
@@ -545,21 +661,37 @@ func (e *Emulator) registerDefaultCsiHandlers() {
 		n, _, _ := params.Param(0, 0)
 		width, height := e.Width(), e.Height()
 		x, y := e.scr.CursorPosition()
+		// Lines that are erased completely become single width.
 		switch n {
 		case 0: // Erase screen below (from after cursor position)
 			rect1 := uv.Rect(x, y, width, 1)            // cursor to end of line
 			rect2 := uv.Rect(0, y+1, width, height-y-1) // next line onwards
 			e.scr.FillArea(e.scr.blankCell(), rect1)
 			e.scr.FillArea(e.scr.blankCell(), rect2)
+			e.scr.eraseImages(rect1)
+			e.scr.eraseImages(rect2)
+			if x == 0 {
+				e.scr.resetLineAttrs(y, height)
+			} else {
+				e.scr.resetLineAttrs(y+1, height)
+			}
 		case 1: // Erase screen above (including cursor)
 			rect := uv.Rect(0, 0, width, y+1)
 			e.scr.FillArea(e.scr.blankCell(), rect)
+			e.scr.eraseImages(rect)
+			if x >= e.scr.lineWidth(y)-1 {
+				e.scr.resetLineAttrs(0, y+1)
+			} else {
+				e.scr.resetLineAttrs(0, y)
+			}
 		case 2: // erase screen
-			fallthrough
-		case 3: // erase display
-			//nolint:godox
-			// TODO: Scrollback buffer support?
 			e.scr.Clear()
+		case 3: // erase saved lines
+			// Like xterm, this only clears the scrollback buffer and leaves
+			// the screen untouched.
+			if e.scr == &e.scrs[0] {
+				e.clearScrollback()
+			}
 		default:
 			return false
 		}
@@ -592,7 +724,9 @@ func (e *Emulator) registerDefaultCsiHandlers() {
 	e.RegisterCsiHandler('L', func(params ansi.Params) bool {
 		// Insert Line [ansi.IL]
 		n, _, _ := params.Param(0, 1)
+		_, y := e.scr.CursorPosition()
 		if e.scr.InsertLine(n) {
+			e.shiftSelection(y, n)
 			// Move the cursor to the left margin.
 			e.scr.setCursorX(0, true)
 		}
@@ -602,7 +736,9 @@ func (e *Emulator) registerDefaultCsiHandlers() {
 	e.RegisterCsiHandler('M', func(params ansi.Params) bool {
 		// Delete Line [ansi.DL]
 		n, _, _ := params.Param(0, 1)
+		_, y := e.scr.CursorPosition()
 		if e.scr.DeleteLine(n) {
+			e.shiftSelection(y, -n)
 			// If the line was deleted successfully, move the cursor to the
 			// left.
 			// Move the cursor to the left margin.
@@ -621,14 +757,20 @@ func (e *Emulator) registerDefaultCsiHandlers() {
 	e.RegisterCsiHandler('S', func(params ansi.Params) bool {
 		// Scroll Up [ansi.SU]
 		n, _, _ := params.Param(0, 1)
-		e.scr.ScrollUp(n)
+		e.scrollUp(n)
 		return true
 	})
 
 	e.RegisterCsiHandler('T', func(params ansi.Params) bool {
+		if len(params) >= 5 {
+			// Initiate Highlight Mouse Tracking
+			e.startHighlight(params)
+			return true
+		}
+
 		// Scroll Down [ansi.SD]
 		n, _, _ := params.Param(0, 1)
-		e.scr.ScrollDown(n)
+		e.scrollDown(n)
 		return true
 	})
 
@@ -693,6 +835,7 @@ func (e *Emulator) registerDefaultCsiHandlers() {
 			1,  // 132 columns
 			6,  // Selective Erase
 			22, // ANSI color
+			28, // Rectangular editing
 		))
 		return true
 	})
@@ -713,6 +856,17 @@ func (e *Emulator) registerDefaultCsiHandlers() {
 		return true
 	})
 
+	e.RegisterCsiHandler(ansi.Command('=', 0, 'c'), func(params ansi.Params) bool {
+		// Tertiary Device Attributes [ansi.DA3]
+		n, _, _ := params.Param(0, 0)
+		if n != 0 {
+			return false
+		}
+
+		_, _ = io.WriteString(e.pw, ansi.TertiaryDeviceAttributes(unitID))
+		return true
+	})
+
 	e.RegisterCsiHandler('d', func(params ansi.Params) bool {
 		// Vertical Position Absolute [ansi.VPA]
 		n, _, _ := params.Param(0, 1)
@@ -839,6 +993,20 @@ func (e *Emulator) registerDefaultCsiHandlers() {
 		return true
 	})
 
+	e.RegisterCsiHandler(ansi.Command(0, '$', 'w'), func(params ansi.Params) bool {
+		// Request Presentation State Report [ansi.DECRQPSR]
+		n, _, _ := params.Param(0, 0)
+		switch n {
+		case 1: // Cursor Information Report [ansi.DECCIR]
+			e.reportCursorInformation()
+		case 2: // Tab Stop Report [ansi.DECTABSR]
+			e.reportTabStops()
+		default:
+			return false
+		}
+		return true
+	})
+
 	e.RegisterCsiHandler(ansi.Command(0, ' ', 'q'), func(params ansi.Params) bool {
 		// Set Cursor Style [ansi.DECSCUSR]
 		n := 1
@@ -854,6 +1022,119 @@ func (e *Emulator) registerDefaultCsiHandlers() {
 		return true
 	})
 
+	e.RegisterCsiHandler(ansi.Command('>', 0, 'q'), func(params ansi.Params) bool {
+		// Report Name and Version [ansi.XTVERSION]
+		n, _, _ := params.Param(0, 0)
+		if n != 0 {
+			return false
+		}
+
+		e.reportNameVersion()
+		return true
+	})
+
+	e.RegisterCsiHandler(ansi.Command(0, '$', 'x'), func(params ansi.Params) bool {
+		// Fill Rectangular Area (DECFRA)
+		ch, _, _ := params.Param(0, 0)
+		area, ok := e.rectArea(params, 1)
+		if ok {
+			e.fillRect(rune(ch), area)
+		}
+		return true
+	})
+
+	e.RegisterCsiHandler(ansi.Command(0, '$', 'z'), func(params ansi.Params) bool {
+		// Erase Rectangular Area (DECERA)
+		area, ok := e.rectArea(params, 0)
+		if ok {
+			e.eraseRect(area, false)
+		}
+		return true
+	})
+
+	e.RegisterCsiHandler(ansi.Command(0, '$', '{'), func(params ansi.Params) bool {
+		// Selective Erase Rectangular Area (DECSERA)
+		area, ok := e.rectArea(params, 0)
+		if ok {
+			e.eraseRect(area, true)
+		}
+		return true
+	})
+
+	e.RegisterCsiHandler(ansi.Command(0, '$', 'v'), func(params ansi.Params) bool {
+		// Copy Rectangular Area (DECCRA)
+		src, ok := e.rectArea(params, 0)
+		if !ok {
+			return true
+		}
+
+		// We don't support pages, so the source and destination pages
+		// (parameters 4 and 7) are ignored.
+		var origin uv.Position
+		if e.isModeSet(ansi.ModeOrigin) {
+			origin = e.scr.ScrollRegion().Min
+		}
+		top, _, _ := params.Param(5, 1)
+		left, _, _ := params.Param(6, 1)
+		dst := uv.Pos(max(1, left)-1+origin.X, max(1, top)-1+origin.Y)
+		e.copyRect(src, dst)
+		return true
+	})
+
+	e.RegisterCsiHandler(ansi.Command(0, '$', 'r'), func(params ansi.Params) bool {
+		// Change Attributes in Rectangular Area (DECCARA)
+		area, ok := e.rectArea(params, 0)
+		if ok {
+			e.changeRectAttrs(area, rectAttrs(params))
+		}
+		return true
+	})
+
+	e.RegisterCsiHandler(ansi.Command(0, '$', 't'), func(params ansi.Params) bool {
+		// Reverse Attributes in Rectangular Area (DECRARA)
+		area, ok := e.rectArea(params, 0)
+		if ok {
+			e.reverseRectAttrs(area, rectAttrs(params))
+		}
+		return true
+	})
+
+	e.RegisterCsiHandler(ansi.Command(0, '*', 'x'), func(params ansi.Params) bool {
+		// Select Attribute Change Extent (DECSACE)
+		n, _, _ := params.Param(0, 0)
+		switch n {
+		case 0, 1:
+			e.rectExtent = false
+		case 2:
+			e.rectExtent = true
+		default:
+			return false
+		}
+		return true
+	})
+
+	e.RegisterCsiHandler(ansi.Command(0, '\'', '}'), func(params ansi.Params) bool {
+		// Insert Column (DECIC)
+		n, _, _ := params.Param(0, 1)
+		e.scr.InsertColumn(n)
+		return true
+	})
+
+	e.RegisterCsiHandler(ansi.Command(0, '\'', '~'), func(params ansi.Params) bool {
+		// Delete Column (DECDC)
+		n, _, _ := params.Param(0, 1)
+		e.scr.DeleteColumn(n)
+		return true
+	})
+
+	e.RegisterCsiHandler(ansi.Command(0, '*', 'y'), func(params ansi.Params) bool {
+		// Request Checksum of Rectangular Area (DECRQCRA)
+		id, _, _ := params.Param(0, 0)
+		area, _ := e.rectArea(params, 2)
+		e.reportRectChecksum(id, area)
+		return true
+	})
+
 	e.RegisterCsiHandler('r', func(params ansi.Params) bool {
 		// Set Top and Bottom Margins [ansi.DECSTBM]
 		top, _, _ := params.Param(0, 1)
@@ -916,4 +1197,47 @@ func (e *Emulator) registerDefaultCsiHandlers() {
 
 		return true
 	})
+
+	e.RegisterCsiHandler(ansi.Command('?', 0, 'u'), func(params ansi.Params) bool {
+		// Request Kitty Keyboard Flags [ansi.RequestKittyKeyboard]
+		e.reportKittyFlags()
+		return true
+	})
+
+	e.RegisterCsiHandler(ansi.Command('>', 0, 'u'), func(params ansi.Params) bool {
+		// Push Kitty Keyboard Flags [ansi.PushKittyKeyboard]
+		flags, _, _ := params.Param(0, 0)
+		e.pushKittyFlags(flags)
+		return true
+	})
+
+	e.RegisterCsiHandler(ansi.Command('<', 0, 'u'), func(params ansi.Params) bool {
+		// Pop Kitty Keyboard Flags [ansi.PopKittyKeyboard]
+		n, _, _ := params.Param(0, 1)
+		e.popKittyFlags(n)
+		return true
+	})
+
+	e.RegisterCsiHandler(ansi.Command('=', 0, 'u'), func(params ansi.Params) bool {
+		// Set Kitty Keyboard Flags [ansi.KittyKeyboard]
+		flags, _, _ := params.Param(0, 0)
+		mode, _, _ := params.Param(1, 1)
+		e.setKittyFlags(flags, mode)
+		return true
+	})
+
+	e.RegisterCsiHandler(ansi.Command('>', 0, 'm'), func(params ansi.Params) bool {
+		// Set Key Modifier Options [ansi.XTMODKEYS]
+		return e.setKeyModifierOptions(params)
+	})
+
+	e.RegisterCsiHandler(ansi.Command('?', 0, 'm'), func(params ansi.Params) bool {
+		// Query Key Modifier Options [ansi.XTQMODKEYS]
+		pp, _, _ := params.Param(0, 0)
+		if pp != 4 {
+			return false
+		}
+		_, _ = io.WriteString(e.pw, ansi.KeyModifierOptions(4, e.modifyOtherKeys))
+		return true
+	})
 }
@@ -0,0 +1,178 @@
+package vt
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+// DefaultLinkPattern 匹配常见的纯文本 URL，可以用于 [Emulator.SetLinkPattern]。
+// 结尾的标点符号不属于 URL。
+var DefaultLinkPattern = regexp.MustCompile(`\b(?:(?:https?|ftp|file)://|mailto:)[^\s<>"'` + "`" + `]*[^\s<>"'` + "`" + `.,;:!?)\]}]`)
+
+// Hyperlink 是视口中可见的一个超链接。
+type Hyperlink struct {
+	// URL 是超链接的目标。
+	URL string
+	// ID 是 OSC 8 的 id 参数。具有相同 id 和 URL 的单元格属于同一个超链接，
+	// 即使它们不相邻。没有 id 的超链接由连续的单元格组成，可以跨越软换行的行。
+	ID string
+	// Params 是 OSC 8 的原始参数。
+	Params string
+	// Spans 是超链接覆盖的单元格区域，以视口坐标表示，每个区域位于一行内，
+	// 按从上到下、从左到右的顺序排列。
+	Spans []uv.Rectangle
+	// Implicit 报告超链接是否是通过 [Emulator.SetLinkPattern] 从文本中识别的，
+	// 而不是由 OSC 8 设置的。
+	Implicit bool
+}
+
+// Contains 报告视口位置 (x, y) 的单元格是否属于该超链接。
+func (h Hyperlink) Contains(x, y int) bool {
+	p := uv.Pos(x, y)
+	for _, span := range h.Spans {
+		if p.In(span) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetLinkPattern 设置用于识别隐式超链接的正则表达式，例如 [DefaultLinkPattern]。
+// 与之匹配、并且没有 OSC 8 超链接的文本会被 [Emulator.Hyperlinks] 和
+// [Emulator.LinkAt] 作为隐式超链接返回。nil 表示不识别隐式超链接，这是默认值。
+func (e *Emulator) SetLinkPattern(re *regexp.Regexp) {
+	e.linkPattern = re
+}
+
+// Hyperlinks 返回视口中可见的所有超链接，按它们第一个单元格的位置排列。
+// 当视口向回滚缓冲区滚动时，回滚缓冲区中可见的超链接也会被返回。
+func (e *Emulator) Hyperlinks() []Hyperlink {
+	var links []Hyperlink
+	byID := map[string]int{}
+	// tail 是上一行末尾的没有 id 的超链接。如果上一行是软换行的，它在本行开头
+	// 继续。
+	tail := -1
+
+	for y := range e.Height() {
+		line, wrapped := e.searchLine(e.selectionLineAt(y))
+		prev := tail
+		tail = -1
+
+		add := func(link uv.Link, x0, x1 int) {
+			id := linkID(link.Params)
+			i := -1
+			switch {
+			case id != "":
+				key := id + "\x00" + link.URL
+				if j, ok := byID[key]; ok {
+					i = j
+				} else {
+					byID[key] = len(links)
+				}
+			case x0 == 0 && prev >= 0 && links[prev].URL == link.URL && links[prev].Params == link.Params:
+				i = prev
+			}
+			if i < 0 {
+				i = len(links)
+				links = append(links, Hyperlink{URL: link.URL, ID: id, Params: link.Params})
+			}
+			links[i].Spans = append(links[i].Spans, uv.Rect(x0, y, x1-x0, 1))
+			if id == "" && wrapped && x1 >= len(line) {
+				tail = i
+			}
+		}
+
+		var cur uv.Link
+		start := 0
+		for x := 0; x < len(line); x++ {
+			c := line[x]
+			if c.IsZero() {
+				// 宽字符的占位单元格属于前一个单元格。
+				continue
+			}
+			if c.Link != cur {
+				if cur.URL != "" {
+					add(cur, start, x)
+				}
+				cur, start = c.Link, x
+			}
+		}
+		if cur.URL != "" {
+			add(cur, start, len(line))
+		}
+	}
+
+	links = append(links, e.implicitLinks()...)
+	sort.SliceStable(links, func(i, j int) bool {
+		a, b := links[i].Spans[0].Min, links[j].Spans[0].Min
+		return a.Y < b.Y || (a.Y == b.Y && a.X < b.X)
+	})
+	return links
+}
+
+// LinkAt 返回视口位置 (x, y) 的超链接。如果该位置没有超链接，则返回 false。
+func (e *Emulator) LinkAt(x, y int) (Hyperlink, bool) {
+	for _, link := range e.Hyperlinks() {
+		if link.Contains(x, y) {
+			return link, true
+		}
+	}
+	return Hyperlink{}, false
+}
+
+// implicitLinks 返回视口中与 [Emulator.SetLinkPattern] 设置的正则表达式匹配的
+// 隐式超链接。包含 OSC 8 超链接单元格的匹配会被忽略。
+func (e *Emulator) implicitLinks() []Hyperlink {
+	if e.linkPattern == nil {
+		return nil
+	}
+
+	var links []Hyperlink
+	top := e.selectionLineAt(0)
+	bottom := top + e.Height()
+	for y := top; y < bottom; {
+		l := e.logicalLine(y)
+		y = l.end + 1
+		for _, loc := range e.linkPattern.FindAllStringIndex(l.text, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			m := l.match(loc)
+			link := Hyperlink{URL: l.text[loc[0]:loc[1]], Implicit: true}
+			explicit := false
+			for row := max(m.Start.Y, top); row <= m.End.Y && row < bottom; row++ {
+				line, _ := e.searchLine(row)
+				x0, x1 := 0, len(line)
+				if row == m.Start.Y {
+					x0 = m.Start.X
+				}
+				if row == m.End.Y {
+					x1 = m.End.X + max(1, line[m.End.X].Width)
+				}
+				for x := x0; x < x1; x++ {
+					if line[x].Link.URL != "" {
+						explicit = true
+					}
+				}
+				link.Spans = append(link.Spans, uv.Rect(x0, row-top, x1-x0, 1))
+			}
+			if !explicit && len(link.Spans) > 0 {
+				links = append(links, link)
+			}
+		}
+	}
+	return links
+}
+
+// linkID 返回 OSC 8 参数中的 id 参数。参数的格式为以冒号分隔的 key=value。
+func linkID(params string) string {
+	for _, p := range strings.Split(params, ":") {
+		if id, ok := strings.CutPrefix(p, "id="); ok {
+			return id
+		}
+	}
+	return ""
+}
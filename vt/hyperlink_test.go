@@ -0,0 +1,72 @@
+package vt
+
+import (
+	"reflect"
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+func link(params, url, text string) string {
+	return "\x1b]8;" + params + ";" + url + "\x07" + text + "\x1b]8;;\x07"
+}
+
+func TestHyperlinks(t *testing.T) {
+	term := newTestTerminal(t, 10, 4)
+	term.WriteString(link("id=a", "http://a", "ab") + " " + link("", "http://b", "cd") + "\r\n")
+	term.WriteString(link("id=a", "http://a", "ef") + "\r\n")
+	// A link without an id continues across a soft wrap.
+	term.WriteString("12345" + link("", "http://c", "6789012"))
+
+	want := []Hyperlink{
+		{URL: "http://a", ID: "a", Params: "id=a", Spans: []uv.Rectangle{uv.Rect(0, 0, 2, 1), uv.Rect(0, 1, 2, 1)}},
+		{URL: "http://b", Spans: []uv.Rectangle{uv.Rect(3, 0, 2, 1)}},
+		{URL: "http://c", Spans: []uv.Rectangle{uv.Rect(5, 2, 5, 1), uv.Rect(0, 3, 2, 1)}},
+	}
+	if got := term.Hyperlinks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Hyperlinks() = %+v, want %+v", got, want)
+	}
+
+	if l, ok := term.LinkAt(1, 3); !ok || l.URL != "http://c" {
+		t.Errorf("LinkAt(1, 3) = %+v, %v", l, ok)
+	}
+	if l, ok := term.LinkAt(2, 0); ok {
+		t.Errorf("LinkAt(2, 0) = %+v, want no link", l)
+	}
+}
+
+func TestHyperlinksSameURLWithoutID(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	// Separate runs without an id are separate links, even with the same URL.
+	term.WriteString(link("", "http://a", "ab") + " " + link("", "http://a", "cd") + "\r\n")
+	term.WriteString(link("", "http://a", "ef"))
+
+	if got := term.Hyperlinks(); len(got) != 3 {
+		t.Errorf("Hyperlinks() = %+v, want 3 links", got)
+	}
+}
+
+func TestImplicitHyperlinks(t *testing.T) {
+	term := newTestTerminal(t, 20, 3)
+	term.WriteString("see https://example.com/x, " + link("", "http://b", "http://a.io") + "\r\n")
+	term.WriteString("mailto:me@x.org")
+
+	if got := term.Hyperlinks(); len(got) != 1 || got[0].Implicit {
+		t.Fatalf("Hyperlinks() without a pattern = %+v", got)
+	}
+
+	term.SetLinkPattern(DefaultLinkPattern)
+	want := []Hyperlink{
+		// The URL wraps onto the next line and drops the trailing comma.
+		{URL: "https://example.com/x", Spans: []uv.Rectangle{uv.Rect(4, 0, 16, 1), uv.Rect(0, 1, 5, 1)}, Implicit: true},
+		// Explicit links take precedence over the text they contain.
+		{URL: "http://b", Spans: []uv.Rectangle{uv.Rect(7, 1, 11, 1)}},
+		{URL: "mailto:me@x.org", Spans: []uv.Rectangle{uv.Rect(0, 2, 15, 1)}, Implicit: true},
+	}
+	if got := term.Hyperlinks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Hyperlinks() = %+v, want %+v", got, want)
+	}
+	if l, ok := term.LinkAt(2, 1); !ok || l.URL != "https://example.com/x" {
+		t.Errorf("LinkAt(2, 1) = %+v, %v", l, ok)
+	}
+}
@@ -21,29 +21,44 @@ const (
 // KeyPressEvent represents a key press event.
 type KeyPressEvent = uv.KeyPressEvent
 
-// SendKey returns the default key map.
+// SendKey encodes the given key event and writes it to the terminal input.
+// Keys are encoded using the Kitty keyboard protocol when it's enabled,
+// XTerm modifyOtherKeys when set, and the legacy encoding otherwise.
 func (e *Emulator) SendKey(k uv.KeyEvent) {
+	if flags := e.KittyKeyboardFlags(); flags != 0 {
+		if seq, legacy := encodeKittyKey(k, flags); !legacy {
+			if seq != "" {
+				io.WriteString(e.pw, seq) //nolint:errcheck,gosec
+			}
+			return
+		}
+	}
+
 	var seq string
 
 	ack := e.isModeSet(ansi.CursorKeysMode)    // Application cursor keys mode
 	akk := e.isModeSet(ansi.NumericKeypadMode) // Application keypad keys mode
 
-	//nolint:godox
-	// TODO: Support Kitty, CSI u, and XTerm modifyOtherKeys.
 	switch key := k.(type) {
 	case KeyPressEvent:
+		if e.modifyOtherKeys > 0 {
+			if mseq, ok := encodeModifyOtherKeys(key, e.modifyOtherKeys); ok {
+				io.WriteString(e.pw, mseq) //nolint:errcheck,gosec
+				return
+			}
+		}
+
+		key.Mod &^= lockMods // Lock modifiers don't affect legacy encoding
 		if key.Mod&ModAlt != 0 {
 			// Handle alt-modified keys
 			seq = "\x1b" + seq
 			key.Mod &^= ModAlt // Remove the Alt modifier for easier matching
 		}
 
-		//nolint:godox
-		// FIXME: We remove any Base and Shifted codes to properly handle
-		// comparison. This is a workaround for the fact that we don't support
-		// extended keys yet.
-		key.BaseCode = 0
-		key.ShiftedCode = 0
+		// Only match on the key code and modifiers. The remaining fields,
+		// such as the key text, are used by the default case below.
+		text := key.Text
+		key = KeyPressEvent{Code: key.Code, Mod: key.Mod}
 
 		switch key {
 		// Control keys
@@ -292,7 +307,10 @@ func (e *Emulator) SendKey(k uv.KeyEvent) {
 
 		default:
 			// Handle the rest of the keys.
-			if key.Mod == 0 {
+			switch {
+			case key.Mod&^ModShift == 0 && text != "":
+				seq += text
+			case key.Mod == 0 && key.Code < KeyExtended:
 				seq += string(key.Code)
 			}
 		}
@@ -0,0 +1,403 @@
+package vt
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/purpose168/charm-experimental-packages-cn/ansi"
+)
+
+// kittyKeyboardStackSize 是每个屏幕的 Kitty 键盘标志堆栈的最大深度。
+// 当堆栈已满时，压入新的标志会丢弃最旧的一项。
+const kittyKeyboardStackSize = 16
+
+// lockMods 是按键锁定状态的修饰符。
+const lockMods = uv.ModCapsLock | uv.ModNumLock | uv.ModScrollLock
+
+// kittyKey 表示 Kitty 键盘协议中功能键的编码，即键码和结束字符。
+type kittyKey struct {
+	code  int
+	final byte
+}
+
+// kittyFunctionalKeys 将功能键映射到其 Kitty 键盘协议编码。
+// 参见 https://sw.kovidgoyal.net/kitty/keyboard-protocol/#functional-key-definitions
+var kittyFunctionalKeys = map[rune]kittyKey{
+	KeyEscape:    {27, 'u'},
+	KeyEnter:     {13, 'u'},
+	KeyTab:       {9, 'u'},
+	KeyBackspace: {127, 'u'},
+	KeyInsert:    {2, '~'},
+	KeyDelete:    {3, '~'},
+	KeyLeft:      {1, 'D'},
+	KeyRight:     {1, 'C'},
+	KeyUp:        {1, 'A'},
+	KeyDown:      {1, 'B'},
+	KeyPgUp:      {5, '~'},
+	KeyPgDown:    {6, '~'},
+	KeyHome:      {1, 'H'},
+	KeyEnd:       {1, 'F'},
+	KeyBegin:     {1, 'E'},
+	KeyF1:        {1, 'P'},
+	KeyF2:        {1, 'Q'},
+	KeyF3:        {13, '~'},
+	KeyF4:        {1, 'S'},
+	KeyF5:        {15, '~'},
+	KeyF6:        {17, '~'},
+	KeyF7:        {18, '~'},
+	KeyF8:        {19, '~'},
+	KeyF9:        {20, '~'},
+	KeyF10:       {21, '~'},
+	KeyF11:       {23, '~'},
+	KeyF12:       {24, '~'},
+
+	KeyCapsLock:    {57358, 'u'},
+	KeyScrollLock:  {57359, 'u'},
+	KeyNumLock:     {57360, 'u'},
+	KeyPrintScreen: {57361, 'u'},
+	KeyPause:       {57362, 'u'},
+	KeyMenu:        {57363, 'u'},
+
+	KeyKpDecimal:  {57409, 'u'},
+	KeyKpDivide:   {57410, 'u'},
+	KeyKpMultiply: {57411, 'u'},
+	KeyKpMinus:    {57412, 'u'},
+	KeyKpPlus:     {57413, 'u'},
+	KeyKpEnter:    {57414, 'u'},
+	KeyKpEqual:    {57415, 'u'},
+	KeyKpSep:      {57416, 'u'},
+	KeyKpLeft:     {57417, 'u'},
+	KeyKpRight:    {57418, 'u'},
+	KeyKpUp:       {57419, 'u'},
+	KeyKpDown:     {57420, 'u'},
+	KeyKpPgUp:     {57421, 'u'},
+	KeyKpPgDown:   {57422, 'u'},
+	KeyKpHome:     {57423, 'u'},
+	KeyKpEnd:      {57424, 'u'},
+	KeyKpInsert:   {57425, 'u'},
+	KeyKpDelete:   {57426, 'u'},
+	KeyKpBegin:    {57427, 'u'},
+
+	KeyMediaPlay:        {57428, 'u'},
+	KeyMediaPause:       {57429, 'u'},
+	KeyMediaPlayPause:   {57430, 'u'},
+	KeyMediaReverse:     {57431, 'u'},
+	KeyMediaStop:        {57432, 'u'},
+	KeyMediaFastForward: {57433, 'u'},
+	KeyMediaRewind:      {57434, 'u'},
+	KeyMediaNext:        {57435, 'u'},
+	KeyMediaPrev:        {57436, 'u'},
+	KeyMediaRecord:      {57437, 'u'},
+	KeyLowerVol:         {57438, 'u'},
+	KeyRaiseVol:         {57439, 'u'},
+	KeyMute:             {57440, 'u'},
+
+	KeyLeftShift:      {57441, 'u'},
+	KeyLeftCtrl:       {57442, 'u'},
+	KeyLeftAlt:        {57443, 'u'},
+	KeyLeftSuper:      {57444, 'u'},
+	KeyLeftHyper:      {57445, 'u'},
+	KeyLeftMeta:       {57446, 'u'},
+	KeyRightShift:     {57447, 'u'},
+	KeyRightCtrl:      {57448, 'u'},
+	KeyRightAlt:       {57449, 'u'},
+	KeyRightSuper:     {57450, 'u'},
+	KeyRightHyper:     {57451, 'u'},
+	KeyRightMeta:      {57452, 'u'},
+	KeyIsoLevel3Shift: {57453, 'u'},
+	KeyIsoLevel5Shift: {57454, 'u'},
+}
+
+func init() {
+	// F13 到 F35 以及小键盘数字键使用连续的私有区键码。
+	for i := range 23 {
+		kittyFunctionalKeys[KeyF13+rune(i)] = kittyKey{57376 + i, 'u'}
+	}
+	for i := range 10 {
+		kittyFunctionalKeys[KeyKp0+rune(i)] = kittyKey{57399 + i, 'u'}
+	}
+}
+
+// KittyKeyboardFlags 返回当前屏幕生效的 Kitty 键盘协议渐进增强标志。
+// 参见 [ansi.KittyDisambiguateEscapeCodes] 等标志。
+func (e *Emulator) KittyKeyboardFlags() int {
+	if n := len(e.scr.kittyFlags); n > 0 {
+		return e.scr.kittyFlags[n-1]
+	}
+	return 0
+}
+
+// ModifyOtherKeys 返回当前的 XTerm modifyOtherKeys 级别。零表示禁用。
+func (e *Emulator) ModifyOtherKeys() int {
+	return e.modifyOtherKeys
+}
+
+// pushKittyFlags 将标志压入当前屏幕的 Kitty 键盘标志堆栈。
+// 这相当于 [ansi.PushKittyKeyboard]。
+func (e *Emulator) pushKittyFlags(flags int) {
+	s := e.scr
+	if len(s.kittyFlags) >= kittyKeyboardStackSize {
+		s.kittyFlags = s.kittyFlags[1:]
+	}
+	s.kittyFlags = append(s.kittyFlags, flags&ansi.KittyAllFlags)
+}
+
+// popKittyFlags 从当前屏幕的 Kitty 键盘标志堆栈弹出 n 项。
+// 这相当于 [ansi.PopKittyKeyboard]。
+func (e *Emulator) popKittyFlags(n int) {
+	s := e.scr
+	s.kittyFlags = s.kittyFlags[:len(s.kittyFlags)-min(max(1, n), len(s.kittyFlags))]
+}
+
+// setKittyFlags 按给定模式修改当前生效的 Kitty 键盘标志。
+// 这相当于 [ansi.KittyKeyboard]。
+func (e *Emulator) setKittyFlags(flags, mode int) {
+	cur := e.KittyKeyboardFlags()
+	switch mode {
+	case 1: // 设置给定标志并清除所有其他标志
+		cur = flags
+	case 2: // 设置给定标志并保持现有标志不变
+		cur |= flags
+	case 3: // 清除给定标志并保持现有标志不变
+		cur &^= flags
+	default:
+		return
+	}
+
+	s := e.scr
+	if len(s.kittyFlags) == 0 {
+		e.pushKittyFlags(cur)
+		return
+	}
+	s.kittyFlags[len(s.kittyFlags)-1] = cur & ansi.KittyAllFlags
+}
+
+// reportKittyFlags 报告当前生效的 Kitty 键盘标志。
+func (e *Emulator) reportKittyFlags() {
+	_, _ = io.WriteString(e.pw, "\x1b[?"+strconv.Itoa(e.KittyKeyboardFlags())+"u")
+}
+
+// setKeyModifierOptions 设置 XTerm 键修饰符选项。目前只支持 modifyOtherKeys
+// (Pp = 4)。这相当于 [ansi.XTMODKEYS]。
+func (e *Emulator) setKeyModifierOptions(params ansi.Params) bool {
+	pp, _, ok := params.Param(0, 0)
+	if !ok || pp != 4 {
+		return false
+	}
+
+	pv, _, _ := params.Param(1, 0)
+	if len(params) < 2 {
+		// 省略 Pv 时，资源重置为其初始值。
+		pv = 0
+	}
+	e.modifyOtherKeys = min(max(0, pv), 2)
+	return true
+}
+
+// kittyMods 将按键修饰符转换为 Kitty 键盘协议的修饰符位掩码。
+func kittyMods(m uv.KeyMod) int {
+	var mods int
+	if m.Contains(ModShift) {
+		mods |= 1
+	}
+	if m.Contains(ModAlt) {
+		mods |= 2
+	}
+	if m.Contains(ModCtrl) {
+		mods |= 4
+	}
+	if m.Contains(uv.ModSuper) {
+		mods |= 8
+	}
+	if m.Contains(uv.ModHyper) {
+		mods |= 16
+	}
+	if m.Contains(ModMeta) {
+		mods |= 32
+	}
+	if m.Contains(uv.ModCapsLock) {
+		mods |= 64
+	}
+	if m.Contains(uv.ModNumLock) {
+		mods |= 128
+	}
+	return mods
+}
+
+// encodeKittyKey 按照 Kitty 键盘协议和给定的渐进增强标志编码按键事件。
+// 如果该按键应该使用传统编码发送，则 legacy 为 true。空序列且 legacy 为
+// false 表示不应报告该事件。
+//
+// 参见 https://sw.kovidgoyal.net/kitty/keyboard-protocol/
+func encodeKittyKey(k uv.KeyEvent, flags int) (seq string, legacy bool) {
+	var key uv.Key
+	event := 1 // 按下
+	switch k := k.(type) {
+	case KeyPressEvent:
+		key = uv.Key(k)
+		if key.IsRepeat {
+			event = 2 // 重复
+		}
+	case uv.KeyReleaseEvent:
+		key = uv.Key(k)
+		event = 3 // 释放
+	default:
+		return "", false
+	}
+
+	reportEvents := flags&ansi.KittyReportEventTypes != 0
+	allKeys := flags&ansi.KittyReportAllKeysAsEscapeCodes != 0
+	if !reportEvents {
+		if event == 3 {
+			return "", false
+		}
+		event = 1
+	}
+
+	fk, functional := kittyFunctionalKeys[key.Code]
+	if !allKeys {
+		// 产生文本的按键以及未修饰的 Enter、Tab 和 Backspace 仍然使用传统
+		// 编码发送，并且不报告它们的释放事件。
+		textKey := !functional && key.Mod&^(ModShift|lockMods) == 0
+		plainKey := functional && key.Mod&^lockMods == 0 &&
+			(key.Code == KeyEnter || key.Code == KeyTab || key.Code == KeyBackspace)
+		if textKey || plainKey {
+			return "", event != 3
+		}
+	}
+
+	mods := kittyMods(key.Mod)
+	var modParam string
+	if mods != 0 || event != 1 {
+		modParam = strconv.Itoa(mods + 1)
+		if event != 1 {
+			modParam += ":" + strconv.Itoa(event)
+		}
+	}
+
+	if functional && fk.final != 'u' {
+		if fk.final == '~' {
+			seq = "\x1b[" + strconv.Itoa(fk.code)
+			if modParam != "" {
+				seq += ";" + modParam
+			}
+			return seq + "~", false
+		}
+		if modParam != "" {
+			return "\x1b[1;" + modParam + string(fk.final), false
+		}
+		return "\x1b[" + string(fk.final), false
+	}
+
+	code := int(key.Code)
+	if functional {
+		code = fk.code
+	} else if key.Code == KeyExtended {
+		r := []rune(key.Text)
+		if len(r) == 0 {
+			return "", false
+		}
+		code = int(r[0])
+	}
+
+	var b strings.Builder
+	b.WriteString("\x1b[")
+	b.WriteString(strconv.Itoa(code))
+	if flags&ansi.KittyReportAlternateKeys != 0 && !functional {
+		var shifted string
+		if key.Mod.Contains(ModShift) && key.ShiftedCode != 0 {
+			shifted = strconv.Itoa(int(key.ShiftedCode))
+		}
+		if key.BaseCode != 0 && int(key.BaseCode) != code {
+			b.WriteString(":" + shifted + ":" + strconv.Itoa(int(key.BaseCode)))
+		} else if shifted != "" {
+			b.WriteString(":" + shifted)
+		}
+	}
+
+	var text string
+	if allKeys && flags&ansi.KittyReportAssociatedKeys != 0 && event != 3 && key.Text != "" {
+		cps := make([]string, 0, len(key.Text))
+		for _, r := range key.Text {
+			cps = append(cps, strconv.Itoa(int(r)))
+		}
+		text = strings.Join(cps, ":")
+	}
+
+	if modParam != "" || text != "" {
+		b.WriteString(";" + modParam)
+	}
+	if text != "" {
+		b.WriteString(";" + text)
+	}
+	b.WriteByte('u')
+
+	return b.String(), false
+}
+
+// xtermMods 将按键修饰符转换为 XTerm 修饰符参数。
+func xtermMods(m uv.KeyMod) int {
+	var mods int
+	if m.Contains(ModShift) {
+		mods |= 1
+	}
+	if m.Contains(ModAlt) {
+		mods |= 2
+	}
+	if m.Contains(ModCtrl) {
+		mods |= 4
+	}
+	if m.Contains(ModMeta) {
+		mods |= 8
+	}
+	return mods + 1
+}
+
+// encodeModifyOtherKeys 按照 XTerm modifyOtherKeys 的给定级别编码按键。
+// 如果按键不受该级别影响，则 ok 为 false，调用者应使用传统编码。
+//
+// 参见 https://invisible-island.net/xterm/manpage/xterm.html#VT100-Widget-Resources:modifyOtherKeys
+func encodeModifyOtherKeys(key KeyPressEvent, level int) (seq string, ok bool) {
+	mods := key.Mod &^ lockMods
+	if mods == 0 {
+		return "", false
+	}
+
+	code := key.Code
+	switch code {
+	case KeyEnter, KeyTab, KeyBackspace, KeyEscape, KeySpace:
+	default:
+		if code >= KeyExtended {
+			// 功能键有自己的修饰编码。
+			return "", false
+		}
+		if mods == ModShift {
+			// 只按下 Shift 的可打印字符作为文本发送。
+			return "", false
+		}
+	}
+
+	if level < 2 {
+		// 级别 1 只修改没有传统编码的组合键。
+		if !mods.Contains(ModCtrl) {
+			return "", false
+		}
+		if mods&^ModAlt == ModCtrl && hasLegacyCtrl(code) {
+			return "", false
+		}
+	}
+
+	if mods.Contains(ModShift) && key.ShiftedCode != 0 {
+		code = key.ShiftedCode
+	}
+
+	return "\x1b[27;" + strconv.Itoa(xtermMods(mods)) + ";" + strconv.Itoa(int(code)) + "~", true
+}
+
+// hasLegacyCtrl 报告 ctrl 加上给定键码是否有传统的 C0 控制字符编码。
+func hasLegacyCtrl(code rune) bool {
+	return code == KeySpace || (code >= 'a' && code <= 'z') ||
+		code == '[' || code == '\\' || code == ']' || code == '^' || code == '_'
+}
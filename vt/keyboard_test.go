@@ -0,0 +1,154 @@
+package vt
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+// readInputEnd 标记 readInput 读取的数据的结束。
+const readInputEnd = "\x1b[readInputEnd]"
+
+// readInput 运行 fn 并返回它写入终端输入的所有数据。
+func readInput(t *testing.T, term *Emulator, fn func()) string {
+	t.Helper()
+	go func() {
+		fn()
+		_, _ = io.WriteString(term.pw, readInputEnd)
+	}()
+
+	var out []byte
+	buf := make([]byte, 256)
+	for {
+		n, err := term.Read(buf)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		out = append(out, buf[:n]...)
+		if i := bytes.Index(out, []byte(readInputEnd)); i >= 0 {
+			return string(out[:i])
+		}
+	}
+}
+
+func TestKittyKeyboardFlags(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	query := func() string {
+		return readInput(t, term, func() { term.WriteString("\x1b[?u") })
+	}
+
+	if got := query(); got != "\x1b[?0u" {
+		t.Fatalf("initial flags = %q", got)
+	}
+
+	term.WriteString("\x1b[>1u\x1b[>3u")
+	if got := query(); got != "\x1b[?3u" {
+		t.Errorf("after push = %q", got)
+	}
+
+	term.WriteString("\x1b[=8;2u")
+	if got := term.KittyKeyboardFlags(); got != 11 {
+		t.Errorf("after set mode 2 = %d", got)
+	}
+	term.WriteString("\x1b[=2;3u")
+	if got := term.KittyKeyboardFlags(); got != 9 {
+		t.Errorf("after set mode 3 = %d", got)
+	}
+
+	term.WriteString("\x1b[<u")
+	if got := term.KittyKeyboardFlags(); got != 1 {
+		t.Errorf("after pop = %d", got)
+	}
+
+	// The alternate screen has its own stack.
+	term.WriteString("\x1b[?1049h")
+	if got := term.KittyKeyboardFlags(); got != 0 {
+		t.Errorf("alt screen flags = %d", got)
+	}
+	term.WriteString("\x1b[?1049l")
+	if got := term.KittyKeyboardFlags(); got != 1 {
+		t.Errorf("main screen flags after alt = %d", got)
+	}
+
+	term.WriteString("\x1b[<10u")
+	if got := term.KittyKeyboardFlags(); got != 0 {
+		t.Errorf("after pop all = %d", got)
+	}
+}
+
+func TestSendKeyKitty(t *testing.T) {
+	cases := []struct {
+		name  string
+		flags string
+		key   uv.KeyEvent
+		want  string
+	}{
+		{"text legacy", "1", KeyPressEvent{Code: 'a', Text: "a"}, "a"},
+		{"shifted text legacy", "1", KeyPressEvent{Code: 'a', ShiftedCode: 'A', Text: "A", Mod: ModShift}, "A"},
+		{"ctrl letter", "1", KeyPressEvent{Code: 'a', Mod: ModCtrl}, "\x1b[97;5u"},
+		{"escape", "1", KeyPressEvent{Code: KeyEscape}, "\x1b[27u"},
+		{"enter legacy", "1", KeyPressEvent{Code: KeyEnter}, "\r"},
+		{"shift enter", "1", KeyPressEvent{Code: KeyEnter, Mod: ModShift}, "\x1b[13;2u"},
+		{"ctrl up", "1", KeyPressEvent{Code: KeyUp, Mod: ModCtrl}, "\x1b[1;5A"},
+		{"delete", "1", KeyPressEvent{Code: KeyDelete}, "\x1b[3~"},
+		{"f13", "1", KeyPressEvent{Code: KeyF13}, "\x1b[57376u"},
+		{"release ignored", "1", uv.KeyReleaseEvent{Code: 'a', Mod: ModCtrl}, ""},
+		{"release", "3", uv.KeyReleaseEvent{Code: 'a', Mod: ModCtrl}, "\x1b[97;5:3u"},
+		{"repeat", "3", KeyPressEvent{Code: KeyLeft, IsRepeat: true}, "\x1b[1;1:2D"},
+		{"text release", "3", uv.KeyReleaseEvent{Code: 'a', Text: "a"}, ""},
+		{"all keys", "8", KeyPressEvent{Code: 'a', Text: "a"}, "\x1b[97u"},
+		{"alternate keys", "12", KeyPressEvent{Code: 'a', ShiftedCode: 'A', Text: "A", Mod: ModShift}, "\x1b[97:65;2u"},
+		{"associated text", "24", KeyPressEvent{Code: 'a', ShiftedCode: 'A', Text: "A", Mod: ModShift}, "\x1b[97;2;65u"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			term := newTestTerminal(t, 10, 2)
+			term.WriteString("\x1b[>" + c.flags + "u")
+			got := readInput(t, term, func() { term.SendKey(c.key) })
+			if got != c.want {
+				t.Errorf("SendKey(%v) = %q, want %q", c.key, got, c.want)
+			}
+		})
+	}
+}
+
+func TestSendKeyModifyOtherKeys(t *testing.T) {
+	cases := []struct {
+		name  string
+		level string
+		key   KeyPressEvent
+		want  string
+	}{
+		{"level 1 ctrl letter", "1", KeyPressEvent{Code: 'a', Mod: ModCtrl}, "\x01"},
+		{"level 1 ctrl digit", "1", KeyPressEvent{Code: '1', Mod: ModCtrl}, "\x1b[27;5;49~"},
+		{"level 1 ctrl shift letter", "1", KeyPressEvent{Code: 'a', ShiftedCode: 'A', Mod: ModCtrl | ModShift}, "\x1b[27;6;65~"},
+		{"level 2 ctrl letter", "2", KeyPressEvent{Code: 'a', Mod: ModCtrl}, "\x1b[27;5;97~"},
+		{"level 2 shift enter", "2", KeyPressEvent{Code: KeyEnter, Mod: ModShift}, "\x1b[27;2;13~"},
+		{"level 2 shifted text", "2", KeyPressEvent{Code: 'a', ShiftedCode: 'A', Text: "A", Mod: ModShift}, "A"},
+		{"level 2 plain text", "2", KeyPressEvent{Code: 'a', Text: "a"}, "a"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			term := newTestTerminal(t, 10, 2)
+			term.WriteString("\x1b[>4;" + c.level + "m")
+			got := readInput(t, term, func() { term.SendKey(c.key) })
+			if got != c.want {
+				t.Errorf("SendKey(%v) = %q, want %q", c.key, got, c.want)
+			}
+		})
+	}
+
+	term := newTestTerminal(t, 10, 2)
+	term.WriteString("\x1b[>4;2m")
+	if got := readInput(t, term, func() { term.WriteString("\x1b[?4m") }); got != "\x1b[>4;2m" {
+		t.Errorf("XTQMODKEYS = %q", got)
+	}
+	term.WriteString("\x1b[>4m")
+	if got := term.ModifyOtherKeys(); got != 0 {
+		t.Errorf("ModifyOtherKeys after reset = %d", got)
+	}
+}
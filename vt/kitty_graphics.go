@@ -0,0 +1,659 @@
+package vt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/purpose168/charm-experimental-packages-cn/ansi"
+	"github.com/purpose168/charm-experimental-packages-cn/ansi/kitty"
+)
+
+// kittyMaxDataSize 是单次 Kitty 图形传输的最大数据大小（以字节为单位）。
+const kittyMaxDataSize = 256 * 1024 * 1024
+
+// kittyMaxStorage 是所有存储的 Kitty 图像的最大总大小（以字节为单位），与
+// kitty 的默认值相同。超过时会先删除最早的图像。
+const kittyMaxStorage = 320 * 1024 * 1024
+
+// kittyImage 是通过 Kitty 图形协议传输并存储在终端中的图像。size 是解码
+// 后的图像按每像素 4 字节计算的大小。
+type kittyImage struct {
+	id, number int
+	img        image.Image
+	size       int
+}
+
+// kittyVirtual 是用于 Unicode 占位符的虚拟放置。
+type kittyVirtual struct {
+	imageID, placementID int
+	cols, rows           int
+}
+
+// kittyTransfer 是正在进行的分块传输。
+type kittyTransfer struct {
+	opts kitty.Options
+	data []byte
+}
+
+// kittyError 是回复给应用程序的 Kitty 图形协议错误。
+type kittyError struct {
+	code, msg string
+}
+
+func (e *kittyError) Error() string {
+	return e.code + ":" + e.msg
+}
+
+// Kitty 图形协议错误。
+var (
+	errKittyNotFound    = &kittyError{"ENOENT", "image not found"}
+	errKittyNoData      = &kittyError{"ENODATA", "no image data"}
+	errKittyTooBig      = &kittyError{"EFBIG", "image data too large"}
+	errKittyUnsupported = &kittyError{"ENOTSUP", "unsupported action or medium"}
+)
+
+// handleKittyGraphics 处理 Kitty 图形协议的 APC G 序列。
+//
+// 参见 https://sw.kovidgoyal.net/kitty/graphics-protocol/
+func (e *Emulator) handleKittyGraphics(data []byte) bool {
+	if len(data) == 0 || data[0] != 'G' {
+		return false
+	}
+
+	control, payload, _ := bytes.Cut(data[1:], []byte{';'})
+	var opts kitty.Options
+	if err := opts.UnmarshalText(control); err != nil {
+		return false
+	}
+	// [kitty.Options.UnmarshalText] 对 m=0 和 m=1 一视同仁，并且忽略 C 键，
+	// 因此我们自己解析它们。
+	more := kittyControlValue(control, "m") == "1"
+	opts.DoNotMoveCursor = kittyControlValue(control, "C") == "1"
+
+	chunk, err := base64.StdEncoding.DecodeString(string(payload))
+	if err != nil {
+		e.kittyTransfer = nil
+		e.kittyReply(&opts, &kittyError{"EINVAL", "invalid base64 data"})
+		return true
+	}
+
+	if t := e.kittyTransfer; t != nil {
+		// 后续的块只携带 m 和 q 键，其余选项来自第一个块。
+		if opts.Quite > 0 {
+			t.opts.Quite = opts.Quite
+		}
+		if len(t.data)+len(chunk) > kittyMaxDataSize {
+			e.kittyTransfer = nil
+			e.kittyReply(&t.opts, errKittyTooBig)
+			return true
+		}
+		t.data = append(t.data, chunk...)
+		if more {
+			return true
+		}
+		e.kittyTransfer = nil
+		opts, chunk = t.opts, t.data
+	} else if more {
+		e.kittyTransfer = &kittyTransfer{opts: opts, data: chunk}
+		return true
+	}
+
+	e.handleKittyCommand(&opts, chunk)
+	return true
+}
+
+// kittyControlValue 返回控制数据中给定键的值。
+func kittyControlValue(control []byte, key string) string {
+	for kv := range bytes.SplitSeq(control, []byte{','}) {
+		if k, v, ok := bytes.Cut(kv, []byte{'='}); ok && string(k) == key {
+			return string(v)
+		}
+	}
+	return ""
+}
+
+// handleKittyCommand 执行一个完整的 Kitty 图形命令。
+func (e *Emulator) handleKittyCommand(opts *kitty.Options, data []byte) {
+	action := opts.Action
+	if action == 0 {
+		action = kitty.Transmit
+	}
+
+	switch action {
+	case kitty.Transmit, kitty.TransmitAndPut:
+		img, err := e.kittyDecode(opts, data)
+		if err != nil {
+			e.kittyReply(opts, err)
+			return
+		}
+		ki := e.kittyStore(opts, img)
+		if action == kitty.TransmitAndPut {
+			e.kittyPut(opts, ki)
+		}
+		e.kittyReply(opts, nil)
+
+	case kitty.Query:
+		_, err := e.kittyDecode(opts, data)
+		e.kittyReply(opts, err)
+
+	case kitty.Put:
+		ki := e.kittyLookup(opts)
+		if ki == nil {
+			e.kittyReply(opts, errKittyNotFound)
+			return
+		}
+		e.kittyPut(opts, ki)
+		e.kittyReply(opts, nil)
+
+	case kitty.Delete:
+		e.kittyDelete(opts)
+
+	default:
+		e.kittyReply(opts, errKittyUnsupported)
+	}
+}
+
+// kittyDecode 解码传输的图像数据。只支持直接传输。
+func (e *Emulator) kittyDecode(opts *kitty.Options, data []byte) (image.Image, error) {
+	if opts.Transmission != 0 && opts.Transmission != kitty.Direct {
+		return nil, errKittyUnsupported
+	}
+	if len(data) == 0 {
+		return nil, errKittyNoData
+	}
+
+	format := opts.Format
+	if format == 0 {
+		format = kitty.RGBA
+	}
+	if format != kitty.PNG {
+		if opts.ImageWidth <= 0 || opts.ImageHeight <= 0 {
+			return nil, &kittyError{"EINVAL", "missing image dimensions"}
+		}
+		if opts.ImageWidth*opts.ImageHeight*4 > kittyMaxDataSize {
+			return nil, errKittyTooBig
+		}
+	}
+
+	dec := kitty.Decoder{
+		Decompress: opts.Compression == kitty.Zlib,
+		Format:     format,
+		Width:      opts.ImageWidth,
+		Height:     opts.ImageHeight,
+	}
+	img, err := dec.Decode(bytes.NewReader(data))
+	if err != nil {
+		if format == kitty.PNG {
+			return nil, &kittyError{"EBADPNG", err.Error()}
+		}
+		return nil, &kittyError{"EINVAL", err.Error()}
+	}
+
+	return img, nil
+}
+
+// kittyStore 存储传输的图像。如果只给出了图像编号，则分配一个新的图像 ID。
+func (e *Emulator) kittyStore(opts *kitty.Options, img image.Image) *kittyImage {
+	if e.kittyImages == nil {
+		e.kittyImages = make(map[int]*kittyImage)
+	}
+
+	id := opts.ID
+	if id == 0 {
+		for {
+			e.kittyNextID++
+			if _, ok := e.kittyImages[e.kittyNextID]; !ok {
+				break
+			}
+		}
+		id = e.kittyNextID
+		if opts.Number > 0 {
+			opts.ID = id
+		}
+	}
+
+	// 替换图像会删除它现有的放置。
+	if _, ok := e.kittyImages[id]; ok {
+		e.kittyRemove(id)
+	}
+
+	size := img.Bounds().Dx() * img.Bounds().Dy() * 4
+	ki := &kittyImage{id: id, number: opts.Number, img: img, size: size}
+	e.kittyImages[id] = ki
+	e.kittyOrder = append(e.kittyOrder, id)
+	e.kittyStorage += size
+	e.kittyApplyQuota(id)
+	return ki
+}
+
+// kittyApplyQuota 在存储的图像超过 [kittyMaxStorage] 时删除最早的图像，但
+// 不删除刚刚存储的图像 keep。与 kitty 一样，先删除没有放置的图像，如果仍然
+// 超过限制，再删除有放置的图像及其放置。
+func (e *Emulator) kittyApplyQuota(keep int) {
+	if e.kittyStorage <= kittyMaxStorage {
+		return
+	}
+
+	used := e.kittyUsed()
+	for _, placed := range []bool{false, true} {
+		for _, id := range slices.Clone(e.kittyOrder) {
+			if e.kittyStorage <= kittyMaxStorage {
+				return
+			}
+			if id != keep && used[id] == placed {
+				e.kittyRemove(id)
+			}
+		}
+	}
+}
+
+// kittyRemove 删除存储的图像及其在两个屏幕上的所有放置。
+func (e *Emulator) kittyRemove(id int) {
+	del := func(p ImagePlacement) bool { return p.ImageID == id }
+	for i := range e.scrs {
+		e.scrs[i].images = deleteImages(e.scrs[i].images, del)
+	}
+	e.kittyVirtuals = slices.DeleteFunc(e.kittyVirtuals, func(v kittyVirtual) bool {
+		return v.imageID == id
+	})
+	if ki, ok := e.kittyImages[id]; ok {
+		e.kittyStorage -= ki.size
+		delete(e.kittyImages, id)
+	}
+	e.kittyOrder = slices.DeleteFunc(e.kittyOrder, func(i int) bool { return i == id })
+}
+
+// kittyLookup 按 ID 或编号查找存储的图像。
+func (e *Emulator) kittyLookup(opts *kitty.Options) *kittyImage {
+	if opts.ID > 0 {
+		return e.kittyImages[opts.ID]
+	}
+	if opts.Number > 0 {
+		for i := len(e.kittyOrder) - 1; i >= 0; i-- {
+			if ki := e.kittyImages[e.kittyOrder[i]]; ki != nil && ki.number == opts.Number {
+				opts.ID = ki.id
+				return ki
+			}
+		}
+	}
+	return nil
+}
+
+// kittyPut 在光标位置放置存储的图像。虚拟放置不会显示，而是由 Unicode
+// 占位符引用。除非给出了 C=1，光标随后移动到图像最后一行的最后一列之后。
+func (e *Emulator) kittyPut(opts *kitty.Options, ki *kittyImage) {
+	img := ki.img
+	if opts.X > 0 || opts.Y > 0 || opts.Width > 0 || opts.Height > 0 {
+		b := img.Bounds()
+		crop := image.Rect(b.Min.X+opts.X, b.Min.Y+opts.Y, b.Max.X, b.Max.Y)
+		if opts.Width > 0 {
+			crop.Max.X = min(crop.Max.X, crop.Min.X+opts.Width)
+		}
+		if opts.Height > 0 {
+			crop.Max.Y = min(crop.Max.Y, crop.Min.Y+opts.Height)
+		}
+		img = subImage(img, crop)
+	}
+
+	size := img.Bounds().Size()
+	cols, rows := e.imageCells(size.X+opts.OffsetX, size.Y+opts.OffsetY)
+	if opts.Columns > 0 {
+		cols = opts.Columns
+	}
+	if opts.Rows > 0 {
+		rows = opts.Rows
+	}
+
+	// 具有相同图像 ID 和放置 ID 的放置会被替换。
+	if opts.PlacementID > 0 {
+		e.kittyDeletePlacements(opts.VirtualPlacement, func(p ImagePlacement) bool {
+			return p.ImageID == ki.id && p.PlacementID == opts.PlacementID
+		})
+	}
+
+	if opts.VirtualPlacement {
+		e.kittyVirtuals = append(e.kittyVirtuals, kittyVirtual{
+			imageID:     ki.id,
+			placementID: opts.PlacementID,
+			cols:        cols,
+			rows:        rows,
+		})
+		return
+	}
+
+	x, y := e.scr.CursorPosition()
+	e.scr.addImage(ImagePlacement{
+		Image:       img,
+		Bounds:      uv.Rect(x, y, cols, rows),
+		ImageID:     ki.id,
+		PlacementID: opts.PlacementID,
+		Z:           opts.Z,
+	})
+
+	if !opts.DoNotMoveCursor {
+		for range rows - 1 {
+			e.index()
+		}
+		e.scr.setCursorX(x+cols, false)
+	}
+}
+
+// kittyDelete 处理 a=d 删除命令。小写的删除类型只删除放置，大写的删除
+// 类型还会释放不再被任何放置引用的图像数据。
+func (e *Emulator) kittyDelete(opts *kitty.Options) {
+	d := opts.Delete
+	if d == 0 {
+		d = kitty.DeleteAll
+	}
+
+	// 单元格坐标从 1 开始。
+	cell := uv.Pos(opts.X-1, opts.Y-1)
+	cx, cy := e.scr.CursorPosition()
+	var del func(p ImagePlacement) bool
+	switch d {
+	case kitty.DeleteAll:
+		del = func(ImagePlacement) bool { return true }
+	case kitty.DeleteID, kitty.DeleteNumber:
+		ki := e.kittyLookup(opts)
+		if ki == nil {
+			return
+		}
+		del = func(p ImagePlacement) bool {
+			return p.ImageID == ki.id && (opts.PlacementID == 0 || p.PlacementID == opts.PlacementID)
+		}
+	case kitty.DeleteCursor:
+		del = func(p ImagePlacement) bool { return uv.Pos(cx, cy).In(p.Bounds) }
+	case kitty.DeleteCell:
+		del = func(p ImagePlacement) bool { return cell.In(p.Bounds) }
+	case kitty.DeleteCellZ:
+		del = func(p ImagePlacement) bool { return cell.In(p.Bounds) && p.Z == opts.Z }
+	case kitty.DeleteRange:
+		del = func(p ImagePlacement) bool { return p.ImageID >= opts.X && p.ImageID <= opts.Y }
+	case kitty.DeleteColumn:
+		del = func(p ImagePlacement) bool { return cell.X >= p.Bounds.Min.X && cell.X < p.Bounds.Max.X }
+	case kitty.DeleteRow:
+		del = func(p ImagePlacement) bool { return cell.Y >= p.Bounds.Min.Y && cell.Y < p.Bounds.Max.Y }
+	case kitty.DeleteZ:
+		del = func(p ImagePlacement) bool { return p.Z == opts.Z }
+	default:
+		// 动画帧不受支持。
+		return
+	}
+
+	// 虚拟放置只能按图像 ID 或范围删除。
+	virtual := d == kitty.DeleteID || d == kitty.DeleteNumber || d == kitty.DeleteRange
+	e.kittyDeletePlacements(virtual, func(p ImagePlacement) bool {
+		return p.ImageID > 0 && del(p)
+	})
+
+	if opts.DeleteResources {
+		e.kittyFreeUnused()
+	}
+}
+
+// kittyDeletePlacements 删除当前屏幕上满足 del 的 Kitty 图像放置。如果
+// virtual 为 true，满足 del 的虚拟放置也会被删除。
+func (e *Emulator) kittyDeletePlacements(virtual bool, del func(p ImagePlacement) bool) {
+	e.scr.images = deleteImages(e.scr.images, func(p ImagePlacement) bool {
+		return p.ImageID > 0 && del(p)
+	})
+	if !virtual {
+		return
+	}
+
+	n := 0
+	for _, v := range e.kittyVirtuals {
+		if !del(ImagePlacement{ImageID: v.imageID, PlacementID: v.placementID}) {
+			e.kittyVirtuals[n] = v
+			n++
+		}
+	}
+	e.kittyVirtuals = e.kittyVirtuals[:n]
+}
+
+// kittyUsed 返回被两个屏幕上的放置或虚拟放置引用的图像 ID。
+func (e *Emulator) kittyUsed() map[int]bool {
+	used := make(map[int]bool)
+	for i := range e.scrs {
+		for _, p := range e.scrs[i].images {
+			used[p.ImageID] = true
+		}
+	}
+	for _, v := range e.kittyVirtuals {
+		used[v.imageID] = true
+	}
+	return used
+}
+
+// kittyFreeUnused 释放不再被任何放置引用的图像数据。
+func (e *Emulator) kittyFreeUnused() {
+	used := e.kittyUsed()
+	for _, id := range slices.Clone(e.kittyOrder) {
+		if !used[id] {
+			e.kittyRemove(id)
+		}
+	}
+}
+
+// kittyReply 将命令的结果回复给应用程序。只有当命令给出了图像 ID 或编号时
+// 才会回复，并且遵循安静模式：q=1 抑制 OK 回复，q=2 还抑制错误回复。
+func (e *Emulator) kittyReply(opts *kitty.Options, err error) {
+	if opts.ID == 0 && opts.Number == 0 {
+		return
+	}
+
+	msg := "OK"
+	if err != nil {
+		if opts.Quite >= 2 {
+			return
+		}
+		var kerr *kittyError
+		if !errors.As(err, &kerr) {
+			kerr = &kittyError{"EINVAL", err.Error()}
+		}
+		msg = kerr.Error()
+	} else if opts.Quite >= 1 {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("\x1b_G")
+	if opts.ID > 0 {
+		b.WriteString("i=" + strconv.Itoa(opts.ID))
+	}
+	if opts.Number > 0 {
+		if opts.ID > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString("I=" + strconv.Itoa(opts.Number))
+	}
+	if opts.PlacementID > 0 {
+		b.WriteString(",p=" + strconv.Itoa(opts.PlacementID))
+	}
+	b.WriteString(";" + msg + "\x1b\\")
+
+	_, _ = io.WriteString(e.pw, b.String())
+}
+
+// kittyDiacritics 将 Unicode 占位符的行列变音符号映射到其值。
+var kittyDiacritics = func() map[rune]int {
+	m := map[rune]int{kitty.Diacritic(0): 0}
+	for i := 1; kitty.Diacritic(i) != kitty.Diacritic(0); i++ {
+		m[kitty.Diacritic(i)] = i
+	}
+	return m
+}()
+
+// kittyPlaceholder 是从 Unicode 占位符单元格解析出的信息。
+type kittyPlaceholder struct {
+	imageID, placementID int
+	row, col             int
+}
+
+// parseKittyPlaceholder 解析 Unicode 占位符单元格。图像 ID 编码在前景色
+// 中，放置 ID 编码在下划线颜色中，行、列和图像 ID 的最高字节编码在变音
+// 符号中。缺失的行和列从左侧的单元格推断。
+func parseKittyPlaceholder(c *uv.Cell, prev *kittyPlaceholder) (kittyPlaceholder, bool) {
+	rs := []rune(c.Content)
+	if len(rs) == 0 || rs[0] != kitty.Placeholder {
+		return kittyPlaceholder{}, false
+	}
+
+	ph := kittyPlaceholder{
+		imageID:     colorID(c.Style.Fg),
+		placementID: colorID(c.Style.UnderlineColor),
+		row:         -1,
+		col:         -1,
+	}
+	var marks []int
+	for _, r := range rs[1:] {
+		if v, ok := kittyDiacritics[r]; ok {
+			marks = append(marks, v)
+		}
+	}
+	if len(marks) > 0 {
+		ph.row = marks[0]
+	}
+	if len(marks) > 1 {
+		ph.col = marks[1]
+	}
+	if len(marks) > 2 {
+		ph.imageID |= marks[2] << 24
+	}
+
+	continues := prev != nil && prev.imageID == ph.imageID && prev.placementID == ph.placementID &&
+		(ph.row < 0 || ph.row == prev.row)
+	if ph.row < 0 {
+		ph.row = 0
+		if continues {
+			ph.row = prev.row
+		}
+	}
+	if ph.col < 0 {
+		ph.col = 0
+		if continues {
+			ph.col = prev.col + 1
+		}
+	}
+
+	return ph, true
+}
+
+// colorID 返回编码在颜色中的 ID。索引颜色编码 8 位 ID，RGB 颜色编码 24 位
+// ID。
+func colorID(c color.Color) int {
+	switch c := c.(type) {
+	case nil:
+		return 0
+	case ansi.BasicColor:
+		return int(c)
+	case ansi.IndexedColor:
+		return int(c)
+	default:
+		r, g, b, _ := c.RGBA()
+		return int(r>>8)<<16 | int(g>>8)<<8 | int(b>>8)
+	}
+}
+
+// placeholderPlacements 返回视口中 Unicode 占位符引用的虚拟放置的图像放置。
+// 同一行中连续的占位符单元格会合并为一个放置，其图像是虚拟放置图像的相应
+// 部分。
+func (e *Emulator) placeholderPlacements() []ImagePlacement {
+	if len(e.kittyVirtuals) == 0 {
+		return nil
+	}
+
+	var placements []ImagePlacement
+	for y := range e.Height() {
+		line := e.viewportLine(y)
+		var prev *kittyPlaceholder
+		var run *ImagePlacement
+		var runPh kittyPlaceholder
+		flush := func() {
+			if run != nil {
+				placements = append(placements, *run)
+				run = nil
+			}
+		}
+		for x := 0; x < len(line); x++ {
+			ph, ok := parseKittyPlaceholder(&line[x], prev)
+			if !ok {
+				flush()
+				prev = nil
+				continue
+			}
+			prev = &ph
+
+			if run != nil && ph.imageID == runPh.imageID && ph.placementID == runPh.placementID &&
+				ph.row == runPh.row && ph.col == runPh.col+run.Bounds.Dx() {
+				run.Bounds.Max.X++
+				run.Image = e.placeholderImage(runPh, run.Bounds.Dx())
+				if run.Image == nil {
+					run = nil
+				}
+				continue
+			}
+
+			flush()
+			if img := e.placeholderImage(ph, 1); img != nil {
+				runPh = ph
+				run = &ImagePlacement{
+					Image:       img,
+					Bounds:      uv.Rect(x, y, 1, 1),
+					ImageID:     ph.imageID,
+					PlacementID: ph.placementID,
+				}
+			}
+		}
+		flush()
+	}
+
+	return placements
+}
+
+// placeholderImage 返回由从占位符开始的 n 个单元格覆盖的虚拟放置图像部分。
+func (e *Emulator) placeholderImage(ph kittyPlaceholder, n int) image.Image {
+	ki := e.kittyImages[ph.imageID]
+	if ki == nil {
+		return nil
+	}
+
+	var v *kittyVirtual
+	for i := len(e.kittyVirtuals) - 1; i >= 0; i-- {
+		vp := &e.kittyVirtuals[i]
+		if vp.imageID == ph.imageID && (ph.placementID == 0 || vp.placementID == ph.placementID) {
+			v = vp
+			break
+		}
+	}
+	if v == nil || ph.row >= v.rows || ph.col >= v.cols {
+		return nil
+	}
+
+	b := ki.img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	end := min(ph.col+n, v.cols)
+	return subImage(ki.img, image.Rect(
+		b.Min.X+w*ph.col/v.cols, b.Min.Y+h*ph.row/v.rows,
+		b.Min.X+w*end/v.cols, b.Min.Y+h*(ph.row+1)/v.rows,
+	))
+}
+
+// subImage 返回图像在给定矩形内的部分。如果图像不支持子图像，则返回整个
+// 图像。
+func subImage(img image.Image, r image.Rectangle) image.Image {
+	if si, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return si.SubImage(r)
+	}
+	return img
+}
@@ -0,0 +1,209 @@
+package vt
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"image/color"
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/purpose168/charm-experimental-packages-cn/ansi"
+	"github.com/purpose168/charm-experimental-packages-cn/ansi/kitty"
+)
+
+// kittyRGBA 返回给定大小的不透明白色 RGBA 像素数据。
+func kittyRGBA(width, height int) []byte {
+	return bytes.Repeat([]byte{0xff, 0xff, 0xff, 0xff}, width*height)
+}
+
+// kittyCmd 返回一个 Kitty 图形 APC 序列。
+func kittyCmd(control string, data []byte) string {
+	return "\x1b_G" + control + ";" + base64.StdEncoding.EncodeToString(data) + "\x1b\\"
+}
+
+func TestKittyGraphicsTransmitAndPut(t *testing.T) {
+	term := newTestTerminal(t, 10, 5)
+	term.SetCellSize(10, 20)
+	term.WriteString("ab")
+
+	got := readInput(t, term, func() {
+		term.WriteString(kittyCmd("a=T,i=1,s=25,v=30", kittyRGBA(25, 30)))
+	})
+	if want := "\x1b_Gi=1;OK\x1b\\"; got != want {
+		t.Errorf("reply = %q, want %q", got, want)
+	}
+
+	placements := term.ImagePlacements()
+	if len(placements) != 1 {
+		t.Fatalf("expected 1 placement, got %d", len(placements))
+	}
+	if want := uv.Rect(2, 0, 3, 2); placements[0].Bounds != want {
+		t.Errorf("bounds = %v, want %v", placements[0].Bounds, want)
+	}
+	if placements[0].ImageID != 1 {
+		t.Errorf("image id = %d", placements[0].ImageID)
+	}
+	if x, y := term.scr.CursorPosition(); x != 5 || y != 1 {
+		t.Errorf("cursor = (%d, %d), want (5, 1)", x, y)
+	}
+
+	// Put the same image again with a placement ID, without moving the cursor.
+	term.WriteString("\x1b[4;1H" + kittyCmd("a=p,i=1,p=7,c=4,r=1,C=1,q=1", nil))
+	placements = term.ImagePlacements()
+	if len(placements) != 2 {
+		t.Fatalf("expected 2 placements, got %d", len(placements))
+	}
+	if want := uv.Rect(0, 3, 4, 1); placements[1].Bounds != want || placements[1].PlacementID != 7 {
+		t.Errorf("placement = %v (p=%d), want %v", placements[1].Bounds, placements[1].PlacementID, want)
+	}
+	if x, y := term.scr.CursorPosition(); x != 0 || y != 3 {
+		t.Errorf("cursor = (%d, %d), want (0, 3)", x, y)
+	}
+
+	// Delete the placement by ID.
+	term.WriteString(kittyCmd("a=d,d=i,i=1,p=7", nil))
+	if got := len(term.ImagePlacements()); got != 1 {
+		t.Errorf("expected 1 placement after delete, got %d", got)
+	}
+
+	// Delete everything and free the image data.
+	term.WriteString(kittyCmd("a=d,d=A", nil))
+	if got := len(term.ImagePlacements()); got != 0 {
+		t.Errorf("expected no placements after delete all, got %d", got)
+	}
+	got = readInput(t, term, func() { term.WriteString(kittyCmd("a=p,i=1", nil)) })
+	if want := "\x1b_Gi=1;ENOENT:image not found\x1b\\"; got != want {
+		t.Errorf("reply = %q, want %q", got, want)
+	}
+}
+
+func TestKittyGraphicsChunkedZlib(t *testing.T) {
+	term := newTestTerminal(t, 10, 5)
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	_, _ = zw.Write(kittyRGBA(4, 4))
+	_ = zw.Close()
+	enc := base64.StdEncoding.EncodeToString(buf.Bytes())
+	mid := len(enc) / 8 * 4
+
+	got := readInput(t, term, func() {
+		term.WriteString("\x1b_Ga=t,I=3,f=32,s=4,v=4,o=z,m=1;" + enc[:mid] + "\x1b\\")
+		term.WriteString("\x1b_Gm=0;" + enc[mid:] + "\x1b\\")
+	})
+	if want := "\x1b_Gi=1,I=3;OK\x1b\\"; got != want {
+		t.Errorf("reply = %q, want %q", got, want)
+	}
+	if ki := term.kittyImages[1]; ki == nil || ki.img.Bounds().Dx() != 4 {
+		t.Fatalf("expected image 1 to be stored")
+	}
+	if got := len(term.ImagePlacements()); got != 0 {
+		t.Errorf("transmit should not place the image, got %d placements", got)
+	}
+}
+
+func TestKittyGraphicsQuery(t *testing.T) {
+	term := newTestTerminal(t, 10, 5)
+
+	got := readInput(t, term, func() {
+		term.WriteString(kittyCmd("a=q,i=31,s=1,v=1", kittyRGBA(1, 1)))
+	})
+	if want := "\x1b_Gi=31;OK\x1b\\"; got != want {
+		t.Errorf("reply = %q, want %q", got, want)
+	}
+	if len(term.kittyImages) != 0 {
+		t.Errorf("query should not store the image")
+	}
+
+	got = readInput(t, term, func() {
+		term.WriteString(kittyCmd("a=q,i=31,t=f", []byte("/tmp/image.png")))
+	})
+	if want := "\x1b_Gi=31;ENOTSUP:unsupported action or medium\x1b\\"; got != want {
+		t.Errorf("reply = %q, want %q", got, want)
+	}
+}
+
+func TestKittyGraphicsPlaceholders(t *testing.T) {
+	term := newTestTerminal(t, 10, 5)
+	term.SetCellSize(10, 20)
+	term.WriteString(kittyCmd("a=T,U=1,i=5,s=30,v=40,c=3,r=2,q=2", kittyRGBA(30, 40)))
+
+	if got := len(term.ImagePlacements()); got != 0 {
+		t.Fatalf("virtual placement should not be visible, got %d", got)
+	}
+
+	ph := string(kitty.Placeholder)
+	term.WriteString(ansi.Style{}.ForegroundColor(color.RGBA{0, 0, 5, 255}).String())
+	term.WriteString(ph + string(kitty.Diacritic(0)) + string(kitty.Diacritic(0)) + ph + ph + "\r\n")
+	term.WriteString(ph + string(kitty.Diacritic(1)) + string(kitty.Diacritic(1)) + ph)
+
+	placements := term.ImagePlacements()
+	if len(placements) != 2 {
+		t.Fatalf("expected 2 placements, got %d", len(placements))
+	}
+	if want := uv.Rect(0, 0, 3, 1); placements[0].Bounds != want {
+		t.Errorf("first row bounds = %v, want %v", placements[0].Bounds, want)
+	}
+	if size := placements[0].Image.Bounds().Size(); size.X != 30 || size.Y != 20 {
+		t.Errorf("first row image size = %v", size)
+	}
+	if want := uv.Rect(0, 1, 2, 1); placements[1].Bounds != want {
+		t.Errorf("second row bounds = %v, want %v", placements[1].Bounds, want)
+	}
+	if r := placements[1].Image.Bounds(); r.Min.X != 10 || r.Min.Y != 20 || r.Dx() != 20 {
+		t.Errorf("second row image rect = %v", r)
+	}
+}
+
+func TestKittyGraphicsErase(t *testing.T) {
+	term := newTestTerminal(t, 10, 5)
+	term.SetCellSize(10, 20)
+	term.WriteString(kittyCmd("a=T,i=1,s=20,v=20,q=2", kittyRGBA(20, 20)))
+
+	// Erasing characters or lines leaves Kitty placements alone.
+	term.WriteString("\x1b[H\x1b[2K\x1b[5X")
+	if got := len(term.ImagePlacements()); got != 1 {
+		t.Fatalf("expected 1 placement after EL and ECH, got %d", got)
+	}
+
+	term.WriteString("\x1b[J")
+	if got := len(term.ImagePlacements()); got != 0 {
+		t.Errorf("expected no placements after ED, got %d", got)
+	}
+}
+
+func TestKittyGraphicsStorageQuota(t *testing.T) {
+	term := newTestTerminal(t, 10, 5)
+	term.WriteString(kittyCmd("a=t,i=1,s=1,v=1,q=2", kittyRGBA(1, 1)))
+	term.WriteString(kittyCmd("a=T,i=2,s=1,v=1,q=2", kittyRGBA(1, 1)))
+	term.WriteString(kittyCmd("a=t,i=3,s=1,v=1,q=2", kittyRGBA(1, 1)))
+
+	// Pretend the stored images fill most of the quota.
+	for _, ki := range term.kittyImages {
+		ki.size = kittyMaxStorage / 3
+	}
+	term.kittyStorage = kittyMaxStorage / 3 * 3
+
+	// Storing another image evicts the oldest images without placements
+	// first, then images with placements.
+	term.WriteString(kittyCmd("a=t,i=4,s=1,v=1,q=2", kittyRGBA(1, 1)))
+	if _, ok := term.kittyImages[1]; ok {
+		t.Error("image 1 was not evicted")
+	}
+	for _, id := range []int{2, 3, 4} {
+		if _, ok := term.kittyImages[id]; !ok {
+			t.Errorf("image %d was evicted", id)
+		}
+	}
+
+	term.kittyImages[4].size = kittyMaxStorage / 3
+	term.kittyStorage += kittyMaxStorage/3 - 4
+	term.WriteString(kittyCmd("a=t,i=5,s=1,v=1,q=2", kittyRGBA(1, 1)))
+	if _, ok := term.kittyImages[3]; ok {
+		t.Error("image 3 was not evicted")
+	}
+	if _, ok := term.kittyImages[2]; !ok || len(term.ImagePlacements()) != 1 {
+		t.Error("placed image 2 was evicted before the quota required it")
+	}
+}
@@ -0,0 +1,111 @@
+package vt
+
+import (
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+// LineAttr 是行的尺寸属性，由 DECSWL、DECDWL 和 DECDHL 设置。
+//
+// 双宽和双高的行只显示屏幕一半的列，单元格仍然存储在 0 到 宽度/2 的列中，
+// 由渲染器负责将它们放大。
+type LineAttr uint8
+
+// 行尺寸属性。
+const (
+	// LineSingleWidth 是默认的单宽单高行。
+	LineSingleWidth LineAttr = iota
+	// LineDoubleWidth 是双宽单高行。
+	LineDoubleWidth
+	// LineDoubleHeightTop 是双宽双高行的上半部分。
+	LineDoubleHeightTop
+	// LineDoubleHeightBottom 是双宽双高行的下半部分。
+	LineDoubleHeightBottom
+)
+
+// IsDoubleWidth 报告该行是否是双宽的。双高的行也是双宽的。
+func (a LineAttr) IsDoubleWidth() bool {
+	return a != LineSingleWidth
+}
+
+// String 返回设置该属性的转义序列。
+func (a LineAttr) String() string {
+	switch a {
+	case LineDoubleWidth:
+		return "\x1b#6"
+	case LineDoubleHeightTop:
+		return "\x1b#3"
+	case LineDoubleHeightBottom:
+		return "\x1b#4"
+	default:
+		return "\x1b#5"
+	}
+}
+
+// columns 返回宽度为 width 的屏幕上具有该属性的行可以显示的列数。
+func (a LineAttr) columns(width int) int {
+	if a.IsDoubleWidth() {
+		return max(1, width/2)
+	}
+	return width
+}
+
+// LineAttr 返回第 y 行的尺寸属性。
+func (s *Screen) LineAttr(y int) LineAttr {
+	if y < 0 || y >= len(s.lineAttrs) {
+		return LineSingleWidth
+	}
+	return s.lineAttrs[y]
+}
+
+// setLineAttr 设置第 y 行的尺寸属性。
+func (s *Screen) setLineAttr(y int, a LineAttr) {
+	if y < 0 || y >= len(s.lineAttrs) || s.lineAttrs[y] == a {
+		return
+	}
+	s.lineAttrs[y] = a
+	s.addDamage(RectDamage(uv.Rect(0, y, s.Width(), 1)))
+}
+
+// resetLineAttrs 将 top 到 bottom（不包含）之间的行重置为单宽单高行。
+func (s *Screen) resetLineAttrs(top, bottom int) {
+	for y := max(0, top); y < bottom && y < len(s.lineAttrs); y++ {
+		s.setLineAttr(y, LineSingleWidth)
+	}
+}
+
+// lineWidth 返回第 y 行可以显示的列数。双宽的行只有屏幕一半的列。
+func (s *Screen) lineWidth(y int) int {
+	return s.LineAttr(y).columns(s.Width())
+}
+
+// LineAttr 返回当前屏幕第 y 行的尺寸属性。
+func (e *Emulator) LineAttr(y int) LineAttr {
+	return e.scr.LineAttr(y)
+}
+
+// setLineAttr 设置光标所在行的尺寸属性。与 VT100 一样，单宽的行变为双宽时，
+// 屏幕右半部分的字符会丢失，光标会被限制在行的新宽度内。
+func (e *Emulator) setLineAttr(a LineAttr) {
+	x, y := e.scr.CursorPosition()
+	old := e.scr.LineAttr(y)
+	e.scr.setLineAttr(y, a)
+
+	if w := e.scr.Width(); !old.IsDoubleWidth() && a.IsDoubleWidth() {
+		e.scr.FillArea(e.scr.blankCell(), uv.Rect(a.columns(w), y, w-a.columns(w), 1))
+	}
+	if old != a {
+		e.atPhantom = false
+	}
+	e.scr.setCursor(x, y, false)
+}
+
+// screenAlignment 用 "E" 填充屏幕，重置边距和所有行的尺寸属性，并将光标移动
+// 到左上角。这相当于 DECALN。
+func (e *Emulator) screenAlignment() {
+	e.scr.resetLineAttrs(0, e.scr.Height())
+	e.scr.Fill(&uv.Cell{Content: "E", Width: 1})
+	e.scr.setVerticalMargins(0, e.scr.Height())
+	e.scr.setHorizontalMargins(0, e.scr.Width())
+	e.atPhantom = false
+	e.scr.setCursor(0, 0, false)
+}
@@ -0,0 +1,152 @@
+package vt
+
+import (
+	"strings"
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+func TestLineAttrs(t *testing.T) {
+	term := newTestTerminal(t, 10, 5)
+	term.WriteString("0123456789\r\n\x1b#3top\r\n\x1b#4top\r\n\x1b#6wide")
+
+	want := []LineAttr{LineSingleWidth, LineDoubleHeightTop, LineDoubleHeightBottom, LineDoubleWidth}
+	for y, a := range want {
+		if got := term.LineAttr(y); got != a {
+			t.Errorf("line %d attr = %d, want %d", y, got, a)
+		}
+	}
+
+	// The cursor is limited to half the columns of a double-width line and
+	// autowrap happens at the middle of the screen.
+	term.WriteString("\x1b[4;10H")
+	if x, y := term.scr.CursorPosition(); x != 4 || y != 3 {
+		t.Errorf("cursor = (%d, %d), want (4, 3)", x, y)
+	}
+	term.WriteString("\x1b[4;1Habcdefg")
+	if got := termText(term)[3]; got != "abcde     " {
+		t.Errorf("line 3 = %q, want %q", got, "abcde     ")
+	}
+	if !term.IsWrapped(3) {
+		t.Error("line 3 should be wrapped")
+	}
+
+	// Making a line double width erases its right half.
+	term.WriteString("\x1b[1;1H\x1b#6")
+	if got := termText(term)[0]; got != "01234     " {
+		t.Errorf("line 0 = %q, want %q", got, "01234     ")
+	}
+	term.WriteString("\x1b#5")
+	if got := term.LineAttr(0); got != LineSingleWidth {
+		t.Errorf("line 0 attr = %d, want single width", got)
+	}
+
+	// Line attributes move with inserted lines.
+	term.WriteString("\x1b[2;1H\x1b[L")
+	if got := term.LineAttr(2); got != LineDoubleHeightTop {
+		t.Errorf("line 2 attr = %d, want double-height top", got)
+	}
+	if got := term.LineAttr(1); got != LineSingleWidth {
+		t.Errorf("line 1 attr = %d, want single width", got)
+	}
+
+	// Lines erased completely by ED become single width.
+	term.WriteString("\x1b[3;1H\x1b[J")
+	for y := range 5 {
+		if got := term.LineAttr(y); got != LineSingleWidth {
+			t.Errorf("line %d attr = %d after ED, want single width", y, got)
+		}
+	}
+}
+
+func TestLineAttrsMargins(t *testing.T) {
+	term := newTestTerminal(t, 10, 4)
+	term.WriteString("\x1b#3top")
+
+	// With left and right margins only part of each row moves, so the line
+	// attributes stay with their rows.
+	term.WriteString("\x1b[?69h\x1b[3;6s\x1b[1;4H\x1b[L")
+	if got := term.LineAttr(0); got != LineDoubleHeightTop {
+		t.Errorf("line 0 attr = %d after IL inside margins, want double-height top", got)
+	}
+	if got := term.LineAttr(1); got != LineSingleWidth {
+		t.Errorf("line 1 attr = %d after IL inside margins, want single width", got)
+	}
+	term.WriteString("\x1b[M")
+	if got := term.LineAttr(0); got != LineDoubleHeightTop {
+		t.Errorf("line 0 attr = %d after DL inside margins, want double-height top", got)
+	}
+}
+
+func TestLineAttrErase(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	term.WriteString("\x1b#6abcde\x1b[1;3H\x1b[K")
+	if got := termText(term)[0]; got != "ab        " {
+		t.Errorf("line 0 = %q, want %q", got, "ab        ")
+	}
+	term.WriteString("\x1b[1;2H\x1b[J")
+	if got := term.LineAttr(0); got != LineDoubleWidth {
+		t.Errorf("line 0 attr = %d, want double width after partial ED", got)
+	}
+	term.WriteString("\x1b[2J")
+	if got := term.LineAttr(0); got != LineSingleWidth {
+		t.Errorf("line 0 attr = %d, want single width after ED 2", got)
+	}
+}
+
+func TestLineAttrRender(t *testing.T) {
+	term := newTestTerminal(t, 6, 2)
+	term.WriteString("ab\r\n\x1b#6cd")
+	if got, want := term.Render(), "ab\n\x1b#6cd"; got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+
+	scr := uv.NewScreenBuffer(6, 2)
+	term.Draw(scr, scr.Bounds())
+	for x, want := range []string{"c", " ", "d", " "} {
+		if c := scr.CellAt(x, 1); c == nil || c.Content != want {
+			t.Errorf("drawn cell %d = %+v, want %q", x, c, want)
+		}
+	}
+}
+
+func TestScreenAlignment(t *testing.T) {
+	term := newTestTerminal(t, 4, 3)
+	term.WriteString("\x1b[2;3r\x1b[2;2H\x1b#6\x1b#8")
+
+	for y, line := range termText(term) {
+		if line != "EEEE" {
+			t.Errorf("line %d = %q, want %q", y, line, "EEEE")
+		}
+		if got := term.LineAttr(y); got != LineSingleWidth {
+			t.Errorf("line %d attr = %d, want single width", y, got)
+		}
+	}
+	if got := term.scr.ScrollRegion(); got != term.scr.Bounds() {
+		t.Errorf("scroll region = %v, want %v", got, term.scr.Bounds())
+	}
+	if x, y := term.scr.CursorPosition(); x != 0 || y != 0 {
+		t.Errorf("cursor = (%d, %d), want (0, 0)", x, y)
+	}
+}
+
+func TestLineAttrSnapshot(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	term.WriteString("\x1b#3big\r\n\x1b#4big")
+
+	var b strings.Builder
+	if _, err := term.Snapshot().WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	other := newTestTerminal(t, 10, 2)
+	other.WriteString(b.String())
+	for y, want := range []LineAttr{LineDoubleHeightTop, LineDoubleHeightBottom} {
+		if got := other.LineAttr(y); got != want {
+			t.Errorf("line %d attr = %d, want %d", y, got, want)
+		}
+	}
+	if got := other.Render(); got != "\x1b#3big\n\x1b#4big" {
+		t.Errorf("Render() = %q", got)
+	}
+}
@@ -2,15 +2,14 @@ package vt
 
 import "github.com/purpose168/charm-experimental-packages-cn/ansi"
 
-// resetModes 将所有模式重置为其默认值。
-func (e *Emulator) resetModes() {
-	e.modes = ansi.Modes{
-		// 已识别的模式及其默认值。
+// defaultModes 返回已识别的模式及其默认值。
+func defaultModes() ansi.Modes {
+	return ansi.Modes{
 		ansi.ModeCursorKeys:          ansi.ModeReset, // ?1
 		ansi.ModeOrigin:              ansi.ModeReset, // ?6
 		ansi.ModeAutoWrap:            ansi.ModeSet,   // ?7
 		ansi.ModeMouseX10:            ansi.ModeReset, // ?9
-		ansi.ModeLineFeedNewLine:     ansi.ModeReset, // ?20
+		ansi.ModeLineFeedNewLine:     ansi.ModeReset, // 20
 		ansi.ModeTextCursorEnable:    ansi.ModeSet,   // ?25
 		ansi.ModeNumericKeypad:       ansi.ModeReset, // ?66
 		ansi.ModeLeftRightMargin:     ansi.ModeReset, // ?69
@@ -19,12 +18,23 @@ func (e *Emulator) resetModes() {
 		ansi.ModeMouseButtonEvent:    ansi.ModeReset, // ?1002
 		ansi.ModeMouseAnyEvent:       ansi.ModeReset, // ?1003
 		ansi.ModeFocusEvent:          ansi.ModeReset, // ?1004
+		ansi.ModeMouseExtUtf8:        ansi.ModeReset, // ?1005
 		ansi.ModeMouseExtSgr:         ansi.ModeReset, // ?1006
+		ansi.ModeMouseExtUrxvt:       ansi.ModeReset, // ?1015
+		ansi.ModeMouseExtSgrPixel:    ansi.ModeReset, // ?1016
 		ansi.ModeAltScreen:           ansi.ModeReset, // ?1047
 		ansi.ModeSaveCursor:          ansi.ModeReset, // ?1048
 		ansi.ModeAltScreenSaveCursor: ansi.ModeReset, // ?1049
 		ansi.ModeBracketedPaste:      ansi.ModeReset, // ?2004
+		ansi.ModeSynchronizedOutput:  ansi.ModeReset, // ?2026
+		ansi.ModeUnicodeCore:         ansi.ModeReset, // ?2027
+		ansi.ModeInBandResize:        ansi.ModeReset, // ?2048
 	}
+}
+
+// resetModes 将所有模式重置为其默认值。
+func (e *Emulator) resetModes() {
+	e.modes = defaultModes()
 
 	// 设置模式效果。
 	for mode, setting := range e.modes {
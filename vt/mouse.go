@@ -1,7 +1,9 @@
 package vt
 
 import (
+	"fmt"
 	"io"
+	"unicode/utf8"
 
 	uv "github.com/charmbracelet/ultraviolet"
 	"github.com/purpose168/charm-experimental-packages-cn/ansi"
@@ -57,22 +59,35 @@ type MouseWheel = uv.MouseWheelEvent
 // MouseMotion 表示鼠标移动事件。
 type MouseMotion = uv.MouseMotionEvent
 
+// X10 和 UTF-8 鼠标编码可以表示的最大坐标（从 0 开始）。
+const (
+	x10MouseLimit  = 255 - 32
+	utf8MouseLimit = 2047 - 32
+)
+
+// highlightTracking 是高亮鼠标跟踪的状态。它由应用程序在按钮按下后用
+// CSI func ; startx ; starty ; firstrow ; lastrow T 开始。
+type highlightTracking struct {
+	// start 是高亮区域的起点。
+	start uv.Position
+	// first 和 last 限制结束位置所在的行，范围为 [first, last)。
+	first, last int
+}
+
 // SendMouse 向终端发送鼠标事件。这可以是任何类型的鼠标事件，
 // 例如 [MouseClick]、[MouseRelease]、[MouseWheel] 或 [MouseMotion]。
+//
+// 事件按照当前的鼠标跟踪模式过滤，并使用当前的鼠标编码（X10、UTF-8、urxvt、
+// SGR 或 SGR 像素）进行编码。SGR 像素编码的坐标根据 [Emulator.CellSize]
+// 计算，指向单元格的左上角。
 func (e *Emulator) SendMouse(m Mouse) {
-	// XXX: 支持 [Utf8ExtMouseMode]、[UrxvtExtMouseMode] 和
-	// [SgrPixelExtMouseMode]。
-	var (
-		enc  ansi.Mode
-		mode ansi.Mode
-	)
-
+	var mode ansi.Mode
 	for _, m := range []ansi.DECMode{
-		ansi.X10MouseMode,         // 按钮按下
-		ansi.NormalMouseMode,      // 按钮按下/释放
-		ansi.HighlightMouseMode,   // 按钮按下/释放/高亮
-		ansi.ButtonEventMouseMode, // 按钮按下/释放/单元格移动
-		ansi.AnyEventMouseMode,    // 按钮按下/释放/所有移动
+		ansi.ModeMouseX10,         // 按钮按下
+		ansi.ModeMouseNormal,      // 按钮按下/释放
+		ansi.ModeMouseHighlight,   // 按钮按下/释放/高亮
+		ansi.ModeMouseButtonEvent, // 按钮按下/释放/单元格移动
+		ansi.ModeMouseAnyEvent,    // 按钮按下/释放/所有移动
 	} {
 		if e.isModeSet(m) {
 			mode = m
@@ -83,33 +98,140 @@ func (e *Emulator) SendMouse(m Mouse) {
 		return
 	}
 
+	var enc ansi.Mode
 	for _, mm := range []ansi.DECMode{
-		// ansi.Utf8ExtMouseMode,
-		ansi.SgrExtMouseMode,
-		// ansi.UrxvtExtMouseMode,
-		// ansi.SgrPixelExtMouseMode,
+		ansi.ModeMouseExtUtf8,
+		ansi.ModeMouseExtUrxvt,
+		ansi.ModeMouseExtSgr,
+		ansi.ModeMouseExtSgrPixel,
 	} {
 		if e.isModeSet(mm) {
 			enc = mm
 		}
 	}
 
-	// 编码按钮
 	mouse := m.Mouse()
 	_, isMotion := m.(MouseMotion)
 	_, isRelease := m.(MouseRelease)
+	if isRelease && mouse.Button >= MouseWheelUp && mouse.Button <= MouseWheelRight {
+		// 滚轮没有释放事件。
+		return
+	}
+
+	switch mode {
+	case ansi.ModeMouseX10:
+		// X10 只报告按钮按下，并且不报告修饰键。
+		if isMotion || isRelease {
+			return
+		}
+		mouse.Mod = 0
+	case ansi.ModeMouseNormal, ansi.ModeMouseHighlight:
+		if isMotion {
+			return
+		}
+		if isRelease && e.highlight != nil {
+			e.reportHighlight(mouse)
+			return
+		}
+	case ansi.ModeMouseButtonEvent:
+		if isMotion && mouse.Button == MouseNone {
+			return
+		}
+	}
+
+	sgr := enc == ansi.ModeMouseExtSgr || enc == ansi.ModeMouseExtSgrPixel
+	if isRelease && !sgr {
+		// 除 SGR 外的编码不报告释放的是哪个按钮。
+		mouse.Button = MouseNone
+	}
+
+	// 编码按钮
 	b := ansi.EncodeMouseButton(mouse.Button, isMotion,
 		mouse.Mod.Contains(ModShift),
 		mouse.Mod.Contains(ModAlt),
 		mouse.Mod.Contains(ModCtrl))
 
+	x, y := max(0, mouse.X), max(0, mouse.Y)
 	switch enc {
-	// XXX: 支持 [ansi.HighlightMouseMode]。
-	// XXX: 支持 [ansi.Utf8ExtMouseMode]、[ansi.UrxvtExtMouseMode] 和
-	// [ansi.SgrPixelExtMouseMode]。
-	case nil: // X10 鼠标编码
-		_, _ = io.WriteString(e.pw, ansi.MouseX10(b, mouse.X, mouse.Y))
-	case ansi.SgrExtMouseMode: // SGR 鼠标编码
-		_, _ = io.WriteString(e.pw, ansi.MouseSgr(b, mouse.X, mouse.Y, isRelease))
+	case nil, ansi.ModeMouseExtUtf8: // X10 和 UTF-8 鼠标编码
+		ext := enc != nil
+		seq := []byte("\x1b[M")
+		if ext {
+			seq = utf8.AppendRune(seq, rune(b)+32)
+		} else {
+			seq = append(seq, b+32)
+		}
+		seq = appendMouseCoord(seq, x, ext)
+		seq = appendMouseCoord(seq, y, ext)
+		_, _ = e.pw.Write(seq)
+	case ansi.ModeMouseExtUrxvt: // urxvt 鼠标编码
+		_, _ = fmt.Fprintf(e.pw, "\x1b[%d;%d;%dM", int(b)+32, x+1, y+1)
+	case ansi.ModeMouseExtSgr: // SGR 鼠标编码
+		_, _ = io.WriteString(e.pw, ansi.MouseSgr(b, x, y, isRelease))
+	case ansi.ModeMouseExtSgrPixel: // SGR 像素鼠标编码
+		_, _ = io.WriteString(e.pw, ansi.MouseSgr(b, x*e.cellWidth, y*e.cellHeight, isRelease))
+	}
+}
+
+// appendMouseCoord 使用 X10 鼠标编码（如果 ext 为 true，则使用 UTF-8 鼠标编码）
+// 追加从 0 开始的坐标 v。与 xterm 相同，超出范围的坐标被限制为可表示的最大值，
+// 而最大值被编码为 0 字节，作为越界标记。
+func appendMouseCoord(b []byte, v int, ext bool) []byte {
+	limit := x10MouseLimit
+	if ext {
+		limit = utf8MouseLimit
+	}
+	if v >= limit {
+		return append(b, 0)
+	}
+	v += 32 + 1
+	if ext && v >= utf8.RuneSelf {
+		return utf8.AppendRune(b, rune(v))
+	}
+	return append(b, byte(v))
+}
+
+// startHighlight 开始或中止高亮鼠标跟踪。params 是 func、startx、starty、
+// firstrow 和 lastrow，都从 1 开始。如果 func 为 0，则中止跟踪。
+func (e *Emulator) startHighlight(params ansi.Params) {
+	fn, _, _ := params.Param(0, 0)
+	if fn == 0 || !e.isModeSet(ansi.ModeMouseHighlight) {
+		e.highlight = nil
+		return
+	}
+
+	x, _, _ := params.Param(1, 1)
+	y, _, _ := params.Param(2, 1)
+	first, _, _ := params.Param(3, 1)
+	last, _, _ := params.Param(4, 1)
+	e.highlight = &highlightTracking{
+		start: uv.Pos(max(1, x)-1, max(1, y)-1),
+		first: max(1, first) - 1,
+		last:  max(1, last) - 1,
+	}
+}
+
+// reportHighlight 在按钮释放时报告高亮区域的结束位置，并结束高亮鼠标跟踪。
+// 结束位置的行被限制在 firstrow 和 lastrow 之间。
+func (e *Emulator) reportHighlight(mouse uv.Mouse) {
+	h := e.highlight
+	e.highlight = nil
+
+	ext := e.isModeSet(ansi.ModeMouseExtUtf8)
+	end := uv.Pos(max(0, mouse.X), max(h.first, min(mouse.Y, h.last-1)))
+	seq := []byte("\x1b[")
+	if end == h.start {
+		// CSI t CxCy
+		seq = append(seq, 't')
+		seq = appendMouseCoord(seq, end.X, ext)
+		seq = appendMouseCoord(seq, end.Y, ext)
+	} else {
+		// CSI T CxCyCxCyCxCy
+		seq = append(seq, 'T')
+		for _, p := range []uv.Position{h.start, end, uv.Pos(max(0, mouse.X), max(0, mouse.Y))} {
+			seq = appendMouseCoord(seq, p.X, ext)
+			seq = appendMouseCoord(seq, p.Y, ext)
+		}
 	}
+	_, _ = e.pw.Write(seq)
 }
@@ -0,0 +1,91 @@
+package vt
+
+import (
+	"testing"
+)
+
+func TestSendMouseEncodings(t *testing.T) {
+	cases := []struct {
+		name  string
+		modes string
+		event Mouse
+		want  string
+	}{
+		{"x10", "?1000", MouseClick{X: 1, Y: 2, Button: MouseLeft}, "\x1b[M\x20\x22\x23"},
+		{"x10 release", "?1000", MouseRelease{X: 1, Y: 2, Button: MouseLeft}, "\x1b[M\x23\x22\x23"},
+		{"x10 limit", "?1000", MouseClick{X: 222, Y: 300, Button: MouseLeft}, "\x1b[M\x20\xff\x00"},
+		{"utf8", "?1000;1005", MouseClick{X: 100, Y: 2, Button: MouseLeft}, "\x1b[M\x20\u0085\x23"},
+		{"utf8 limit", "?1000;1005", MouseClick{X: 3000, Y: 2, Button: MouseLeft}, "\x1b[M\x20\x00\x23"},
+		{"urxvt", "?1000;1015", MouseClick{X: 300, Y: 2, Button: MouseRight, Mod: ModCtrl}, "\x1b[50;301;3M"},
+		{"urxvt release", "?1000;1015", MouseRelease{X: 1, Y: 2, Button: MouseRight}, "\x1b[35;2;3M"},
+		{"sgr", "?1000;1006", MouseRelease{X: 1, Y: 2, Button: MouseRight}, "\x1b[<2;2;3m"},
+		{"sgr pixel", "?1000;1016", MouseClick{X: 1, Y: 2, Button: MouseLeft}, "\x1b[<0;11;41M"},
+		{"sgr over urxvt", "?1000;1015;1006", MouseClick{X: 1, Y: 2, Button: MouseLeft}, "\x1b[<0;2;3M"},
+		{"x10 mode", "?9", MouseClick{X: 1, Y: 2, Button: MouseLeft, Mod: ModShift}, "\x1b[M\x20\x22\x23"},
+		{"button event motion", "?1002;1006", MouseMotion{X: 1, Y: 2, Button: MouseLeft}, "\x1b[<32;2;3M"},
+		{"any event motion", "?1003;1006", MouseMotion{X: 1, Y: 2}, "\x1b[<35;2;3M"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			term := newTestTerminal(t, 10, 5)
+			term.SetCellSize(10, 20)
+			term.WriteString("\x1b[" + tc.modes + "h")
+			got := readInput(t, term, func() { term.SendMouse(tc.event) })
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSendMouseFiltered(t *testing.T) {
+	cases := []struct {
+		name  string
+		modes string
+		event Mouse
+	}{
+		{"no tracking", "?1006", MouseClick{X: 1, Y: 2, Button: MouseLeft}},
+		{"x10 mode release", "?9", MouseRelease{X: 1, Y: 2, Button: MouseLeft}},
+		{"normal motion", "?1000", MouseMotion{X: 1, Y: 2, Button: MouseLeft}},
+		{"button event motion", "?1002", MouseMotion{X: 1, Y: 2}},
+		{"wheel release", "?1000;1006", MouseRelease{X: 1, Y: 2, Button: MouseWheelUp}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			term := newTestTerminal(t, 10, 5)
+			term.WriteString("\x1b[" + tc.modes + "h")
+			if got := readInput(t, term, func() { term.SendMouse(tc.event) }); got != "" {
+				t.Errorf("got %q, want nothing", got)
+			}
+		})
+	}
+}
+
+func TestSendMouseHighlight(t *testing.T) {
+	term := newTestTerminal(t, 10, 5)
+	term.WriteString("\x1b[?1001h")
+
+	got := readInput(t, term, func() { term.SendMouse(MouseClick{X: 1, Y: 1, Button: MouseLeft}) })
+	if want := "\x1b[M\x20\x22\x22"; got != want {
+		t.Errorf("press: got %q, want %q", got, want)
+	}
+
+	// The end row is limited to the rows [2, 4).
+	term.WriteString("\x1b[1;2;2;2;4T")
+	got = readInput(t, term, func() { term.SendMouse(MouseRelease{X: 5, Y: 4, Button: MouseLeft}) })
+	if want := "\x1b[T\x22\x22\x26\x23\x26\x25"; got != want {
+		t.Errorf("release: got %q, want %q", got, want)
+	}
+
+	term.WriteString("\x1b[1;2;2;1;5T")
+	got = readInput(t, term, func() { term.SendMouse(MouseRelease{X: 1, Y: 1, Button: MouseLeft}) })
+	if want := "\x1b[t\x22\x22"; got != want {
+		t.Errorf("release at start: got %q, want %q", got, want)
+	}
+
+	// Without highlight tracking the release is reported normally.
+	got = readInput(t, term, func() { term.SendMouse(MouseRelease{X: 1, Y: 1, Button: MouseLeft}) })
+	if want := "\x1b[M\x23\x22\x22"; got != want {
+		t.Errorf("release: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,466 @@
+// Package mux 在一个 [uv.Screen] 上并排显示多个虚拟终端窗格。
+//
+// 窗格按水平和垂直分割组成一棵树，每个分割按比例在两个子节点之间分配空间，
+// 子节点之间绘制一个单元格宽的边框。多路复用器将焦点、按键、鼠标和粘贴路由到
+// 活动窗格，并在调整大小时同时调整模拟器和伪终端的大小。
+package mux
+
+import (
+	"math"
+	"sync"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+// Direction 是分割的方向。
+type Direction int
+
+// 分割方向。
+const (
+	// Horizontal 将区域分割为左右两个窗格，中间是垂直边框。
+	Horizontal Direction = iota
+	// Vertical 将区域分割为上下两个窗格，中间是水平边框。
+	Vertical
+)
+
+// node 是窗格树中的一个节点。叶子节点包含一个窗格，其他节点包含两个子节点。
+type node struct {
+	parent *node
+	pane   *Pane
+
+	dir      Direction
+	ratio    float64
+	children [2]*node
+	area     uv.Rectangle
+}
+
+// Mux 是一个窗格多路复用器。它可以被多个 goroutine 同时使用。
+type Mux struct {
+	mu     sync.Mutex
+	root   *node
+	active *Pane
+	// grab 是接收拖动和释放事件的窗格，即按下鼠标按钮时指针下的窗格。
+	grab   *Pane
+	nextID int
+
+	borderStyle       uv.Style
+	activeBorderStyle uv.Style
+	onUpdate          func()
+}
+
+// New 创建一个给定大小的多路复用器，其中包含一个填满整个区域的窗格。
+func New(width, height int) *Mux {
+	m := &Mux{}
+	area := uv.Rect(0, 0, width, height)
+	p := m.newPane(area)
+	m.root = &node{pane: p, area: area}
+	m.active = p
+	return m
+}
+
+// SetBorderStyle 设置边框的样式，以及与活动窗格相邻的边框的样式。
+func (m *Mux) SetBorderStyle(style, active uv.Style) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.borderStyle, m.activeBorderStyle = style, active
+}
+
+// SetUpdateFunc 设置在任何窗格的模拟器收到输出后调用的函数，可用于安排
+// 重绘。它在窗格的读取 goroutine 中被调用。
+func (m *Mux) SetUpdateFunc(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onUpdate = fn
+}
+
+// Panes 返回所有窗格，按从左到右、从上到下的树顺序排列。
+func (m *Mux) Panes() []*Pane {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var panes []*Pane
+	m.walk(m.root, func(n *node) {
+		if n.pane != nil {
+			panes = append(panes, n.pane)
+		}
+	})
+	return panes
+}
+
+// Active 返回活动窗格。如果没有窗格，则返回 nil。
+func (m *Mux) Active() *Pane {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.active
+}
+
+// Focus 将 p 设为活动窗格。之前的活动窗格会收到失去焦点事件，p 会收到获得
+// 焦点事件。
+func (m *Mux) Focus(p *Pane) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.focus(p)
+}
+
+// Split 分割窗格 p，并在其右侧（Horizontal）或下方（Vertical）创建一个新
+// 窗格。ratio 是 p 保留的空间比例，它被限制在 0 和 1 之间。新窗格成为活动
+// 窗格并被返回。如果 p 不属于该多路复用器，则返回 nil。
+func (m *Mux) Split(p *Pane, dir Direction, ratio float64) *Pane {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := m.find(p)
+	if n == nil {
+		return nil
+	}
+
+	np := m.newPane(uv.Rectangle{})
+	// 当前节点变为分割节点，原窗格移动到第一个子节点。
+	n.children = [2]*node{{parent: n, pane: p}, {parent: n, pane: np}}
+	n.pane, n.dir, n.ratio = nil, dir, clampRatio(ratio)
+	m.layout(n, n.area)
+	m.focus(np)
+	return np
+}
+
+// SetRatio 设置包含窗格 p 的分割的比例，即 p 所在一侧保留的空间比例。
+func (m *Mux) SetRatio(p *Pane, ratio float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := m.find(p)
+	if n == nil || n.parent == nil {
+		return
+	}
+	ratio = clampRatio(ratio)
+	if n.parent.children[1] == n {
+		ratio = 1 - ratio
+	}
+	n.parent.ratio = ratio
+	m.layout(n.parent, n.parent.area)
+}
+
+// Remove 关闭并删除窗格 p。它的兄弟节点会占据它的空间。如果 p 是活动窗格，
+// 兄弟节点中的第一个窗格成为活动窗格。
+func (m *Mux) Remove(p *Pane) error {
+	if !m.remove(p) {
+		return nil
+	}
+	// 在释放锁之后关闭窗格，因为窗格的输出 goroutine 在退出前可能需要获取锁。
+	return p.Close()
+}
+
+// remove 从布局中删除窗格 p。如果 p 不在多路复用器中，则返回 false。
+func (m *Mux) remove(p *Pane) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := m.find(p)
+	if n == nil {
+		return false
+	}
+
+	if parent := n.parent; parent == nil {
+		m.root = nil
+		m.active = nil
+	} else {
+		sibling := parent.children[0]
+		if sibling == n {
+			sibling = parent.children[1]
+		}
+		// 兄弟节点替换父节点。
+		sibling.parent = parent.parent
+		if parent.parent == nil {
+			m.root = sibling
+		} else if parent.parent.children[0] == parent {
+			parent.parent.children[0] = sibling
+		} else {
+			parent.parent.children[1] = sibling
+		}
+		m.layout(sibling, parent.area)
+		if m.active == p {
+			m.active = nil
+			m.focus(m.first(sibling))
+		}
+	}
+	if m.grab == p {
+		m.grab = nil
+	}
+	return true
+}
+
+// Resize 调整多路复用器的大小，并调整每个窗格的模拟器和伪终端的大小。
+func (m *Mux) Resize(width, height int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.root != nil {
+		m.layout(m.root, uv.Rect(0, 0, width, height))
+	}
+}
+
+// PaneAt 返回多路复用器中位置 (x, y) 处的窗格。如果该位置在边框上或在
+// 多路复用器之外，则返回 nil。
+func (m *Mux) PaneAt(x, y int) *Pane {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.paneAt(x, y)
+}
+
+// SendKey 将按键事件发送到活动窗格。
+func (m *Mux) SendKey(k uv.KeyEvent) {
+	if p := m.Active(); p != nil {
+		p.emu.SendKey(k)
+	}
+}
+
+// SendText 将文本发送到活动窗格。
+func (m *Mux) SendText(text string) {
+	if p := m.Active(); p != nil {
+		p.emu.SendText(text)
+	}
+}
+
+// Paste 将文本粘贴到活动窗格。
+func (m *Mux) Paste(text string) {
+	if p := m.Active(); p != nil {
+		p.emu.Paste(text)
+	}
+}
+
+// SendMouse 将鼠标事件发送到指针下的窗格，坐标会转换为窗格内的坐标。点击
+// 另一个窗格会使其成为活动窗格。按下按钮后，拖动和释放事件会发送到按下按钮
+// 时的窗格，即使指针已经离开该窗格。
+func (m *Mux) SendMouse(ev uv.MouseEvent) {
+	m.mu.Lock()
+	mouse := ev.Mouse()
+	p := m.paneAt(mouse.X, mouse.Y)
+	switch ev.(type) {
+	case uv.MouseClickEvent:
+		if p != nil {
+			m.focus(p)
+		}
+		m.grab = p
+	case uv.MouseReleaseEvent:
+		if m.grab != nil {
+			p = m.grab
+		}
+		m.grab = nil
+	case uv.MouseMotionEvent:
+		if m.grab != nil {
+			p = m.grab
+		}
+	}
+	var origin uv.Position
+	if p != nil {
+		origin = p.area.Min
+	}
+	m.mu.Unlock()
+
+	if p == nil {
+		return
+	}
+
+	mouse.X -= origin.X
+	mouse.Y -= origin.Y
+	switch ev.(type) {
+	case uv.MouseClickEvent:
+		ev = uv.MouseClickEvent(mouse)
+	case uv.MouseReleaseEvent:
+		ev = uv.MouseReleaseEvent(mouse)
+	case uv.MouseWheelEvent:
+		ev = uv.MouseWheelEvent(mouse)
+	case uv.MouseMotionEvent:
+		ev = uv.MouseMotionEvent(mouse)
+	default:
+		return
+	}
+	p.emu.SendMouse(ev)
+}
+
+// CursorPosition 返回活动窗格的光标在多路复用器中的位置。如果没有窗格，则
+// 返回 false。
+func (m *Mux) CursorPosition() (uv.Position, bool) {
+	m.mu.Lock()
+	p := m.active
+	m.mu.Unlock()
+	if p == nil {
+		return uv.Position{}, false
+	}
+	return p.emu.CursorPosition().Add(p.Bounds().Min), true
+}
+
+// Draw 将所有窗格和边框绘制到 scr 的给定区域。
+func (m *Mux) Draw(scr uv.Screen, area uv.Rectangle) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.walk(m.root, func(n *node) {
+		if n.pane != nil {
+			n.pane.emu.Draw(scr, n.pane.area.Add(area.Min))
+			return
+		}
+
+		style := m.borderStyle
+		if m.active != nil && m.adjacent(n, m.active) {
+			style = m.activeBorderStyle
+		}
+		border := m.border(n).Add(area.Min)
+		content := "│"
+		if n.dir == Vertical {
+			content = "─"
+		}
+		cell := uv.Cell{Content: content, Width: 1, Style: style}
+		for y := border.Min.Y; y < border.Max.Y; y++ {
+			for x := border.Min.X; x < border.Max.X; x++ {
+				scr.SetCell(x, y, &cell)
+			}
+		}
+	})
+}
+
+// Close 关闭所有窗格。
+func (m *Mux) Close() error {
+	var err error
+	for _, p := range m.Panes() {
+		if perr := p.Close(); err == nil {
+			err = perr
+		}
+	}
+	return err
+}
+
+// newPane 创建一个新的窗格。
+func (m *Mux) newPane(area uv.Rectangle) *Pane {
+	m.nextID++
+	return newPane(m, m.nextID, area)
+}
+
+// update 调用更新函数。
+func (m *Mux) update() {
+	m.mu.Lock()
+	fn := m.onUpdate
+	m.mu.Unlock()
+	if fn != nil {
+		fn()
+	}
+}
+
+// focus 将 p 设为活动窗格。
+func (m *Mux) focus(p *Pane) {
+	if p == m.active {
+		return
+	}
+	if m.active != nil {
+		m.active.emu.Blur()
+	}
+	m.active = p
+	if p != nil {
+		p.emu.Focus()
+	}
+}
+
+// walk 按树顺序对每个节点调用 fn。
+func (m *Mux) walk(n *node, fn func(*node)) {
+	if n == nil {
+		return
+	}
+	fn(n)
+	if n.pane == nil {
+		m.walk(n.children[0], fn)
+		m.walk(n.children[1], fn)
+	}
+}
+
+// find 返回包含窗格 p 的叶子节点。
+func (m *Mux) find(p *Pane) *node {
+	var found *node
+	m.walk(m.root, func(n *node) {
+		if n.pane != nil && n.pane == p {
+			found = n
+		}
+	})
+	return found
+}
+
+// first 返回子树中的第一个窗格。
+func (m *Mux) first(n *node) *Pane {
+	for n.pane == nil {
+		n = n.children[0]
+	}
+	return n.pane
+}
+
+// paneAt 返回位置 (x, y) 处的窗格。
+func (m *Mux) paneAt(x, y int) *Pane {
+	var found *Pane
+	m.walk(m.root, func(n *node) {
+		if n.pane != nil && uv.Pos(x, y).In(n.pane.area) {
+			found = n.pane
+		}
+	})
+	return found
+}
+
+// adjacent 报告分割节点 n 的边框是否与窗格 p 相邻。
+func (m *Mux) adjacent(n *node, p *Pane) bool {
+	border := m.border(n)
+	a := p.area
+	if n.dir == Horizontal {
+		return (a.Max.X == border.Min.X || a.Min.X == border.Max.X) &&
+			a.Min.Y < border.Max.Y && a.Max.Y > border.Min.Y
+	}
+	return (a.Max.Y == border.Min.Y || a.Min.Y == border.Max.Y) &&
+		a.Min.X < border.Max.X && a.Max.X > border.Min.X
+}
+
+// border 返回分割节点 n 的边框区域。
+func (m *Mux) border(n *node) uv.Rectangle {
+	_, _, border := splitArea(n.area, n.dir, n.ratio)
+	return border
+}
+
+// layout 将节点 n 及其子树放置在给定区域中。
+func (m *Mux) layout(n *node, area uv.Rectangle) {
+	n.area = area
+	if n.pane != nil {
+		n.pane.resize(area)
+		return
+	}
+	first, second, _ := splitArea(area, n.dir, n.ratio)
+	m.layout(n.children[0], first)
+	m.layout(n.children[1], second)
+}
+
+// splitArea 按方向和比例分割区域，返回两个子区域和它们之间的边框。
+func splitArea(area uv.Rectangle, dir Direction, ratio float64) (first, second, border uv.Rectangle) {
+	size := area.Dx()
+	if dir == Vertical {
+		size = area.Dy()
+	}
+
+	// 扣除边框后，每一侧至少保留一个单元格。
+	avail := max(0, size-1)
+	n := int(math.Round(float64(avail) * ratio))
+	if avail >= 2 {
+		n = min(max(n, 1), avail-1)
+	}
+
+	first, second, border = area, area, area
+	if dir == Horizontal {
+		first.Max.X = area.Min.X + n
+		border.Min.X, border.Max.X = first.Max.X, min(area.Max.X, first.Max.X+1)
+		second.Min.X = border.Max.X
+	} else {
+		first.Max.Y = area.Min.Y + n
+		border.Min.Y, border.Max.Y = first.Max.Y, min(area.Max.Y, first.Max.Y+1)
+		second.Min.Y = border.Max.Y
+	}
+	return first, second, border
+}
+
+// clampRatio 将比例限制在 0 和 1 之间。无效的比例被视为 0.5。
+func clampRatio(ratio float64) float64 {
+	if math.IsNaN(ratio) {
+		return 0.5
+	}
+	return min(max(ratio, 0), 1)
+}
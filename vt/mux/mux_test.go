@@ -0,0 +1,209 @@
+package mux
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+// fakePty is a Pty backed by a pipe. Output written to w is read by the
+// pane, and input written by the pane is collected.
+type fakePty struct {
+	r *io.PipeReader
+	w *io.PipeWriter
+
+	mu     sync.Mutex
+	input  bytes.Buffer
+	width  int
+	height int
+}
+
+func newFakePty() *fakePty {
+	r, w := io.Pipe()
+	return &fakePty{r: r, w: w}
+}
+
+func (p *fakePty) Read(b []byte) (int, error) { return p.r.Read(b) }
+
+func (p *fakePty) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.input.Write(b)
+}
+
+func (p *fakePty) Close() error { return p.w.Close() }
+
+func (p *fakePty) Resize(width, height int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.width, p.height = width, height
+	return nil
+}
+
+func (p *fakePty) Start(*exec.Cmd) error { return nil }
+
+func (p *fakePty) size() (int, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.width, p.height
+}
+
+// waitInput waits until the pane wrote want to the pty.
+func (p *fakePty) waitInput(t *testing.T, want string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		p.mu.Lock()
+		got := p.input.String()
+		p.mu.Unlock()
+		if got == want {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t.Fatalf("pty input = %q, want %q", p.input.String(), want)
+}
+
+func TestSplitLayout(t *testing.T) {
+	m := New(21, 10)
+	defer m.Close() //nolint:errcheck
+
+	left := m.Active()
+	right := m.Split(left, Horizontal, 0.5)
+	bottom := m.Split(right, Vertical, 0.5)
+
+	if want := uv.Rect(0, 0, 10, 10); left.Bounds() != want {
+		t.Errorf("left = %v, want %v", left.Bounds(), want)
+	}
+	if want := uv.Rect(11, 0, 10, 5); right.Bounds() != want {
+		t.Errorf("right = %v, want %v", right.Bounds(), want)
+	}
+	if want := uv.Rect(11, 6, 10, 4); bottom.Bounds() != want {
+		t.Errorf("bottom = %v, want %v", bottom.Bounds(), want)
+	}
+	if m.Active() != bottom {
+		t.Error("the new pane should be active")
+	}
+	if got := m.Panes(); len(got) != 3 || got[0] != left || got[1] != right || got[2] != bottom {
+		t.Errorf("Panes() = %v", got)
+	}
+	if m.PaneAt(10, 3) != nil || m.PaneAt(12, 7) != bottom {
+		t.Error("PaneAt does not match the layout")
+	}
+
+	m.SetRatio(left, 0.25)
+	if want := uv.Rect(0, 0, 5, 10); left.Bounds() != want {
+		t.Errorf("left after SetRatio = %v, want %v", left.Bounds(), want)
+	}
+	if w := right.Emulator().Width(); w != 15 {
+		t.Errorf("right emulator width = %d, want 15", w)
+	}
+
+	if err := m.Remove(right); err != nil {
+		t.Fatal(err)
+	}
+	if want := uv.Rect(6, 0, 15, 10); bottom.Bounds() != want {
+		t.Errorf("bottom after Remove = %v, want %v", bottom.Bounds(), want)
+	}
+}
+
+func TestDrawAndResize(t *testing.T) {
+	m := New(11, 3)
+	defer m.Close() //nolint:errcheck
+
+	left := m.Active()
+	right := m.Split(left, Horizontal, 0.5)
+	pty := newFakePty()
+	if err := right.Start(pty, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{}, 16)
+	m.SetUpdateFunc(func() { done <- struct{}{} })
+	_, _ = io.WriteString(pty.w, "hi")
+	<-done
+	_, _ = left.Emulator().WriteString("yo")
+
+	scr := uv.NewScreenBuffer(11, 3)
+	m.Draw(scr, scr.Bounds())
+	if got, want := scr.Render(), "yo   │hi\n     │\n     │"; got != want {
+		t.Errorf("Draw() = %q, want %q", got, want)
+	}
+
+	m.Resize(21, 5)
+	if w, h := pty.size(); w != 10 || h != 5 {
+		t.Errorf("pty size = %dx%d, want 10x5", w, h)
+	}
+	if w, h := right.Emulator().Width(), right.Emulator().Height(); w != 10 || h != 5 {
+		t.Errorf("emulator size = %dx%d, want 10x5", w, h)
+	}
+}
+
+func TestRemoveWaitsForOutput(t *testing.T) {
+	m := New(11, 3)
+	defer m.Close() //nolint:errcheck
+
+	p := m.Split(m.Active(), Horizontal, 0.5)
+	pty := newFakePty()
+	if err := p.Start(pty, nil); err != nil {
+		t.Fatal(err)
+	}
+	updated := make(chan struct{}, 1)
+	m.SetUpdateFunc(func() {
+		select {
+		case updated <- struct{}{}:
+		default:
+		}
+	})
+	_, _ = io.WriteString(pty.w, "hi")
+	<-updated
+
+	if err := m.Remove(p); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-p.Done():
+	default:
+		t.Error("Remove returned before the pane stopped reading output")
+	}
+}
+
+func TestRouteInput(t *testing.T) {
+	m := New(21, 10)
+	defer m.Close() //nolint:errcheck
+
+	left := m.Active()
+	right := m.Split(left, Horizontal, 0.5)
+	lpty, rpty := newFakePty(), newFakePty()
+	if err := left.Start(lpty, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := right.Start(rpty, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	m.SendText("a")
+	rpty.waitInput(t, "a")
+
+	// Clicking the left pane focuses it and translates the coordinates.
+	_, _ = left.Emulator().WriteString("\x1b[?1000h\x1b[?1006h")
+	m.SendMouse(uv.MouseClickEvent{X: 3, Y: 2, Button: uv.MouseLeft})
+	if m.Active() != left {
+		t.Fatal("click should focus the left pane")
+	}
+	lpty.waitInput(t, "\x1b[<0;4;3M")
+
+	// Releasing over the other pane goes to the pane that was clicked.
+	m.SendMouse(uv.MouseReleaseEvent{X: 15, Y: 2, Button: uv.MouseLeft})
+	lpty.waitInput(t, "\x1b[<0;4;3M\x1b[<0;16;3m")
+
+	m.Paste("p")
+	lpty.waitInput(t, "\x1b[<0;4;3M\x1b[<0;16;3mp")
+}
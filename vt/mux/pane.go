@@ -0,0 +1,168 @@
+package mux
+
+import (
+	"errors"
+	"io"
+	"os/exec"
+	"sync"
+
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/purpose168/charm-experimental-packages-cn/vt"
+)
+
+// Pty 是窗格使用的伪终端接口。[xpty.Pty] 满足该接口。
+//
+// [xpty.Pty]: https://pkg.go.dev/github.com/purpose168/charm-experimental-packages-cn/xpty#Pty
+type Pty interface {
+	io.ReadWriteCloser
+
+	// Resize 调整伪终端的大小。
+	Resize(width, height int) error
+
+	// Start 在伪终端上启动一个命令。
+	Start(cmd *exec.Cmd) error
+}
+
+// ErrPaneAttached 在窗格已经连接到伪终端时返回。
+var ErrPaneAttached = errors.New("mux: pane is already attached")
+
+// Pane 是一个显示虚拟终端的窗格。
+type Pane struct {
+	id  int
+	emu *vt.SafeEmulator
+	mux *Mux
+
+	// area 是窗格在多路复用器中的区域，由多路复用器的锁保护。
+	area uv.Rectangle
+
+	mu   sync.Mutex
+	pty  Pty
+	cmd  *exec.Cmd
+	done chan struct{}
+	// input 在将模拟器的输入复制到伪终端的 goroutine 退出时关闭。
+	input chan struct{}
+}
+
+// newPane 创建一个给定大小的窗格。
+func newPane(m *Mux, id int, area uv.Rectangle) *Pane {
+	return &Pane{
+		id:   id,
+		emu:  vt.NewSafeEmulator(max(1, area.Dx()), max(1, area.Dy())),
+		mux:  m,
+		area: area,
+		done: make(chan struct{}),
+	}
+}
+
+// ID 返回窗格的唯一标识符。
+func (p *Pane) ID() int {
+	return p.id
+}
+
+// Emulator 返回窗格的模拟器。
+func (p *Pane) Emulator() *vt.SafeEmulator {
+	return p.emu
+}
+
+// Bounds 返回窗格在多路复用器中的区域，不包括边框。
+func (p *Pane) Bounds() uv.Rectangle {
+	p.mux.mu.Lock()
+	defer p.mux.mu.Unlock()
+	return p.area
+}
+
+// Cmd 返回在窗格中运行的命令。如果窗格没有连接到伪终端，则返回 nil。
+func (p *Pane) Cmd() *exec.Cmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cmd
+}
+
+// Done 返回一个在伪终端的输出结束时关闭的通道，通常是因为命令已经退出。
+func (p *Pane) Done() <-chan struct{} {
+	return p.done
+}
+
+// Start 将窗格连接到 pty，调整 pty 的大小以匹配窗格，并在其上启动 cmd。
+// pty 的输出会写入窗格的模拟器，模拟器的输入（按键、鼠标和对查询的回复）
+// 会写入 pty。
+func (p *Pane) Start(pty Pty, cmd *exec.Cmd) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pty != nil {
+		return ErrPaneAttached
+	}
+
+	if err := pty.Resize(p.emu.Width(), p.emu.Height()); err != nil {
+		return err //nolint:wrapcheck
+	}
+	if err := pty.Start(cmd); err != nil {
+		return err //nolint:wrapcheck
+	}
+	p.pty, p.cmd = pty, cmd
+	p.input = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := pty.Read(buf)
+			if n > 0 {
+				_, _ = p.emu.Write(buf[:n])
+				p.mux.update()
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	go func(input chan struct{}) {
+		defer close(input)
+		_, _ = io.Copy(pty, p.emu)
+	}(p.input)
+
+	return nil
+}
+
+// Close 关闭窗格的伪终端和模拟器，并等待读取伪终端输出的 goroutine 和将模拟器
+// 输入复制到伪终端的 goroutine 退出。Close 返回后，窗格不再写入模拟器，也不再
+// 通知多路复用器更新。
+func (p *Pane) Close() error {
+	p.mu.Lock()
+	pty, input := p.pty, p.input
+	p.mu.Unlock()
+
+	var err error
+	if pty != nil {
+		err = pty.Close()
+	}
+	// 先关闭模拟器，以免输出 goroutine 在写入对查询的回复时阻塞。
+	if eerr := p.emu.Close(); err == nil {
+		err = eerr
+	}
+	if pty != nil {
+		<-p.done
+	}
+	if input != nil {
+		<-input
+	}
+	return err //nolint:wrapcheck
+}
+
+// resize 将窗格移动到给定区域，并调整模拟器和伪终端的大小。
+func (p *Pane) resize(area uv.Rectangle) {
+	old := p.area
+	p.area = area
+	if old.Dx() == area.Dx() && old.Dy() == area.Dy() {
+		return
+	}
+
+	w, h := max(1, area.Dx()), max(1, area.Dy())
+	p.emu.Resize(w, h)
+	p.mu.Lock()
+	pty := p.pty
+	p.mu.Unlock()
+	if pty != nil {
+		_ = pty.Resize(w, h)
+	}
+}
@@ -0,0 +1,283 @@
+package vt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Notification 表示应用程序通过 OSC 9、OSC 777 或 OSC 99 请求的桌面通知。
+type Notification struct {
+	// ID 是 OSC 99 通知的标识符，可用于替换或关闭通知。其他通知的 ID 为空。
+	ID string
+
+	// Title 是通知的标题。OSC 9 通知没有标题。
+	Title string
+
+	// Body 是通知的正文。
+	Body string
+
+	// Urgency 是通知的紧急程度：0 表示低，1 表示正常，2 表示紧急。
+	Urgency int
+}
+
+// ProgressState 表示进度条的状态。
+type ProgressState int
+
+// 进度条状态。
+const (
+	// ProgressNone 隐藏进度条。
+	ProgressNone ProgressState = iota
+	// ProgressNormal 显示正常的进度。
+	ProgressNormal
+	// ProgressError 显示出错的进度。
+	ProgressError
+	// ProgressIndeterminate 显示不确定的进度。
+	ProgressIndeterminate
+	// ProgressWarning 显示暂停或警告的进度。
+	ProgressWarning
+)
+
+// Progress 表示应用程序通过 ConEmu 的 OSC 9;4 报告的进度。
+type Progress struct {
+	// State 是进度条的状态。
+	State ProgressState
+
+	// Percent 是进度的百分比，范围为 0 到 100。
+	Percent int
+}
+
+// maxNotificationSize 是分块发送的 OSC 99 通知的最大大小（以字节为单位），
+// 超出的部分会被丢弃。
+const maxNotificationSize = 64 * 1024
+
+// maxPendingNotifications 是同时等待剩余分块的 OSC 99 通知的最大数量。超出
+// 时会丢弃最早的通知。
+const maxPendingNotifications = 16
+
+// Progress 返回应用程序最后报告的进度。
+func (e *Emulator) Progress() Progress {
+	return e.progress
+}
+
+// handleNotify 处理 OSC 9 通知、OSC 9;4 进度和 OSC 9;9 工作目录报告。其他以
+// "N;" 开头的 ConEmu 子命令会被忽略，不会作为通知报告。
+func (e *Emulator) handleNotify(cmd int, data []byte) {
+	_, msg, ok := bytes.Cut(data, []byte{';'})
+	if cmd != 9 || !ok {
+		// Invalid, ignore
+		return
+	}
+
+	switch conEmuCommand(msg) {
+	case 0:
+	case 4:
+		e.handleProgress(msg)
+		return
+	case 9:
+		// ConEmu reports the working directory as 9;9;"path".
+		if _, path, ok := bytes.Cut(msg, []byte{';'}); ok {
+			e.setWorkingDirectory(strings.Trim(string(path), `"`))
+		}
+		return
+	default:
+		// Unsupported ConEmu subcommand, ignore
+		return
+	}
+
+	if e.cb.Notify != nil {
+		e.cb.Notify(Notification{Body: string(msg), Urgency: 1})
+	}
+}
+
+// maxConEmuCommand 是 OSC 9 中最大的 ConEmu 子命令编号。
+const maxConEmuCommand = 12
+
+// conEmuCommand 返回 OSC 9 数据中 ConEmu 子命令的编号，形式为 "N" 或 "N;..."。
+// 如果数据是普通的通知正文，则返回 0。
+func conEmuCommand(msg []byte) int {
+	num, _, _ := bytes.Cut(msg, []byte{';'})
+	n, err := strconv.Atoi(string(num))
+	if err != nil || n < 1 || n > maxConEmuCommand || num[0] == '+' {
+		return 0
+	}
+	return n
+}
+
+// handleProgress 处理 OSC 9;4 进度，data 是 "4;st;pr"。缺少百分比的错误和警告
+// 状态保留之前的百分比。
+func (e *Emulator) handleProgress(data []byte) {
+	parts := strings.Split(string(data), ";")
+	st, pr := 0, -1
+	if len(parts) > 1 && parts[1] != "" {
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n < int(ProgressNone) || n > int(ProgressWarning) {
+			return
+		}
+		st = n
+	}
+	if len(parts) > 2 && parts[2] != "" {
+		n, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return
+		}
+		pr = min(max(0, n), 100)
+	}
+
+	p := Progress{State: ProgressState(st)}
+	switch p.State {
+	case ProgressNormal:
+		p.Percent = max(0, pr)
+	case ProgressError, ProgressWarning:
+		p.Percent = e.progress.Percent
+		if pr >= 0 {
+			p.Percent = pr
+		}
+	}
+
+	e.progress = p
+	if e.cb.Progress != nil {
+		e.cb.Progress(p)
+	}
+}
+
+// handleNotifyRxvt 处理 OSC 777 ; notify ; title ; body 通知。
+func (e *Emulator) handleNotifyRxvt(cmd int, data []byte) {
+	parts := bytes.SplitN(data, []byte{';'}, 4)
+	if cmd != 777 || len(parts) < 3 || string(parts[1]) != "notify" {
+		// Invalid, ignore
+		return
+	}
+
+	n := Notification{Title: string(parts[2]), Urgency: 1}
+	if len(parts) == 4 {
+		n.Body = string(parts[3])
+	}
+	if e.cb.Notify != nil {
+		e.cb.Notify(n)
+	}
+}
+
+// pendingNotification 是正在分块接收的 OSC 99 通知。
+type pendingNotification struct {
+	Notification
+	size int
+}
+
+// handleDesktopNotification 处理 kitty 的 OSC 99 ; metadata ; payload 通知。
+// metadata 中 d=0 的分块会累积到相同 ID 的通知中，直到 d=1 的分块到达才报告
+// 通知。
+//
+// 参见：https://sw.kovidgoyal.net/kitty/desktop-notifications/
+func (e *Emulator) handleDesktopNotification(cmd int, data []byte) {
+	parts := bytes.SplitN(data, []byte{';'}, 3)
+	if cmd != 99 || len(parts) != 3 {
+		// Invalid, ignore
+		return
+	}
+
+	var (
+		id      string
+		done    = true
+		payload = "title"
+		encoded bool
+		urgency = -1
+	)
+	for _, kv := range bytes.Split(parts[1], []byte{':'}) {
+		k, v, _ := bytes.Cut(kv, []byte{'='})
+		switch string(k) {
+		case "i":
+			id = string(v)
+		case "d":
+			done = string(v) != "0"
+		case "p":
+			payload = string(v)
+		case "e":
+			encoded = string(v) == "1"
+		case "u":
+			if n, err := strconv.Atoi(string(v)); err == nil && n >= 0 && n <= 2 {
+				urgency = n
+			}
+		}
+	}
+
+	text := string(parts[2])
+	if encoded {
+		b, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			e.logf("无效的通知数据: %v", err)
+			return
+		}
+		text = string(b)
+	}
+
+	switch payload {
+	case "?":
+		// Report the supported payload types and urgency levels.
+		if e.cb.Notify != nil {
+			_, _ = io.WriteString(e.pw, "\x1b]99;i="+id+":p=?;p=title,body,close,?:u=0,1,2\x1b\\")
+		}
+		return
+	case "close":
+		e.removePendingNotification(id)
+		if e.cb.CloseNotification != nil && id != "" {
+			e.cb.CloseNotification(id)
+		}
+		return
+	case "title", "body":
+	default:
+		// Icons, buttons and other payloads are not supported.
+		return
+	}
+
+	pn := e.pendingNotification(id)
+	if pn == nil {
+		pn = &pendingNotification{Notification: Notification{ID: id, Urgency: 1}}
+		// 完整的通知直接报告，不需要等待。
+		if !done {
+			if len(e.notifications) >= maxPendingNotifications {
+				e.notifications = slices.Delete(e.notifications, 0, 1)
+			}
+			e.notifications = append(e.notifications, pn)
+		}
+	}
+
+	if pn.size+len(text) <= maxNotificationSize {
+		pn.size += len(text)
+		if payload == "title" {
+			pn.Title += text
+		} else {
+			pn.Body += text
+		}
+	}
+	if urgency >= 0 {
+		pn.Urgency = urgency
+	}
+
+	if done {
+		e.removePendingNotification(id)
+		if e.cb.Notify != nil {
+			e.cb.Notify(pn.Notification)
+		}
+	}
+}
+
+// pendingNotification 返回 ID 为 id 的正在接收的通知，如果没有则返回 nil。
+func (e *Emulator) pendingNotification(id string) *pendingNotification {
+	for _, pn := range e.notifications {
+		if pn.ID == id {
+			return pn
+		}
+	}
+	return nil
+}
+
+// removePendingNotification 删除 ID 为 id 的正在接收的通知。
+func (e *Emulator) removePendingNotification(id string) {
+	e.notifications = slices.DeleteFunc(e.notifications, func(pn *pendingNotification) bool {
+		return pn.ID == id
+	})
+}
@@ -0,0 +1,139 @@
+package vt
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/purpose168/charm-experimental-packages-cn/ansi"
+)
+
+func TestNotifications(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	var (
+		got    []Notification
+		closed []string
+	)
+	term.SetCallbacks(Callbacks{
+		Notify:            func(n Notification) { got = append(got, n) },
+		CloseNotification: func(id string) { closed = append(closed, id) },
+	})
+
+	term.WriteString(ansi.Notify("hello"))
+	term.WriteString("\x1b]777;notify;Build;done; no errors\x07")
+	term.WriteString(ansi.DesktopNotification("Simple"))
+
+	// Chunked kitty notification with an ID, a base64 body and an urgency.
+	term.WriteString(ansi.DesktopNotification("Hel", "i=n1", "d=0"))
+	term.WriteString(ansi.DesktopNotification("lo", "i=n1", "d=0"))
+	term.WriteString(ansi.DesktopNotification("Ym9keQ==", "i=n1", "p=body", "e=1", "u=2"))
+	term.WriteString(ansi.DesktopNotification("", "i=n1", "p=close"))
+
+	// Icons are not supported and don't produce a notification.
+	term.WriteString(ansi.DesktopNotification("aWNvbg==", "i=n2", "p=icon", "e=1"))
+
+	want := []Notification{
+		{Body: "hello", Urgency: 1},
+		{Title: "Build", Body: "done; no errors", Urgency: 1},
+		{Title: "Simple", Urgency: 1},
+		{ID: "n1", Title: "Hello", Body: "body", Urgency: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("notifications = %+v, want %+v", got, want)
+	}
+	if want := []string{"n1"}; !reflect.DeepEqual(closed, want) {
+		t.Errorf("closed = %q, want %q", closed, want)
+	}
+}
+
+func TestNotificationsPendingLimit(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	var got []Notification
+	term.SetCallbacks(Callbacks{Notify: func(n Notification) { got = append(got, n) }})
+
+	for i := range maxPendingNotifications + 1 {
+		term.WriteString(ansi.DesktopNotification("a", "i=p"+strconv.Itoa(i), "d=0"))
+	}
+	// Complete notifications are delivered even when many are pending.
+	term.WriteString(ansi.DesktopNotification("new", "i=z"))
+	// The oldest pending notification was evicted, the others are kept.
+	term.WriteString(ansi.DesktopNotification("b", "i=p0"))
+	term.WriteString(ansi.DesktopNotification("b", "i=p1"))
+
+	want := []Notification{
+		{ID: "z", Title: "new", Urgency: 1},
+		{ID: "p0", Title: "b", Urgency: 1},
+		{ID: "p1", Title: "ab", Urgency: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("notifications = %+v, want %+v", got, want)
+	}
+}
+
+func TestNotifyConEmuCommands(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	var (
+		got []Notification
+		cwd []string
+	)
+	term.SetCallbacks(Callbacks{
+		Notify:           func(n Notification) { got = append(got, n) },
+		WorkingDirectory: func(p string) { cwd = append(cwd, p) },
+	})
+
+	term.WriteString("\x1b]9;9;\"C:\\Users\"\x07")
+	term.WriteString("\x1b]9;1;500\x07")     // sleep
+	term.WriteString("\x1b]9;12\x07")        // prompt start
+	term.WriteString("\x1b]9;42;answer\x07") // not a ConEmu subcommand
+	term.WriteString("\x1b]9;3 files done\x07")
+
+	want := []Notification{
+		{Body: "42;answer", Urgency: 1},
+		{Body: "3 files done", Urgency: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("notifications = %#v, want %#v", got, want)
+	}
+	if want := []string{`C:\Users`}; !reflect.DeepEqual(cwd, want) {
+		t.Errorf("working directory = %q, want %q", cwd, want)
+	}
+}
+
+func TestNotificationQuery(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	term.SetCallbacks(Callbacks{Notify: func(Notification) {}})
+
+	got := readInput(t, term, func() { term.WriteString(ansi.DesktopNotification("", "i=q", "p=?")) })
+	if want := "\x1b]99;i=q:p=?;p=title,body,close,?:u=0,1,2\x1b\\"; got != want {
+		t.Errorf("query reply = %q, want %q", got, want)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	var got []Progress
+	term.SetCallbacks(Callbacks{Progress: func(p Progress) { got = append(got, p) }})
+
+	term.WriteString(ansi.SetProgressBar(40))
+	term.WriteString("\x1b]9;4;2\x07")
+	term.WriteString(ansi.SetWarningProgressBar(150))
+	term.WriteString(ansi.SetIndeterminateProgressBar)
+	term.WriteString(ansi.ResetProgressBar)
+	term.WriteString("\x1b]9;4;9;10\x07") // invalid state
+
+	want := []Progress{
+		{ProgressNormal, 40},
+		{ProgressError, 40},
+		{ProgressWarning, 100},
+		{ProgressIndeterminate, 0},
+		{ProgressNone, 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("progress = %+v, want %+v", got, want)
+	}
+
+	term.WriteString(ansi.SetProgressBar(7))
+	if p := term.Progress(); p != (Progress{ProgressNormal, 7}) {
+		t.Errorf("Progress() = %+v", p)
+	}
+}
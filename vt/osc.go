@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"image/color"
 	"io"
+	"strconv"
 
 	"github.com/purpose168/charm-experimental-packages-cn/ansi"
 )
@@ -116,7 +117,11 @@ func (e *Emulator) handleWorkingDirectory(cmd int, data []byte) {
 		return
 	}
 
-	path := string(parts[1])
+	e.setWorkingDirectory(string(parts[1]))
+}
+
+// setWorkingDirectory 记录当前工作目录并通知 [Callbacks.WorkingDirectory]。
+func (e *Emulator) setWorkingDirectory(path string) {
 	e.cwd = path
 
 	if e.cb.WorkingDirectory != nil {
@@ -125,12 +130,113 @@ func (e *Emulator) handleWorkingDirectory(cmd int, data []byte) {
 }
 
 func (e *Emulator) handleHyperlink(cmd int, data []byte) {
-	parts := bytes.Split(data, []byte{';'})
+	// The URL itself may contain semicolons.
+	parts := bytes.SplitN(data, []byte{';'}, 3)
 	if len(parts) != 3 || cmd != 8 {
 		// Invalid, ignore
 		return
 	}
 
-	e.scr.cur.Link.URL = string(parts[1])
-	e.scr.cur.Link.Params = string(parts[2])
+	e.scr.cur.Link.Params = string(parts[1])
+	e.scr.cur.Link.URL = string(parts[2])
+}
+
+// specialColorBase 是第一个特殊颜色的 OSC 4 索引。256 及以上的索引指向 OSC 5
+// 特殊颜色。
+const specialColorBase = 256
+
+func (e *Emulator) handlePaletteColor(cmd int, data []byte) {
+	if cmd != 4 && cmd != 5 && cmd != 104 && cmd != 105 {
+		// Invalid, ignore
+		return
+	}
+
+	parts := bytes.Split(data, []byte{';'})
+	if len(parts) == 0 {
+		// Invalid, ignore
+		return
+	}
+
+	switch cmd {
+	case 104, 105: // Reset colors
+		if len(parts) == 1 || (len(parts) == 2 && len(parts[1]) == 0) {
+			if cmd == 104 {
+				for i := range e.colors {
+					e.setPaletteColor(i, nil)
+				}
+			} else {
+				for i := range e.specialColors {
+					e.setPaletteColor(specialColorBase+i, nil)
+				}
+			}
+			return
+		}
+		for _, p := range parts[1:] {
+			if i, ok := e.paletteIndex(cmd, p); ok {
+				e.setPaletteColor(i, nil)
+			}
+		}
+	case 4, 5: // Set/Query colors
+		// The sequence may contain several index;spec pairs.
+		for j := 1; j+1 < len(parts); j += 2 {
+			i, ok := e.paletteIndex(cmd, parts[j])
+			if !ok {
+				continue
+			}
+
+			arg := string(parts[j+1])
+			if arg == "?" {
+				c := e.paletteColor(i)
+				if c == nil {
+					continue
+				}
+				xrgb := ansi.XRGBColor{Color: c}
+				io.WriteString(e.pw, "\x1b]"+strconv.Itoa(cmd)+";"+string(parts[j])+";"+xrgb.String()+"\x07") //nolint:errcheck,gosec
+			} else if c := ansi.XParseColor(arg); c != nil {
+				e.setPaletteColor(i, c)
+			}
+		}
+	}
+}
+
+// paletteIndex 将 OSC 4/5/104/105 序列中的颜色编号解析为 OSC 4 索引。OSC 5 和
+// OSC 105 直接寻址特殊颜色。负数或超出范围的编号返回 false。
+func (e *Emulator) paletteIndex(cmd int, p []byte) (int, bool) {
+	i, err := strconv.Atoi(string(p))
+	if err != nil || i < 0 {
+		return 0, false
+	}
+	if cmd == 5 || cmd == 105 {
+		if i >= len(e.specialColors) {
+			return 0, false
+		}
+		i += specialColorBase
+	}
+	return i, true
+}
+
+// paletteColor 返回给定 OSC 4 索引处的调色板颜色，包括特殊颜色。未设置的特殊
+// 颜色回退到前景色。
+func (e *Emulator) paletteColor(i int) color.Color {
+	if i >= specialColorBase {
+		i -= specialColorBase
+		if i >= len(e.specialColors) {
+			return nil
+		}
+		if c := e.specialColors[i]; c != nil {
+			return c
+		}
+		return e.ForegroundColor()
+	}
+	return e.IndexedColor(i)
+}
+
+// setPaletteColor 设置给定 OSC 4 索引处的调色板颜色，包括特殊颜色。nil 颜色会
+// 将该项重置为默认值。
+func (e *Emulator) setPaletteColor(i int, c color.Color) {
+	if i >= specialColorBase {
+		e.SetSpecialColor(i-specialColorBase, c)
+		return
+	}
+	e.SetIndexedColor(i, c)
 }
@@ -0,0 +1,99 @@
+package vt
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestPaletteColor(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+
+	term.WriteString("\x1b]4;1;rgb:ff/00/00;2;#00ff00\x07")
+	if got := term.IndexedColor(1); !sameColor(got, color.RGBA{0xff, 0, 0, 0xff}) {
+		t.Errorf("color 1 = %v", got)
+	}
+	if got := term.IndexedColor(2); !sameColor(got, color.RGBA{0, 0xff, 0, 0xff}) {
+		t.Errorf("color 2 = %v", got)
+	}
+
+	got := readInput(t, term, func() { term.WriteString("\x1b]4;1;?;2;?\x07") })
+	want := "\x1b]4;1;rgb:ffff/0000/0000\x07\x1b]4;2;rgb:0000/ffff/0000\x07"
+	if got != want {
+		t.Errorf("query = %q, want %q", got, want)
+	}
+
+	term.WriteString("\x1b]104;1\x07")
+	if got := term.IndexedColor(1); sameColor(got, color.RGBA{0xff, 0, 0, 0xff}) {
+		t.Errorf("color 1 was not reset")
+	}
+	if got := term.IndexedColor(2); !sameColor(got, color.RGBA{0, 0xff, 0, 0xff}) {
+		t.Errorf("color 2 was reset")
+	}
+	term.WriteString("\x1b]104\x07")
+	if got := term.IndexedColor(2); sameColor(got, color.RGBA{0, 0xff, 0, 0xff}) {
+		t.Errorf("color 2 was not reset")
+	}
+
+	// Special colors, addressed with OSC 5 or with OSC 4 indexes above 255.
+	term.WriteString("\x1b]5;0;rgb:12/34/56\x07\x1b]4;257;rgb:ab/cd/ef\x07")
+	if got := term.SpecialColor(SpecialColorBold); !sameColor(got, color.RGBA{0x12, 0x34, 0x56, 0xff}) {
+		t.Errorf("bold color = %v", got)
+	}
+	got = readInput(t, term, func() { term.WriteString("\x1b]5;1;?\x07") })
+	if want := "\x1b]5;1;rgb:abab/cdcd/efef\x07"; got != want {
+		t.Errorf("special query = %q, want %q", got, want)
+	}
+	term.WriteString("\x1b]105\x07")
+	if got := term.SpecialColor(SpecialColorUnderline); got != nil {
+		t.Errorf("underline color was not reset: %v", got)
+	}
+}
+
+func TestPaletteColorInvalidIndex(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	red := color.RGBA{0xff, 0, 0, 0xff}
+
+	// Negative and out-of-range indexes must not wrap onto the 256-color
+	// palette or the special colors.
+	term.WriteString("\x1b]5;-1;#ff0000\x07\x1b]5;5;#ff0000\x07\x1b]4;-1;#ff0000\x07")
+	if got := term.IndexedColor(255); sameColor(got, red) {
+		t.Errorf("color 255 was overwritten")
+	}
+	for i := range 5 {
+		if got := term.SpecialColor(i); got != nil {
+			t.Errorf("special color %d = %v, want nil", i, got)
+		}
+	}
+
+	term.SetIndexedColor(255, red)
+	got := readInput(t, term, func() { term.WriteString("\x1b]5;-1;?\x07\x1b]4;-1;?\x07") })
+	if got != "" {
+		t.Errorf("query = %q, want no reply", got)
+	}
+	term.WriteString("\x1b]105;-1\x07\x1b]104;-1\x07")
+	if got := term.IndexedColor(255); !sameColor(got, red) {
+		t.Errorf("color 255 was reset: %v", got)
+	}
+}
+
+func sameColor(a, b color.Color) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
+
+func TestHyperlink(t *testing.T) {
+	term := newTestTerminal(t, 10, 1)
+	// The parameters come before the URL, and the URL may contain semicolons.
+	term.WriteString("\x1b]8;id=x;http://a/?q=1;b=2\x07a\x1b]8;;\x07b")
+
+	if got := term.CellAt(0, 0).Link; got.Params != "id=x" || got.URL != "http://a/?q=1;b=2" {
+		t.Errorf("link = %+v", got)
+	}
+	if got := term.CellAt(1, 0).Link; got.URL != "" || got.Params != "" {
+		t.Errorf("link after reset = %+v", got)
+	}
+}
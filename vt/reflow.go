@@ -0,0 +1,255 @@
+package vt
+
+import (
+	"slices"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+// reflowRow 是重新换行时使用的一个物理行及其软换行标志。
+type reflowRow struct {
+	line    uv.Line
+	wrapped bool
+}
+
+// reflowLine 是按新宽度重新排列后的一个逻辑行。
+type reflowLine struct {
+	rows  []reflowRow
+	cells []uv.Cell
+	// cols 是每个单元格在原逻辑行中的起始列。
+	cols []int
+	// pos 是每个单元格在重新排列后的位置，Y 相对于逻辑行的第一行。
+	pos []uv.Position
+}
+
+// layoutLine 将逻辑行的单元格按给定宽度排列为物理行。放不下的宽字符会
+// 移动到下一行，与自动换行的行为一致。
+func layoutLine(cells []uv.Cell, cols []int, width int) *reflowLine {
+	l := &reflowLine{
+		rows:  []reflowRow{{line: uv.NewLine(width)}},
+		cells: cells,
+		cols:  cols,
+		pos:   make([]uv.Position, len(cells)),
+	}
+
+	x := 0
+	for i := range cells {
+		w := max(1, cells[i].Width)
+		if x > 0 && x+w > width {
+			l.rows[len(l.rows)-1].wrapped = true
+			l.rows = append(l.rows, reflowRow{line: uv.NewLine(width)})
+			x = 0
+		}
+		l.rows[len(l.rows)-1].line.Set(x, &cells[i])
+		l.pos[i] = uv.Pos(x, len(l.rows)-1)
+		x += w
+	}
+
+	return l
+}
+
+// locate 返回原逻辑行中第 col 列在重新排列后的位置。如果该列正好位于某一行
+// 的末尾之后，返回该行的最后一列，并且 atEnd 为 true，这对应于光标的幻影
+// （待换行）状态。
+func (l *reflowLine) locate(col, width int) (x, y int, atEnd bool) {
+	for i := len(l.cells) - 1; i >= 0; i-- {
+		if l.cols[i] > col {
+			continue
+		}
+		if w := max(1, l.cells[i].Width); col < l.cols[i]+w {
+			return l.pos[i].X, l.pos[i].Y, false
+		}
+		break
+	}
+
+	// 该列位于内容之后。
+	var end, row, extra int
+	if n := len(l.cells); n > 0 {
+		w := max(1, l.cells[n-1].Width)
+		end, row = l.pos[n-1].X+w, l.pos[n-1].Y
+		extra = col - (l.cols[n-1] + w)
+	} else {
+		extra = col
+	}
+
+	linear := end + extra
+	if linear > 0 && linear%width == 0 {
+		return width - 1, row + linear/width - 1, true
+	}
+	return linear % width, row + linear/width, false
+}
+
+// isBlankLine 报告行是否只包含空白单元格。
+func isBlankLine(line uv.Line) bool {
+	for i := range line {
+		if !line[i].IsZero() && !line[i].Equal(&uv.EmptyCell) {
+			return false
+		}
+	}
+	return true
+}
+
+// reflow 将主屏幕调整为给定大小，并按新的宽度重新换行软换行的逻辑行。
+// 回滚缓冲区中的行也参与重新换行，因此行可以在屏幕和回滚缓冲区之间移动。
+// 光标保持在同一个逻辑字符上。
+func (e *Emulator) reflow(width, height int) {
+	s := &e.scrs[0]
+	oldWidth, oldHeight := s.Width(), s.Height()
+	if width <= 0 || height <= 0 || oldWidth <= 0 {
+		s.Resize(width, height)
+		return
+	}
+
+	active := e.scr == s
+	phantom := active && e.atPhantom
+
+	// 收集回滚缓冲区和屏幕中已使用的所有物理行。
+	sb := e.scrollback
+	rows := make([]reflowRow, 0, sb.Len()+oldHeight)
+	for i := range sb.Len() {
+		rows = append(rows, reflowRow{line: sb.Line(i), wrapped: sb.IsWrapped(i)})
+	}
+
+	top := len(rows)
+	cur := s.cur.Position
+	used := cur.Y + 1
+	for y := oldHeight - 1; y >= used; y-- {
+		if !isBlankLine(s.buf.Line(y)) {
+			used = y + 1
+			break
+		}
+	}
+	for y := range used {
+		rows = append(rows, reflowRow{line: s.buf.Line(y), wrapped: s.IsWrapped(y)})
+	}
+
+	curRow, curCol := top+cur.Y, cur.X
+	if phantom {
+		curCol++
+	}
+
+	// 命令标记使用绝对行号，rows 和 out 的第 0 行的绝对行号都是 base。它们
+	// 像光标一样随所在的字符移动。
+	base := e.lineOffset - top
+	marks := e.commandMarks()
+	points := make([]uv.Position, len(marks))
+	for k, p := range marks {
+		points[k] = uv.Pos(p.X, p.Y-base)
+	}
+	// 图像放置随左上角的单元格移动。
+	for _, img := range s.images {
+		points = append(points, uv.Pos(img.Bounds.Min.X, top+img.Bounds.Min.Y))
+	}
+	moved := slices.Clone(points)
+
+	// 将物理行拼接为逻辑行，并按新的宽度重新排列。
+	var out []reflowRow
+	var newTop, newX, newY int
+	var newPhantom bool
+	for i := 0; i < len(rows); {
+		j := i
+		for j < len(rows)-1 && rows[j].wrapped {
+			j++
+		}
+
+		var cells []uv.Cell
+		var cols []int
+		for k := i; k <= j; k++ {
+			for x := range rows[k].line {
+				if rows[k].line[x].IsZero() {
+					// 宽字符的占位单元格。
+					continue
+				}
+				cells = append(cells, rows[k].line[x])
+				cols = append(cols, (k-i)*oldWidth+x)
+			}
+		}
+		for len(cells) > 0 && cells[len(cells)-1].Equal(&uv.EmptyCell) {
+			cells = cells[:len(cells)-1]
+			cols = cols[:len(cols)-1]
+		}
+
+		l := layoutLine(cells, cols, width)
+		if top >= i && top <= j {
+			_, y, atEnd := l.locate((top-i)*oldWidth, width)
+			if atEnd {
+				y++
+			}
+			newTop = len(out) + y
+		}
+		if curRow >= i && curRow <= j {
+			x, y, atEnd := l.locate((curRow-i)*oldWidth+curCol, width)
+			newX, newY, newPhantom = x, len(out)+y, atEnd
+		}
+		for k, p := range points {
+			if p.Y >= i && p.Y <= j {
+				x, y, _ := l.locate((p.Y-i)*oldWidth+p.X, width)
+				moved[k] = uv.Pos(x, len(out)+y)
+			}
+		}
+		out = append(out, l.rows...)
+		i = j + 1
+	}
+
+	// 已使用的行之下的位置保持与内容末尾的距离。已经不在回滚缓冲区中的位置
+	// 不变。
+	for k, p := range points {
+		if p.Y >= len(rows) {
+			moved[k] = uv.Pos(min(p.X, width-1), len(out)+p.Y-len(rows))
+		}
+	}
+	for k, m := range marks {
+		if points[k].Y >= 0 {
+			*m = uv.Pos(moved[k].X, base+moved[k].Y)
+		}
+	}
+
+	// 增加高度时从回滚缓冲区拉回行，然后确保光标可见。
+	newTop -= min(newTop, max(0, height-oldHeight))
+	if newY >= newTop+height {
+		newTop = newY - height + 1
+	}
+	newTop = min(newTop, newY)
+
+	// 保持回滚缓冲区中最旧一行的绝对行号不变。
+	e.lineOffset += newTop - top
+
+	sb.Clear()
+	for i := 0; i < newTop && i < len(out); i++ {
+		sb.push(out[i].line, out[i].wrapped)
+	}
+
+	// 完全移入回滚缓冲区的图像放置会被删除，与滚动时相同。
+	for k, img := range s.images {
+		p := moved[len(marks)+k]
+		s.images[k].Bounds = img.Bounds.Add(uv.Pos(p.X, p.Y-newTop).Sub(img.Bounds.Min))
+	}
+	s.images = deleteImages(s.images, func(p ImagePlacement) bool {
+		return p.Bounds.Max.Y <= 0
+	})
+
+	s.Resize(width, height)
+	for y := range height {
+		line := s.buf.Line(y)
+		if newTop+y < len(out) {
+			copy(line, out[newTop+y].line)
+			s.setWrapped(y, out[newTop+y].wrapped)
+		} else {
+			copy(line, uv.NewLine(width))
+			s.setWrapped(y, false)
+		}
+		// 重新换行后的行总是单宽的。
+		s.setLineAttr(y, LineSingleWidth)
+	}
+
+	newY -= newTop
+	if active {
+		s.setCursor(newX, newY, false)
+		e.atPhantom = newPhantom
+	} else {
+		s.cur.X, s.cur.Y = newX, newY
+	}
+	s.saved.X = min(s.saved.X, width-1)
+	s.saved.Y = min(s.saved.Y, height-1)
+	e.setScrollOffset(e.scrollOffset)
+}
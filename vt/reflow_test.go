@@ -0,0 +1,170 @@
+package vt
+
+import (
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/purpose168/charm-experimental-packages-cn/ansi"
+)
+
+func TestReflow(t *testing.T) {
+	cases := []struct {
+		name   string
+		w, h   int
+		input  string
+		nw, nh int
+		want   []string
+		pos    uv.Position
+		sb     []string
+	}{
+		{
+			name: "narrower",
+			w:    10, h: 3,
+			input: "abcdefgh\r\nxy",
+			nw:    4, nh: 3,
+			want: []string{"abcd", "efgh", "xy  "},
+			pos:  uv.Pos(2, 2),
+		},
+		{
+			name: "narrower pushes lines into scrollback",
+			w:    10, h: 2,
+			input: "abcdefgh\r\nxy",
+			nw:    4, nh: 2,
+			want: []string{"efgh", "xy  "},
+			pos:  uv.Pos(2, 1),
+			sb:   []string{"abcd"},
+		},
+		{
+			name: "wider joins soft wrapped lines",
+			w:    4, h: 3,
+			input: "abcdefgh",
+			nw:    10, nh: 3,
+			want: []string{"abcdefgh  ", "          ", "          "},
+			pos:  uv.Pos(8, 0),
+		},
+		{
+			name: "hard newlines are kept",
+			w:    4, h: 3,
+			input: "ab\r\ncd",
+			nw:    10, nh: 3,
+			want: []string{"ab        ", "cd        ", "          "},
+			pos:  uv.Pos(2, 1),
+		},
+		{
+			name: "wider pulls lines back from scrollback",
+			w:    4, h: 2,
+			input: "abcdefghij",
+			nw:    8, nh: 2,
+			want: []string{"abcdefgh", "ij      "},
+			pos:  uv.Pos(2, 1),
+		},
+		{
+			name: "wide characters move to the next line",
+			w:    6, h: 2,
+			input: "ab你好",
+			nw:    4, nh: 2,
+			want: []string{"ab你", "好  "},
+			pos:  uv.Pos(0, 1),
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			term := newTestTerminal(t, tt.w, tt.h)
+			term.WriteString(tt.input)
+			term.Resize(tt.nw, tt.nh)
+
+			got := termText(term)
+			if len(got) != len(tt.want) {
+				t.Fatalf("output length doesn't match: want %d, got %d", len(tt.want), len(got))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("line %d doesn't match:\nwant: %q\ngot:  %q", i+1, tt.want[i], got[i])
+				}
+			}
+			if pos := term.CursorPosition(); pos != tt.pos {
+				t.Errorf("cursor position doesn't match: want %v, got %v", tt.pos, pos)
+			}
+			sb := term.Scrollback()
+			if sb.Len() != len(tt.sb) {
+				t.Fatalf("scrollback length doesn't match: want %d, got %d", len(tt.sb), sb.Len())
+			}
+			for i := range tt.sb {
+				if got := sb.Line(i).String(); got != tt.sb[i] {
+					t.Errorf("scrollback line %d doesn't match: want %q, got %q", i, tt.sb[i], got)
+				}
+			}
+		})
+	}
+}
+
+func TestInsertDeleteLineWrapped(t *testing.T) {
+	term := newTestTerminal(t, 10, 4)
+	term.WriteString("0123456789abc")
+
+	// Full-width lines move together with their soft-wrap flags.
+	term.WriteString("\x1b[1;1H\x1b[L")
+	if term.IsWrapped(0) || !term.IsWrapped(1) {
+		t.Errorf("after IL: wrapped = %v, %v, want false, true", term.IsWrapped(0), term.IsWrapped(1))
+	}
+	term.WriteString("\x1b[M")
+	if !term.IsWrapped(0) || term.IsWrapped(1) {
+		t.Errorf("after DL: wrapped = %v, %v, want true, false", term.IsWrapped(0), term.IsWrapped(1))
+	}
+
+	// With left and right margins only part of each row moves, so the rows
+	// in the scroll region are no longer soft-wrapped.
+	term.WriteString("\x1b[?69h\x1b[3;6s\x1b[1;4H\x1b[L")
+	for y := range 4 {
+		if term.IsWrapped(y) {
+			t.Errorf("line %d is wrapped after IL inside margins", y)
+		}
+	}
+}
+
+func TestReflowPhantom(t *testing.T) {
+	term := newTestTerminal(t, 4, 2)
+	term.WriteString("abcd") // cursor is pending wrap
+	term.Resize(8, 2)
+	term.WriteString("e")
+	if got := termText(term)[0]; got != "abcde   " {
+		t.Errorf("want %q, got %q", "abcde   ", got)
+	}
+	term.Resize(2, 3)
+	term.WriteString("f")
+	want := []string{"ab", "cd", "ef"}
+	for i, got := range termText(term) {
+		if got != want[i] {
+			t.Errorf("line %d: want %q, got %q", i, want[i], got)
+		}
+	}
+}
+
+func TestReflowAltScreen(t *testing.T) {
+	term := newTestTerminal(t, 4, 2)
+	term.WriteString("\x1b[?1049habcdef")
+	term.Resize(8, 2)
+	if got := termText(term); got[0] != "abcd    " || got[1] != "ef      " {
+		t.Errorf("expected the alt screen not to reflow, got %q", got)
+	}
+	if term.Scrollback().Len() != 0 {
+		t.Errorf("expected no scrollback")
+	}
+}
+
+func TestReflowCommandMarks(t *testing.T) {
+	term := newTestTerminal(t, 10, 5)
+	term.WriteString(ansi.FinalTermPrompt() + "$ " + ansi.FinalTermCmdStart() + "ls\r\n")
+	term.WriteString(ansi.FinalTermCmdExecuted() + "0123456789abc" + ansi.FinalTermCmdFinished("0"))
+
+	// The output wraps onto one more line, and the marks move with it.
+	term.Resize(5, 5)
+	cmd := term.Commands()[0]
+	if cmd.Prompt != uv.Pos(0, 0) || cmd.Input != uv.Pos(2, 0) || cmd.Output != uv.Pos(0, 1) || cmd.End != uv.Pos(3, 3) {
+		t.Errorf("command after reflow = %+v", cmd)
+	}
+	if got, want := term.CommandOutput(0), "0123456789abc"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
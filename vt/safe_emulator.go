@@ -2,7 +2,9 @@ package vt
 
 import (
 	"image/color"
+	"regexp"
 	"sync"
+	"time"
 
 	uv "github.com/charmbracelet/ultraviolet"
 )
@@ -11,6 +13,9 @@ import (
 type SafeEmulator struct {
 	*Emulator
 	mu sync.RWMutex
+
+	// syncTimer 在同步输出帧超时时结束该帧，如果没有打开的帧则为 nil。
+	syncTimer *time.Timer
 }
 
 var _ Terminal = (*SafeEmulator)(nil)
@@ -26,7 +31,30 @@ func NewSafeEmulator(w, h int) *SafeEmulator {
 func (se *SafeEmulator) Write(data []byte) (int, error) {
 	se.mu.Lock()
 	defer se.mu.Unlock()
-	return se.Emulator.Write(data)
+	n, err := se.Emulator.Write(data)
+	se.armSyncTimer()
+	return n, err
+}
+
+// WriteString 以并发安全的方式向模拟器写入字符串。
+func (se *SafeEmulator) WriteString(s string) (int, error) {
+	return se.Write([]byte(s))
+}
+
+// armSyncTimer 在同步输出帧打开时启动计时器，在帧超时的时刻结束帧，使保留
+// 的损坏不必等到下一次写入才传递。调用者必须持有 se.mu。
+func (se *SafeEmulator) armSyncTimer() {
+	if se.syncTimer != nil || !se.Emulator.SyncFrameOpen() {
+		return
+	}
+	se.syncTimer = time.AfterFunc(time.Until(se.Emulator.syncDeadline()), func() {
+		se.mu.Lock()
+		defer se.mu.Unlock()
+		se.syncTimer = nil
+		se.Emulator.expireSyncFrame()
+		// 在计时器触发前可能已经打开了新的帧。
+		se.armSyncTimer()
+	})
 }
 
 // Read 以并发安全的方式从模拟器读取数据。
@@ -180,3 +208,311 @@ func (se *SafeEmulator) Draw(s uv.Screen, a uv.Rectangle) {
 	defer se.mu.RUnlock()
 	se.Emulator.Draw(s, a)
 }
+
+// ScrollOffset 以并发安全的方式返回视口向回滚缓冲区滚动的行数。
+func (se *SafeEmulator) ScrollOffset() int {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.ScrollOffset()
+}
+
+// SetScrollOffset 以并发安全的方式设置视口向回滚缓冲区滚动的行数。
+func (se *SafeEmulator) SetScrollOffset(n int) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.SetScrollOffset(n)
+}
+
+// ScrollViewport 以并发安全的方式按给定的增量滚动视口。
+func (se *SafeEmulator) ScrollViewport(delta int) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.ScrollViewport(delta)
+}
+
+// SetScrollbackSize 以并发安全的方式设置回滚缓冲区的最大行数。
+func (se *SafeEmulator) SetScrollbackSize(n int) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.SetScrollbackSize(n)
+}
+
+// ClearScrollback 以并发安全的方式清除回滚缓冲区。
+func (se *SafeEmulator) ClearScrollback() {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.ClearScrollback()
+}
+
+// KittyKeyboardFlags 以并发安全的方式返回当前生效的 Kitty 键盘协议标志。
+func (se *SafeEmulator) KittyKeyboardFlags() int {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.KittyKeyboardFlags()
+}
+
+// ModifyOtherKeys 以并发安全的方式返回当前的 XTerm modifyOtherKeys 级别。
+func (se *SafeEmulator) ModifyOtherKeys() int {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.ModifyOtherKeys()
+}
+
+// SetTermcap 以并发安全的方式设置用于回复 XTGETTCAP 请求的功能表。
+func (se *SafeEmulator) SetTermcap(caps map[string]string) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.SetTermcap(caps)
+}
+
+// ImagePlacements 以并发安全的方式返回当前屏幕上可见的图像放置。
+func (se *SafeEmulator) ImagePlacements() []ImagePlacement {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.ImagePlacements()
+}
+
+// SetCellSize 以并发安全的方式设置单元格的像素大小。
+func (se *SafeEmulator) SetCellSize(width, height int) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.SetCellSize(width, height)
+}
+
+// SetClipboardPolicy 以并发安全的方式设置剪贴板策略。
+func (se *SafeEmulator) SetClipboardPolicy(p ClipboardPolicy) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.SetClipboardPolicy(p)
+}
+
+// Commands 以并发安全的方式返回通过 shell 集成标记的命令。
+func (se *SafeEmulator) Commands() []Command {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.Commands()
+}
+
+// CommandOutput 以并发安全的方式返回第 i 个命令的输出文本。
+func (se *SafeEmulator) CommandOutput(i int) string {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.CommandOutput(i)
+}
+
+// ScrollToCommand 以并发安全的方式滚动视口到第 i 个命令的提示符。
+func (se *SafeEmulator) ScrollToCommand(i int) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.ScrollToCommand(i)
+}
+
+// SpecialColor 以并发安全的方式返回特殊颜色。
+func (se *SafeEmulator) SpecialColor(i int) color.Color {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.SpecialColor(i)
+}
+
+// SetSpecialColor 以并发安全的方式设置特殊颜色。
+func (se *SafeEmulator) SetSpecialColor(i int, c color.Color) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.SetSpecialColor(i, c)
+}
+
+// SyncFrameOpen 以并发安全的方式报告同步输出帧是否仍然打开。
+func (se *SafeEmulator) SyncFrameOpen() bool {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.SyncFrameOpen()
+}
+
+// SetSyncTimeout 以并发安全的方式设置同步输出帧的超时时间。
+func (se *SafeEmulator) SetSyncTimeout(d time.Duration) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.SetSyncTimeout(d)
+}
+
+// StartSelection 以并发安全的方式开始一个新的选择。
+func (se *SafeEmulator) StartSelection(x, y int, mode SelectionMode) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.StartSelection(x, y, mode)
+}
+
+// ExtendSelection 以并发安全的方式扩展当前选择。
+func (se *SafeEmulator) ExtendSelection(x, y int) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.ExtendSelection(x, y)
+}
+
+// ClearSelection 以并发安全的方式清除当前选择。
+func (se *SafeEmulator) ClearSelection() {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.ClearSelection()
+}
+
+// Selection 以并发安全的方式返回当前选择。
+func (se *SafeEmulator) Selection() (Selection, bool) {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.Selection()
+}
+
+// IsSelected 以并发安全的方式报告视口位置 (x, y) 的单元格是否被选中。
+func (se *SafeEmulator) IsSelected(x, y int) bool {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.IsSelected(x, y)
+}
+
+// SelectedText 以并发安全的方式返回选中的纯文本。
+func (se *SafeEmulator) SelectedText() string {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.SelectedText()
+}
+
+// SelectedStyledText 以并发安全的方式返回带样式的选中文本。
+func (se *SafeEmulator) SelectedStyledText() string {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.SelectedStyledText()
+}
+
+// SelectedCells 以并发安全的方式返回选择覆盖的单元格。
+func (se *SafeEmulator) SelectedCells() []SelectedCell {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.SelectedCells()
+}
+
+// Search 以并发安全的方式搜索当前屏幕和回滚缓冲区。
+func (se *SafeEmulator) Search(re *regexp.Regexp) []Match {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.Search(re)
+}
+
+// SearchNext 以并发安全的方式返回在 from 之后开始的第一个匹配。
+func (se *SafeEmulator) SearchNext(re *regexp.Regexp, from uv.Position) (Match, bool) {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.SearchNext(re, from)
+}
+
+// SearchPrev 以并发安全的方式返回在 from 之前开始的最后一个匹配。
+func (se *SafeEmulator) SearchPrev(re *regexp.Regexp, from uv.Position) (Match, bool) {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.SearchPrev(re, from)
+}
+
+// DrainDamage 以并发安全的方式返回并清除累积的损坏。
+func (se *SafeEmulator) DrainDamage() []Damage {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	return se.Emulator.DrainDamage()
+}
+
+// Focus 以并发安全的方式通知终端获得焦点。
+func (se *SafeEmulator) Focus() {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.Focus()
+}
+
+// Blur 以并发安全的方式通知终端失去焦点。
+func (se *SafeEmulator) Blur() {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.Blur()
+}
+
+// Snapshot 以并发安全的方式返回模拟器当前状态的快照。
+func (se *SafeEmulator) Snapshot() *Snapshot {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.Snapshot()
+}
+
+// Restore 以并发安全的方式将模拟器恢复到快照的状态。
+func (se *SafeEmulator) Restore(s *Snapshot) error {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	return se.Emulator.Restore(s)
+}
+
+// Progress 以并发安全的方式返回应用程序最后报告的进度。
+func (se *SafeEmulator) Progress() Progress {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.Progress()
+}
+
+// LineAttr 以并发安全的方式返回当前屏幕第 y 行的尺寸属性。
+func (se *SafeEmulator) LineAttr(y int) LineAttr {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.LineAttr(y)
+}
+
+// SetTerminalVersion 以并发安全的方式设置用于回复 XTVERSION 请求的终端名称和版本。
+func (se *SafeEmulator) SetTerminalVersion(name, version string) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.SetTerminalVersion(name, version)
+}
+
+// SetDefaultForegroundColor 以并发安全的方式设置终端的默认前景颜色。
+func (se *SafeEmulator) SetDefaultForegroundColor(c color.Color) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.SetDefaultForegroundColor(c)
+}
+
+// SetDefaultBackgroundColor 以并发安全的方式设置终端的默认背景颜色。
+func (se *SafeEmulator) SetDefaultBackgroundColor(c color.Color) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.SetDefaultBackgroundColor(c)
+}
+
+// SetDefaultCursorColor 以并发安全的方式设置终端的默认光标颜色。
+func (se *SafeEmulator) SetDefaultCursorColor(c color.Color) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.SetDefaultCursorColor(c)
+}
+
+// Hyperlinks 以并发安全的方式返回视口中可见的所有超链接。
+func (se *SafeEmulator) Hyperlinks() []Hyperlink {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.Hyperlinks()
+}
+
+// LinkAt 以并发安全的方式返回视口位置 (x, y) 的超链接。
+func (se *SafeEmulator) LinkAt(x, y int) (Hyperlink, bool) {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+	return se.Emulator.LinkAt(x, y)
+}
+
+// SetLinkPattern 以并发安全的方式设置用于识别隐式超链接的正则表达式。
+func (se *SafeEmulator) SetLinkPattern(re *regexp.Regexp) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.SetLinkPattern(re)
+}
+
+// SetDefaultIndexedColor 以并发安全的方式设置默认索引颜色。
+func (se *SafeEmulator) SetDefaultIndexedColor(i int, c color.Color) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.Emulator.SetDefaultIndexedColor(i, c)
+}
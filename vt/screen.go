@@ -15,6 +15,17 @@ type Screen struct {
 	cur, saved Cursor
 	// scroll 是滚动区域。
 	scroll uv.Rectangle
+	// wrapped 记录每一行是否以自动换行（软换行）结束，而不是以真正的换行符结束。
+	wrapped []bool
+	// lineAttrs 记录每一行的尺寸属性。
+	lineAttrs []LineAttr
+	// kittyFlags 是 Kitty 键盘协议的渐进增强标志堆栈。主屏幕和备用屏幕
+	// 各自维护独立的堆栈。
+	kittyFlags []int
+	// images 是屏幕上的图像放置。
+	images []ImagePlacement
+	// damage 是收集屏幕损坏的队列。它可以为 nil。
+	damage *damageQueue
 }
 
 // NewScreen 创建一个新屏幕。
@@ -28,9 +39,14 @@ func NewScreen(w, h int) *Screen {
 // 它清除屏幕，将光标设置到左上角，重置光标样式，并重置滚动区域。
 func (s *Screen) Reset() {
 	s.buf.Clear()
+	clear(s.wrapped)
+	clear(s.lineAttrs)
+	s.kittyFlags = s.kittyFlags[:0]
+	s.images = nil
 	s.cur = Cursor{}
 	s.saved = Cursor{}
 	s.scroll = s.buf.Bounds()
+	s.addDamage(ScreenDamage{Width: s.Width(), Height: s.Height()})
 }
 
 // Bounds 返回屏幕的边界。
@@ -51,6 +67,11 @@ func (s *Screen) CellAt(x int, y int) *uv.Cell {
 // SetCell 设置给定x, y位置的单元格。
 func (s *Screen) SetCell(x, y int, c *uv.Cell) {
 	s.buf.SetCell(x, y, c)
+	w := 1
+	if c != nil && c.Width > 1 {
+		w = c.Width
+	}
+	s.addDamage(CellDamage{X: x, Y: y, Width: w})
 }
 
 // Height 返回屏幕的高度。
@@ -62,6 +83,31 @@ func (s *Screen) Height() int {
 func (s *Screen) Resize(width int, height int) {
 	s.buf.Resize(width, height)
 	s.scroll = s.buf.Bounds()
+	if height > len(s.wrapped) {
+		s.wrapped = append(s.wrapped, make([]bool, height-len(s.wrapped))...)
+	}
+	s.wrapped = s.wrapped[:height]
+	if height > len(s.lineAttrs) {
+		s.lineAttrs = append(s.lineAttrs, make([]LineAttr, height-len(s.lineAttrs))...)
+	}
+	s.lineAttrs = s.lineAttrs[:height]
+}
+
+// IsWrapped 报告第 y 行是否以自动换行（软换行）结束。软换行的行与下一行
+// 共同组成一个逻辑行。
+func (s *Screen) IsWrapped(y int) bool {
+	if y < 0 || y >= len(s.wrapped) {
+		return false
+	}
+	return s.wrapped[y]
+}
+
+// setWrapped 设置第 y 行的软换行标志。
+func (s *Screen) setWrapped(y int, wrapped bool) {
+	if y < 0 || y >= len(s.wrapped) {
+		return
+	}
+	s.wrapped[y] = wrapped
 }
 
 // Width 返回屏幕的宽度。
@@ -69,14 +115,16 @@ func (s *Screen) Width() int {
 	return s.buf.Width()
 }
 
-// Clear 用空白单元格清除屏幕。
+// Clear 用空白单元格清除屏幕，删除所有图像放置，并将所有行重置为单宽单高行。
 func (s *Screen) Clear() {
 	s.ClearArea(s.Bounds())
+	s.eraseImages(s.Bounds())
+	s.resetLineAttrs(0, s.Height())
 }
 
 // ClearArea 清除给定区域。
 func (s *Screen) ClearArea(area uv.Rectangle) {
-	s.buf.ClearArea(area)
+	s.FillArea(nil, area)
 }
 
 // Fill 填充屏幕或其部分。
@@ -85,8 +133,16 @@ func (s *Screen) Fill(c *uv.Cell) {
 }
 
 // FillArea 用给定的单元格填充给定区域。
+// 与 xterm 一样，延伸到行尾的填充会清除这些行的软换行标志。
 func (s *Screen) FillArea(c *uv.Cell, area uv.Rectangle) {
 	s.buf.FillArea(c, area)
+	s.clearImages(area)
+	s.addDamage(RectDamage(area))
+	if area.Max.X >= s.Width() {
+		for y := max(0, area.Min.Y); y < area.Max.Y && y < len(s.wrapped); y++ {
+			s.wrapped[y] = false
+		}
+	}
 }
 
 // setHorizontalMargins 设置水平边距。
@@ -116,6 +172,7 @@ func (s *Screen) setCursor(x, y int, margins bool) {
 		y = ordered.Clamp(s.scroll.Min.Y+y, s.scroll.Min.Y, s.scroll.Max.Y-1) // 限制在滚动区域内
 		x = ordered.Clamp(s.scroll.Min.X+x, s.scroll.Min.X, s.scroll.Max.X-1) // 限制在滚动区域内
 	}
+	x = min(x, s.lineWidth(y)-1) // 限制在双宽行的宽度内
 	s.cur.X, s.cur.Y = x, y
 
 	// 如果光标位置发生变化，调用回调
@@ -147,6 +204,7 @@ func (s *Screen) moveCursor(dx, dy int) {
 		y = ordered.Clamp(pt.Y, 0, s.buf.Height()-1) // 限制在屏幕边界内
 		x = ordered.Clamp(pt.X, 0, s.buf.Width()-1)  // 限制在屏幕边界内
 	}
+	x = min(x, s.lineWidth(y)-1) // 限制在双宽行的宽度内
 
 	s.cur.X, s.cur.Y = x, y
 
@@ -234,6 +292,16 @@ func (s *Screen) InsertCell(n int) {
 
 	x, y := s.cur.X, s.cur.Y
 	s.buf.InsertCellArea(x, y, n, s.blankCell(), s.scroll)
+
+	if uv.Pos(x, y).In(s.scroll) {
+		// 光标右侧的单元格向右移动，光标处插入空白单元格。
+		w := s.scroll.Max.X - x
+		n = min(n, w)
+		if n < w {
+			s.addDamage(MoveDamage{Src: uv.Rect(x, y, w-n, 1), Dst: uv.Rect(x+n, y, w-n, 1)})
+		}
+		s.addDamage(RectDamage(uv.Rect(x, y, n, 1)))
+	}
 }
 
 // DeleteCell 删除光标位置的n个单元格，将左侧的单元格向左移动。
@@ -245,6 +313,82 @@ func (s *Screen) DeleteCell(n int) {
 
 	x, y := s.cur.X, s.cur.Y
 	s.buf.DeleteCellArea(x, y, n, s.blankCell(), s.scroll)
+
+	if uv.Pos(x, y).In(s.scroll) {
+		// 被删除单元格右侧的单元格向左移动，右边距处插入空白单元格。
+		w := s.scroll.Max.X - x
+		n = min(n, w)
+		if n < w {
+			s.addDamage(MoveDamage{Src: uv.Rect(x+n, y, w-n, 1), Dst: uv.Rect(x, y, w-n, 1)})
+		}
+		s.addDamage(RectDamage(uv.Rect(s.scroll.Max.X-n, y, n, 1)))
+	}
+}
+
+// InsertColumn 在光标所在列插入n个空白列，将滚动区域内右侧的列向右推并移出
+// 右边距。如果光标在滚动区域外，这没有效果。
+func (s *Screen) InsertColumn(n int) {
+	x := s.cur.X
+	if n <= 0 || !s.cur.Position.In(s.scroll) {
+		return
+	}
+
+	for y := s.scroll.Min.Y; y < s.scroll.Max.Y; y++ {
+		s.buf.InsertCellArea(x, y, n, s.blankCell(), s.scroll)
+	}
+
+	w := s.scroll.Max.X - x
+	n = min(n, w)
+	if n < w {
+		s.addDamage(MoveDamage{
+			Src: uv.Rect(x, s.scroll.Min.Y, w-n, s.scroll.Dy()),
+			Dst: uv.Rect(x+n, s.scroll.Min.Y, w-n, s.scroll.Dy()),
+		})
+	}
+	s.addDamage(RectDamage(uv.Rect(x, s.scroll.Min.Y, n, s.scroll.Dy())))
+}
+
+// DeleteColumn 删除光标所在列开始的n列，将滚动区域内右侧的列向左移动，并在
+// 右边距处插入空白列。如果光标在滚动区域外，这没有效果。
+func (s *Screen) DeleteColumn(n int) {
+	x := s.cur.X
+	if n <= 0 || !s.cur.Position.In(s.scroll) {
+		return
+	}
+
+	for y := s.scroll.Min.Y; y < s.scroll.Max.Y; y++ {
+		s.buf.DeleteCellArea(x, y, n, s.blankCell(), s.scroll)
+	}
+
+	w := s.scroll.Max.X - x
+	n = min(n, w)
+	if n < w {
+		s.addDamage(MoveDamage{
+			Src: uv.Rect(x+n, s.scroll.Min.Y, w-n, s.scroll.Dy()),
+			Dst: uv.Rect(x, s.scroll.Min.Y, w-n, s.scroll.Dy()),
+		})
+	}
+	s.addDamage(RectDamage(uv.Rect(s.scroll.Max.X-n, s.scroll.Min.Y, n, s.scroll.Dy())))
+}
+
+// updateArea 对区域中的每个单元格调用 fn 来修改它。宽字符的占位单元格会被跳过。
+func (s *Screen) updateArea(area uv.Rectangle, fn func(*uv.Cell)) {
+	area = area.Intersect(s.Bounds())
+	if area.Empty() {
+		return
+	}
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			c := s.buf.CellAt(x, y)
+			if c == nil || c.Width == 0 {
+				continue
+			}
+			c = c.Clone()
+			fn(c)
+			s.buf.SetCell(x, y, c)
+		}
+	}
+	s.addDamage(RectDamage(area))
 }
 
 // ScrollUp 在给定区域内向上滚动内容n行。超过上边缘滚动的行将丢失。
@@ -283,6 +427,11 @@ func (s *Screen) InsertLine(n int) bool {
 
 	s.buf.InsertLineArea(y, n, s.blankCell(), s.scroll)
 
+	n = min(n, s.scroll.Max.Y-y)
+	s.shiftLineFlags(y, n)
+	s.shiftImages(y, n)
+	s.scrollDamage(y, n)
+
 	return true
 }
 
@@ -305,9 +454,63 @@ func (s *Screen) DeleteLine(n int) bool {
 
 	s.buf.DeleteLineArea(y, n, s.blankCell(), scroll)
 
+	n = min(n, scroll.Max.Y-y)
+	s.shiftLineFlags(y, -n)
+	s.shiftImages(y, -n)
+	s.scrollDamage(y, -n)
+
 	return true
 }
 
+// shiftLineFlags 将第 y 行及其下方滚动区域内的软换行标志和行尺寸属性垂直移动
+// n 行，正值向下移动，负值向上移动。这些标志属于整行，因此只有滚动区域横跨
+// 整个屏幕宽度时才随行移动；否则行只有一部分移动，受影响的行不再是软换行的，
+// 行尺寸属性保持不变。
+func (s *Screen) shiftLineFlags(y, n int) {
+	bottom := s.scroll.Max.Y
+	if s.scroll.Min.X > 0 || s.scroll.Max.X < s.Width() {
+		clear(s.wrapped[y:bottom])
+		return
+	}
+	if n > 0 {
+		copy(s.wrapped[y+n:bottom], s.wrapped[y:bottom-n])
+		clear(s.wrapped[y : y+n])
+		copy(s.lineAttrs[y+n:bottom], s.lineAttrs[y:bottom-n])
+		clear(s.lineAttrs[y : y+n])
+	} else {
+		n = -n
+		copy(s.wrapped[y:bottom-n], s.wrapped[y+n:bottom])
+		clear(s.wrapped[bottom-n : bottom])
+		copy(s.lineAttrs[y:bottom-n], s.lineAttrs[y+n:bottom])
+		clear(s.lineAttrs[bottom-n : bottom])
+	}
+}
+
+// scrollDamage 报告第 y 行及其下方的滚动区域内容垂直移动 n 行造成的损坏。
+func (s *Screen) scrollDamage(y, n int) {
+	area := s.scroll
+	area.Min.Y = y
+	if max(n, -n) < area.Dy() {
+		s.addDamage(ScrollDamage{Rectangle: area, Dy: n})
+	}
+
+	// 滚入区域的空白行。
+	blank := area
+	if n > 0 {
+		blank.Max.Y = min(area.Max.Y, y+n)
+	} else {
+		blank.Min.Y = max(y, area.Max.Y+n)
+	}
+	s.addDamage(RectDamage(blank))
+}
+
+// addDamage 将损坏加入屏幕的损坏队列。
+func (s *Screen) addDamage(d Damage) {
+	if s.damage != nil {
+		s.damage.add(d, s.Bounds())
+	}
+}
+
 // blankCell 返回光标空白单元格，背景颜色设置为当前笔背景颜色。
 // 如果笔背景颜色为nil，返回值为nil。
 func (s *Screen) blankCell() *uv.Cell {
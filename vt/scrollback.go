@@ -0,0 +1,227 @@
+package vt
+
+import (
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+// DefaultScrollbackSize 是新建模拟器的默认回滚缓冲区行数。
+const DefaultScrollbackSize = 10000
+
+// Scrollback 表示一个有界的回滚缓冲区。它是一个环形缓冲区，保存从主屏幕
+// 顶部滚出的行。当缓冲区已满时，最旧的行会被丢弃。
+type Scrollback struct {
+	// lines 是环形缓冲区的存储。
+	lines []uv.Line
+	// wrapped 记录每一行是否以软换行结束，与 lines 使用相同的索引。
+	wrapped []bool
+	// start 是最旧一行在 lines 中的索引。
+	start int
+	// maxLines 是缓冲区可以保存的最大行数。
+	maxLines int
+}
+
+// NewScrollback 创建一个最多保存 maxLines 行的回滚缓冲区。
+// 如果 maxLines 小于或等于零，缓冲区不会保存任何行。
+func NewScrollback(maxLines int) *Scrollback {
+	return &Scrollback{maxLines: max(0, maxLines)}
+}
+
+// Len 返回缓冲区中的行数。
+func (s *Scrollback) Len() int {
+	return len(s.lines)
+}
+
+// MaxLines 返回缓冲区可以保存的最大行数。
+func (s *Scrollback) MaxLines() int {
+	return s.maxLines
+}
+
+// SetMaxLines 设置缓冲区可以保存的最大行数。如果当前行数超过新的上限，
+// 最旧的行会被丢弃。
+func (s *Scrollback) SetMaxLines(n int) {
+	n = max(0, n)
+	lines := s.Lines()
+	wrapped := make([]bool, len(lines))
+	for i := range wrapped {
+		wrapped[i] = s.IsWrapped(i)
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+		wrapped = wrapped[len(wrapped)-n:]
+	}
+	s.lines = lines
+	s.wrapped = wrapped
+	s.start = 0
+	s.maxLines = n
+}
+
+// Line 返回第 i 行，其中 0 是最旧的一行。如果索引越界，则返回 nil。
+// 返回的行不应被修改。
+func (s *Scrollback) Line(i int) uv.Line {
+	if i < 0 || i >= len(s.lines) {
+		return nil
+	}
+	return s.lines[(s.start+i)%len(s.lines)]
+}
+
+// IsWrapped 报告第 i 行是否以软换行结束，即它与下一行属于同一个逻辑行。
+func (s *Scrollback) IsWrapped(i int) bool {
+	if i < 0 || i >= len(s.wrapped) {
+		return false
+	}
+	return s.wrapped[(s.start+i)%len(s.wrapped)]
+}
+
+// Lines 返回一个按从旧到新排列的包含缓冲区中所有行的新切片。
+func (s *Scrollback) Lines() []uv.Line {
+	lines := make([]uv.Line, 0, len(s.lines))
+	for i := range s.lines {
+		lines = append(lines, s.Line(i))
+	}
+	return lines
+}
+
+// Push 将一行追加到缓冲区末尾。该行会被复制，因此调用者可以继续使用它。
+// 如果缓冲区已满，最旧的行会被丢弃。
+func (s *Scrollback) Push(line uv.Line) {
+	s.push(line, false)
+}
+
+// push 将一行及其软换行标志追加到缓冲区末尾。
+func (s *Scrollback) push(line uv.Line, wrapped bool) {
+	if s.maxLines <= 0 {
+		return
+	}
+
+	l := make(uv.Line, len(line))
+	copy(l, line)
+	if len(s.lines) < s.maxLines {
+		s.lines = append(s.lines, l)
+		s.wrapped = append(s.wrapped, wrapped)
+		return
+	}
+
+	s.lines[s.start] = l
+	s.wrapped[s.start] = wrapped
+	s.start = (s.start + 1) % len(s.lines)
+}
+
+// Clear 清除缓冲区中的所有行。
+func (s *Scrollback) Clear() {
+	s.lines = nil
+	s.wrapped = nil
+	s.start = 0
+}
+
+// Scrollback 返回主屏幕的回滚缓冲区。
+func (e *Emulator) Scrollback() *Scrollback {
+	return e.scrollback
+}
+
+// SetScrollbackSize 设置回滚缓冲区可以保存的最大行数。零表示禁用回滚缓冲区。
+func (e *Emulator) SetScrollbackSize(n int) {
+	e.scrollback.SetMaxLines(n)
+	e.setScrollOffset(e.scrollOffset)
+	e.pruneSelection()
+	e.flushDamage()
+}
+
+// ClearScrollback 清除回滚缓冲区并将视口重置到屏幕底部。
+func (e *Emulator) ClearScrollback() {
+	e.clearScrollback()
+	e.flushDamage()
+}
+
+// clearScrollback 清除回滚缓冲区并将视口重置到屏幕底部，但不传递损坏。
+func (e *Emulator) clearScrollback() {
+	e.scrollback.Clear()
+	e.setScrollOffset(0)
+	e.pruneSelection()
+}
+
+// ScrollOffset 返回视口向回滚缓冲区滚动的行数。零表示视口位于屏幕底部，
+// 即显示当前屏幕。
+func (e *Emulator) ScrollOffset() int {
+	return e.scrollOffset
+}
+
+// SetScrollOffset 设置视口向回滚缓冲区滚动的行数。偏移量被限制在零和回滚
+// 缓冲区行数之间。[Emulator.Draw] 会根据该偏移量绘制回滚缓冲区中的行。
+func (e *Emulator) SetScrollOffset(n int) {
+	e.setScrollOffset(n)
+	e.flushDamage()
+}
+
+// ScrollViewport 按给定的增量滚动视口。正值向回滚缓冲区（向上）滚动，
+// 负值向屏幕底部（向下）滚动。
+func (e *Emulator) ScrollViewport(delta int) {
+	e.setScrollOffset(e.scrollOffset + delta)
+	e.flushDamage()
+}
+
+// setScrollOffset 设置视口偏移量，并将其限制在有效范围内。视口改变时整个屏幕
+// 被标记为损坏。写入期间损坏在 [Emulator.Write] 结束时传递；导出的滚动方法
+// 不伴随写入，因此需要自行调用 flushDamage。
+func (e *Emulator) setScrollOffset(n int) {
+	n = min(max(0, n), e.scrollback.Len())
+	if n != e.scrollOffset {
+		e.scrollOffset = n
+		e.damageScreen()
+	}
+}
+
+// viewportLine 返回视口中第 y 行对应的行。当视口向回滚缓冲区滚动时，
+// 顶部的行来自回滚缓冲区，其余的行来自主屏幕。交替屏幕没有回滚缓冲区，
+// 因此总是返回当前屏幕的行。
+func (e *Emulator) viewportLine(y int) uv.Line {
+	offset := e.scrollOffset
+	if e.scr != &e.scrs[0] {
+		offset = 0
+	}
+	if y < offset {
+		return e.scrollback.Line(e.scrollback.Len() - offset + y)
+	}
+	return e.scr.buf.Line(y - offset)
+}
+
+// viewportLineAttr 返回视口中第 y 行的尺寸属性。回滚缓冲区中的行总是单宽的。
+func (e *Emulator) viewportLineAttr(y int) LineAttr {
+	offset := e.scrollOffset
+	if e.scr != &e.scrs[0] {
+		offset = 0
+	}
+	if y < offset {
+		return LineSingleWidth
+	}
+	return e.scr.LineAttr(y - offset)
+}
+
+// scrollUp 在滚动区域内向上滚动内容 n 行。当主屏幕的滚动区域从屏幕顶部开始
+// 并覆盖整个宽度时，滚出的行会被保存到回滚缓冲区。
+func (e *Emulator) scrollUp(n int) {
+	scroll := e.scr.ScrollRegion()
+	if e.scr == &e.scrs[0] && n > 0 && scroll.Min.Y == 0 &&
+		scroll.Min.X == 0 && scroll.Max.X == e.scr.Width() {
+		n := min(n, scroll.Dy())
+		for y := range n {
+			e.scrollback.push(e.scr.buf.Line(y), e.scr.IsWrapped(y))
+		}
+		e.lineOffset += n
+		if e.scrollOffset > 0 {
+			// 保持视口固定在相同的内容上。
+			e.setScrollOffset(e.scrollOffset + n)
+		}
+		e.pruneSelection()
+	} else if n > 0 {
+		e.shiftSelection(scroll.Min.Y, -n)
+	}
+	e.scr.ScrollUp(n)
+}
+
+// scrollDown 在滚动区域内向下滚动内容 n 行。
+func (e *Emulator) scrollDown(n int) {
+	if n > 0 {
+		e.shiftSelection(e.scr.ScrollRegion().Min.Y, n)
+	}
+	e.scr.ScrollDown(n)
+}
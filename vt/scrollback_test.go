@@ -0,0 +1,80 @@
+package vt
+
+import (
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+func TestScrollback(t *testing.T) {
+	term := newTestTerminal(t, 5, 2)
+	term.WriteString("\x1b[1mone\x1b[m\r\n\x1b]8;;https://example.com\x07two\x1b]8;;\x07\r\nthree\r\nfour")
+
+	sb := term.Scrollback()
+	if sb.Len() != 2 {
+		t.Fatalf("expected 2 scrollback lines, got %d", sb.Len())
+	}
+	if got := sb.Line(0).String(); got != "one" {
+		t.Errorf("line 0: want %q, got %q", "one", got)
+	}
+	if attrs := sb.Line(0).At(0).Style.Attrs; attrs&uv.AttrBold == 0 {
+		t.Errorf("line 0: expected bold style to be kept")
+	}
+	if url := sb.Line(1).At(0).Link.URL; url != "https://example.com" {
+		t.Errorf("line 1: expected hyperlink to be kept, got %q", url)
+	}
+
+	// Erase saved lines.
+	term.WriteString("\x1b[3J")
+	if sb.Len() != 0 {
+		t.Errorf("expected scrollback to be cleared, got %d lines", sb.Len())
+	}
+	if got := termText(term); got[0] != "three" || got[1] != "four " {
+		t.Errorf("expected screen to be untouched, got %q", got)
+	}
+}
+
+func TestScrollbackBounded(t *testing.T) {
+	term := newTestTerminal(t, 5, 1)
+	term.SetScrollbackSize(2)
+	term.WriteString("a\r\nb\r\nc\r\nd")
+
+	sb := term.Scrollback()
+	if sb.Len() != 2 {
+		t.Fatalf("expected 2 scrollback lines, got %d", sb.Len())
+	}
+	if got := sb.Line(0).String() + sb.Line(1).String(); got != "bc" {
+		t.Errorf("expected oldest lines to be dropped, got %q", got)
+	}
+}
+
+func TestScrollbackAltScreen(t *testing.T) {
+	term := newTestTerminal(t, 5, 1)
+	term.WriteString("\x1b[?1049ha\r\nb\r\nc")
+	if n := term.Scrollback().Len(); n != 0 {
+		t.Errorf("expected no scrollback from the alt screen, got %d lines", n)
+	}
+}
+
+func TestScrollbackViewport(t *testing.T) {
+	term := newTestTerminal(t, 5, 2)
+	term.WriteString("a\r\nb\r\nc\r\nd")
+
+	term.SetScrollOffset(10)
+	if off := term.ScrollOffset(); off != 2 {
+		t.Fatalf("expected offset to be clamped to 2, got %d", off)
+	}
+
+	scr := uv.NewScreenBuffer(5, 2)
+	term.Draw(scr, scr.Bounds())
+	if got := scr.CellAt(0, 0).Content + scr.CellAt(0, 1).Content; got != "ab" {
+		t.Errorf("expected viewport to show scrollback, got %q", got)
+	}
+
+	// New output keeps the viewport anchored on the same content.
+	term.SetScrollOffset(1)
+	term.WriteString("\r\ne")
+	if off := term.ScrollOffset(); off != 2 {
+		t.Errorf("expected offset to follow new scrollback lines, got %d", off)
+	}
+}
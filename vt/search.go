@@ -0,0 +1,150 @@
+package vt
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+// Match 表示一个搜索匹配。Start 是匹配的第一个单元格，End 是匹配的最后一个
+// 单元格（包含）。Y 的含义与 [Selection] 相同：在主屏幕上是绝对行号，在交替
+// 屏幕上是屏幕行号。
+type Match struct {
+	Start, End uv.Position
+}
+
+// Literal 返回匹配给定文本的正则表达式，用于按字面文本搜索。
+func Literal(s string) *regexp.Regexp {
+	return regexp.MustCompile(regexp.QuoteMeta(s))
+}
+
+// Search 返回当前屏幕中与 re 匹配的所有文本，按从旧到新排列。在主屏幕上，
+// 回滚缓冲区也会被搜索。软换行的行会被合并为一个逻辑行，因此匹配可以跨越
+// 多行。空匹配会被忽略。
+func (e *Emulator) Search(re *regexp.Regexp) []Match {
+	var matches []Match
+	first, last := e.searchBounds()
+	for y := first; y <= last; {
+		l := e.logicalLine(y)
+		matches = append(matches, l.matches(re)...)
+		y = l.end + 1
+	}
+	return matches
+}
+
+// SearchNext 返回在 from 之后开始的第一个匹配。搜索从 from 所在的逻辑行开始
+// 向后进行，并在找到匹配时停止。如果没有更多的匹配，则返回 false。
+func (e *Emulator) SearchNext(re *regexp.Regexp, from uv.Position) (Match, bool) {
+	first, last := e.searchBounds()
+	for y := max(first, from.Y); y <= last; {
+		l := e.logicalLine(y)
+		for _, m := range l.matches(re) {
+			if posBefore(from, m.Start) {
+				return m, true
+			}
+		}
+		y = l.end + 1
+	}
+	return Match{}, false
+}
+
+// SearchPrev 返回在 from 之前开始的最后一个匹配。搜索从 from 所在的逻辑行
+// 开始向前进行，并在找到匹配时停止。如果没有更多的匹配，则返回 false。
+func (e *Emulator) SearchPrev(re *regexp.Regexp, from uv.Position) (Match, bool) {
+	first, last := e.searchBounds()
+	for y := min(last, from.Y); y >= first; {
+		l := e.logicalLine(y)
+		matches := l.matches(re)
+		for i := len(matches) - 1; i >= 0; i-- {
+			if posBefore(matches[i].Start, from) {
+				return matches[i], true
+			}
+		}
+		y = l.start - 1
+	}
+	return Match{}, false
+}
+
+// searchBounds 返回可以搜索的第一行和最后一行。
+func (e *Emulator) searchBounds() (first, last int) {
+	if e.scr != &e.scrs[0] {
+		return 0, e.scr.Height() - 1
+	}
+	return e.lineOffset - e.scrollback.Len(), e.lineOffset + e.scr.Height() - 1
+}
+
+// searchLine 返回当前屏幕第 y 行及其软换行标志，Y 的含义与 [Match] 相同。
+// 如果该行不存在，则返回 nil。
+func (e *Emulator) searchLine(y int) (uv.Line, bool) {
+	if e.scr == &e.scrs[0] {
+		return e.absoluteLine(y)
+	}
+	if y < 0 || y >= e.scr.Height() {
+		return nil, false
+	}
+	return e.scr.buf.Line(y), e.scr.IsWrapped(y)
+}
+
+// logicalLine 是由软换行连接起来的一组行的文本，以及文本中每个单元格的字节
+// 偏移量和位置。
+type logicalLine struct {
+	start, end int
+	text       string
+	offsets    []int
+	cells      []uv.Position
+}
+
+// logicalLine 返回包含第 y 行的逻辑行。
+func (e *Emulator) logicalLine(y int) logicalLine {
+	l := logicalLine{start: y}
+	for {
+		if line, wrapped := e.searchLine(l.start - 1); line == nil || !wrapped {
+			break
+		}
+		l.start--
+	}
+
+	var b strings.Builder
+	for l.end = l.start; ; l.end++ {
+		line, wrapped := e.searchLine(l.end)
+		for x, c := range line {
+			if c.IsZero() {
+				continue
+			}
+			l.offsets = append(l.offsets, b.Len())
+			l.cells = append(l.cells, uv.Pos(x, l.end))
+			if c.Content == "" {
+				b.WriteByte(' ')
+			} else {
+				b.WriteString(c.Content)
+			}
+		}
+		if next, _ := e.searchLine(l.end + 1); !wrapped || next == nil {
+			break
+		}
+	}
+	l.text = strings.TrimRight(b.String(), " ")
+	return l
+}
+
+// matches 返回逻辑行中与 re 匹配的所有非空文本。
+func (l logicalLine) matches(re *regexp.Regexp) []Match {
+	var matches []Match
+	for _, loc := range re.FindAllStringIndex(l.text, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		matches = append(matches, l.match(loc))
+	}
+	return matches
+}
+
+// match 返回覆盖逻辑行文本中 loc 字节范围的匹配。
+func (l logicalLine) match(loc []int) Match {
+	// 包含匹配开始和结束字节的单元格。
+	i := sort.SearchInts(l.offsets, loc[0]+1) - 1
+	j := sort.SearchInts(l.offsets, loc[1]) - 1
+	return Match{Start: l.cells[i], End: l.cells[j]}
+}
@@ -0,0 +1,83 @@
+package vt
+
+import (
+	"regexp"
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+func TestSearch(t *testing.T) {
+	term := newTestTerminal(t, 10, 3)
+	term.WriteString("foo bar\r\nbarbaz foo\r\n你好foo")
+
+	matches := term.Search(Literal("foo"))
+	want := []Match{
+		{uv.Pos(0, 0), uv.Pos(2, 0)},
+		{uv.Pos(7, 1), uv.Pos(9, 1)},
+		// Wide characters take two cells each.
+		{uv.Pos(4, 2), uv.Pos(6, 2)},
+	}
+	if len(matches) != len(want) {
+		t.Fatalf("Search() = %v, want %v", matches, want)
+	}
+	for i := range want {
+		if matches[i] != want[i] {
+			t.Errorf("match %d = %v, want %v", i, matches[i], want[i])
+		}
+	}
+
+	re := regexp.MustCompile(`好f`)
+	if got := term.Search(re); len(got) != 1 || got[0] != (Match{uv.Pos(2, 2), uv.Pos(4, 2)}) {
+		t.Errorf("Search(%v) = %v", re, got)
+	}
+}
+
+func TestSearchWrappedAndScrollback(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	term.WriteString("12345678foo\r\nbar\r\nbaz")
+
+	// "foo" is split by a soft wrap, and the line scrolled into the
+	// scrollback.
+	matches := term.Search(Literal("foo"))
+	if want := (Match{uv.Pos(8, 0), uv.Pos(0, 1)}); len(matches) != 1 || matches[0] != want {
+		t.Errorf("Search() = %v, want [%v]", matches, want)
+	}
+	if got := term.AbsoluteLine(0); got != 2 {
+		t.Errorf("AbsoluteLine(0) = %d, want 2", got)
+	}
+}
+
+func TestSearchNextPrev(t *testing.T) {
+	term := newTestTerminal(t, 10, 3)
+	term.WriteString("ab ab\r\nxx\r\nab")
+	re := Literal("ab")
+
+	m, ok := term.SearchNext(re, uv.Pos(-1, 0))
+	if !ok || m.Start != uv.Pos(0, 0) {
+		t.Fatalf("first SearchNext = %v, %v", m, ok)
+	}
+	m, ok = term.SearchNext(re, m.Start)
+	if !ok || m.Start != uv.Pos(3, 0) {
+		t.Fatalf("second SearchNext = %v, %v", m, ok)
+	}
+	m, ok = term.SearchNext(re, m.Start)
+	if !ok || m.Start != uv.Pos(0, 2) {
+		t.Fatalf("third SearchNext = %v, %v", m, ok)
+	}
+	if _, ok := term.SearchNext(re, m.Start); ok {
+		t.Error("expected no more matches")
+	}
+
+	m, ok = term.SearchPrev(re, m.Start)
+	if !ok || m.Start != uv.Pos(3, 0) {
+		t.Fatalf("SearchPrev = %v, %v", m, ok)
+	}
+	m, ok = term.SearchPrev(re, m.Start)
+	if !ok || m.Start != uv.Pos(0, 0) {
+		t.Fatalf("second SearchPrev = %v, %v", m, ok)
+	}
+	if _, ok := term.SearchPrev(re, m.Start); ok {
+		t.Error("expected no earlier matches")
+	}
+}
@@ -0,0 +1,392 @@
+package vt
+
+import (
+	"strings"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+// SelectionMode 表示选择的模式。
+type SelectionMode int
+
+// 选择模式。
+const (
+	// SelectionLinear 按阅读顺序选择从起点到终点的所有单元格。
+	SelectionLinear SelectionMode = iota
+	// SelectionBlock 选择以起点和终点为对角的矩形区域。
+	SelectionBlock
+	// SelectionWord 与 SelectionLinear 相同，但两端扩展到完整的单词。
+	SelectionWord
+	// SelectionLine 与 SelectionLinear 相同，但两端扩展到完整的逻辑行，
+	// 即由软换行连接起来的所有行。
+	SelectionLine
+)
+
+// DefaultWordDelimiters 是单词选择默认使用的分隔符。空白总是分隔单词。
+const DefaultWordDelimiters = "\"'`()[]{}<>|;,"
+
+// Selection 表示文本选择。
+//
+// 在主屏幕上，位置的 Y 是绝对行号（参见 [Emulator.AbsoluteLine]），因此当内容
+// 滚动到回滚缓冲区时，选择仍然指向相同的内容。在交替屏幕上，Y 是屏幕行号。
+type Selection struct {
+	// Mode 是选择的模式。
+	Mode SelectionMode
+	// Start 是选择开始的位置，End 是选择扩展到的位置。End 可以在 Start
+	// 之前。
+	Start, End uv.Position
+}
+
+// SelectedCell 表示选择覆盖的一个单元格。
+type SelectedCell struct {
+	// Position 是单元格的位置，Y 的含义与 [Selection] 相同。
+	Position uv.Position
+	// Cell 是单元格的副本。
+	Cell uv.Cell
+}
+
+// selection 是模拟器的当前选择，以及它所在的屏幕。
+type selection struct {
+	Selection
+	alt bool
+}
+
+// StartSelection 在视口位置 (x, y) 开始一个新的选择，替换任何现有的选择。
+func (e *Emulator) StartSelection(x, y int, mode SelectionMode) {
+	pos := uv.Pos(x, e.selectionLineAt(y))
+	e.sel = &selection{
+		Selection: Selection{Mode: mode, Start: pos, End: pos},
+		alt:       e.scr == &e.scrs[1],
+	}
+}
+
+// ExtendSelection 将当前选择的终点移动到视口位置 (x, y)。如果没有选择，
+// 则不执行任何操作。
+func (e *Emulator) ExtendSelection(x, y int) {
+	if e.sel == nil {
+		return
+	}
+	e.sel.End = uv.Pos(x, e.selectionLineAt(y))
+}
+
+// ClearSelection 清除当前选择。
+func (e *Emulator) ClearSelection() {
+	e.sel = nil
+}
+
+// Selection 返回当前选择。如果没有选择，则返回 false。
+func (e *Emulator) Selection() (Selection, bool) {
+	if e.sel == nil {
+		return Selection{}, false
+	}
+	return e.sel.Selection, true
+}
+
+// SetWordDelimiters 设置单词选择使用的分隔符。
+func (e *Emulator) SetWordDelimiters(delims string) {
+	e.wordDelims = delims
+}
+
+// IsSelected 报告视口位置 (x, y) 的单元格是否被选中。
+func (e *Emulator) IsSelected(x, y int) bool {
+	if !e.selectionVisible() {
+		return false
+	}
+	y = e.selectionLineAt(y)
+	if e.sel.Mode == SelectionBlock {
+		start, end := e.selectionBlock()
+		return x >= start.X && x <= end.X && y >= start.Y && y <= end.Y
+	}
+	start, end := e.selectionRange()
+	p := uv.Pos(x, y)
+	return !posBefore(p, start) && !posBefore(end, p)
+}
+
+// SelectedText 以纯文本形式返回选中的文本。软换行的行会被合并，行尾的空白
+// 会被删除。
+func (e *Emulator) SelectedText() string {
+	var b strings.Builder
+	e.selectedRows(func(_, _ int, line uv.Line, wrapped, last bool) {
+		var row strings.Builder
+		for _, c := range line {
+			if c.IsZero() {
+				continue
+			}
+			if c.Content == "" {
+				row.WriteByte(' ')
+			} else {
+				row.WriteString(c.Content)
+			}
+		}
+		if wrapped && !last {
+			b.WriteString(row.String())
+			return
+		}
+		b.WriteString(strings.TrimRight(row.String(), " "))
+		if !last {
+			b.WriteByte('\n')
+		}
+	})
+	return b.String()
+}
+
+// SelectedStyledText 返回选中的文本，样式和超链接编码为 ANSI 转义序列。每一
+// 行的末尾都会重置样式和超链接。
+func (e *Emulator) SelectedStyledText() string {
+	var b strings.Builder
+	e.selectedRows(func(_, _ int, line uv.Line, wrapped, last bool) {
+		b.WriteString(line.Render())
+		if wrapped && !last {
+			// Render 会删除行尾的空白，但软换行的行尾空白是文本的一部分。
+			n := len(line)
+			for n > 0 && line[n-1].Equal(&uv.EmptyCell) {
+				n--
+			}
+			b.WriteString(strings.Repeat(" ", len(line)-n))
+			return
+		}
+		if !last {
+			b.WriteByte('\n')
+		}
+	})
+	return b.String()
+}
+
+// SelectedCells 返回选择覆盖的单元格的副本。宽字符的后续单元格不包括在内。
+func (e *Emulator) SelectedCells() []SelectedCell {
+	var cells []SelectedCell
+	e.selectedRows(func(x, y int, line uv.Line, _, _ bool) {
+		for i, c := range line {
+			if c.IsZero() {
+				continue
+			}
+			cells = append(cells, SelectedCell{Position: uv.Pos(x+i, y), Cell: c})
+		}
+	})
+	return cells
+}
+
+// selectionLineAt 将视口的第 y 行转换为选择使用的行号。
+func (e *Emulator) selectionLineAt(y int) int {
+	if e.scr != &e.scrs[0] {
+		return y
+	}
+	return e.lineOffset - e.scrollOffset + y
+}
+
+// selectionVisible 报告是否存在属于当前屏幕的选择。
+func (e *Emulator) selectionVisible() bool {
+	return e.sel != nil && e.sel.alt == (e.scr == &e.scrs[1])
+}
+
+// selectionLine 返回选择使用的第 y 行及其软换行标志。如果该行不存在，则
+// 返回 nil。
+func (e *Emulator) selectionLine(y int) (uv.Line, bool) {
+	if !e.sel.alt {
+		return e.absoluteLine(y)
+	}
+	s := &e.scrs[1]
+	if y < 0 || y >= s.Height() {
+		return nil, false
+	}
+	return s.buf.Line(y), s.IsWrapped(y)
+}
+
+// selectionBlock 返回块选择的左上角和右下角（包含）。
+func (e *Emulator) selectionBlock() (start, end uv.Position) {
+	s := e.sel
+	start = uv.Pos(min(s.Start.X, s.End.X), min(s.Start.Y, s.End.Y))
+	end = uv.Pos(max(s.Start.X, s.End.X), max(s.Start.Y, s.End.Y))
+	return start, end
+}
+
+// selectionRange 返回线性、单词和行选择的起点和终点（包含），并根据选择模式
+// 进行扩展。
+func (e *Emulator) selectionRange() (start, end uv.Position) {
+	start, end = e.sel.Start, e.sel.End
+	if posBefore(end, start) {
+		start, end = end, start
+	}
+
+	switch e.sel.Mode {
+	case SelectionWord:
+		start, end = e.wordStart(start), e.wordEnd(end)
+	case SelectionLine:
+		for {
+			if line, wrapped := e.selectionLine(start.Y - 1); line == nil || !wrapped {
+				break
+			}
+			start.Y--
+		}
+		for {
+			line, wrapped := e.selectionLine(end.Y)
+			if next, _ := e.selectionLine(end.Y + 1); !wrapped || next == nil {
+				end.X = max(0, len(line)-1)
+				break
+			}
+			end.Y++
+		}
+		start.X = 0
+	}
+
+	// 从宽字符的后续单元格开始的选择包括整个字符。
+	if line, _ := e.selectionLine(start.Y); start.X < len(line) {
+		for start.X > 0 && line[start.X].IsZero() {
+			start.X--
+		}
+	}
+	return start, end
+}
+
+// selectedRows 对选择覆盖的每一行调用 fn，传入该行被选中的单元格及其起始列。wrapped
+// 报告该行是否以软换行结束并且选择延续到下一行，last 报告是否为最后一行。
+// 块选择从不合并行。
+func (e *Emulator) selectedRows(fn func(x, y int, line uv.Line, wrapped, last bool)) {
+	if !e.selectionVisible() {
+		return
+	}
+
+	var start, end uv.Position
+	block := e.sel.Mode == SelectionBlock
+	if block {
+		start, end = e.selectionBlock()
+	} else {
+		start, end = e.selectionRange()
+	}
+
+	for y := start.Y; y <= end.Y; y++ {
+		line, wrapped := e.selectionLine(y)
+		x0, x1 := 0, len(line)
+		if block {
+			// 块选择包括部分被覆盖的宽字符。
+			x0 = start.X
+			for x0 > 0 && x0 < len(line) && line[x0].IsZero() {
+				x0--
+			}
+		} else if y == start.Y {
+			x0 = start.X
+		}
+		if block || y == end.Y {
+			x1 = min(x1, end.X+1)
+		}
+		wrapped = wrapped && !block && x1 == len(line)
+		if x0 >= x1 {
+			line = nil
+		} else {
+			line = line[x0:x1]
+		}
+		fn(x0, y, line, wrapped, y == end.Y)
+	}
+}
+
+// wordClass 返回单元格的单词类别：0 表示空白，1 表示分隔符，2 表示单词字符。
+func (e *Emulator) wordClass(c *uv.Cell) int {
+	switch {
+	case c == nil || c.Content == "" || c.Content == " " || c.Content == "\t":
+		return 0
+	case strings.Contains(e.wordDelims, c.Content):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// wordCell 返回位置 p 处的单元格。宽字符的后续单元格返回该宽字符。
+func (e *Emulator) wordCell(p uv.Position) *uv.Cell {
+	line, _ := e.selectionLine(p.Y)
+	for p.X > 0 && p.X < len(line) && line[p.X].IsZero() {
+		p.X--
+	}
+	return line.At(p.X)
+}
+
+// wordStart 将 p 向左扩展到单词的开始。单词可以跨越软换行。
+func (e *Emulator) wordStart(p uv.Position) uv.Position {
+	class := e.wordClass(e.wordCell(p))
+	if class == 1 {
+		return p
+	}
+	for {
+		q := p
+		if q.X > 0 {
+			q.X--
+		} else {
+			line, wrapped := e.selectionLine(q.Y - 1)
+			if line == nil || !wrapped {
+				return p
+			}
+			q = uv.Pos(len(line)-1, q.Y-1)
+		}
+		if e.wordClass(e.wordCell(q)) != class {
+			return p
+		}
+		p = q
+	}
+}
+
+// wordEnd 将 p 向右扩展到单词的结束。单词可以跨越软换行。
+func (e *Emulator) wordEnd(p uv.Position) uv.Position {
+	class := e.wordClass(e.wordCell(p))
+	if class == 1 {
+		return p
+	}
+	for {
+		q := p
+		line, wrapped := e.selectionLine(q.Y)
+		if q.X < len(line)-1 {
+			q.X++
+		} else {
+			if next, _ := e.selectionLine(q.Y + 1); !wrapped || next == nil {
+				return p
+			}
+			q = uv.Pos(0, q.Y+1)
+		}
+		if e.wordClass(e.wordCell(q)) != class {
+			return p
+		}
+		p = q
+	}
+}
+
+// shiftSelection 在当前屏幕第 y 行及其下方的滚动区域内容垂直移动 n 行时
+// 调整选择。完全位于移动内容之内的选择随内容一起移动；与移动内容部分重叠或
+// 被移出滚动区域的选择会被清除。
+func (e *Emulator) shiftSelection(y, n int) {
+	if !e.selectionVisible() {
+		return
+	}
+
+	base := 0
+	if !e.sel.alt {
+		base = e.lineOffset
+	}
+	top := min(e.sel.Start.Y, e.sel.End.Y) - base
+	bottom := max(e.sel.Start.Y, e.sel.End.Y) - base
+
+	scroll := e.scr.ScrollRegion()
+	if bottom < y || top >= scroll.Max.Y {
+		return
+	}
+	if top < y || bottom >= scroll.Max.Y || top+n < y || bottom+n >= scroll.Max.Y ||
+		scroll.Min.X != 0 || scroll.Max.X != e.scr.Width() {
+		e.sel = nil
+		return
+	}
+	e.sel.Start.Y += n
+	e.sel.End.Y += n
+}
+
+// pruneSelection 清除已经不在回滚缓冲区中的选择。
+func (e *Emulator) pruneSelection() {
+	if e.sel == nil || e.sel.alt {
+		return
+	}
+	if min(e.sel.Start.Y, e.sel.End.Y) < e.lineOffset-e.scrollback.Len() {
+		e.sel = nil
+	}
+}
+
+// posBefore 报告 a 是否按阅读顺序位于 b 之前。
+func posBefore(a, b uv.Position) bool {
+	return a.Y < b.Y || (a.Y == b.Y && a.X < b.X)
+}
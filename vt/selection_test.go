@@ -0,0 +1,134 @@
+package vt
+
+import (
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+func TestSelectionLinear(t *testing.T) {
+	term := newTestTerminal(t, 10, 4)
+	term.WriteString("hello world\r\nfoo\r\nbar")
+
+	// "hello worl" wraps onto "d".
+	term.StartSelection(6, 0, SelectionLinear)
+	term.ExtendSelection(1, 3)
+	if got, want := term.SelectedText(), "world\nfoo\nba"; got != want {
+		t.Errorf("SelectedText() = %q, want %q", got, want)
+	}
+	if !term.IsSelected(0, 1) || term.IsSelected(5, 0) || term.IsSelected(2, 3) {
+		t.Error("IsSelected does not match the selection")
+	}
+
+	// Selecting backwards gives the same text.
+	term.StartSelection(1, 3, SelectionLinear)
+	term.ExtendSelection(6, 0)
+	if got, want := term.SelectedText(), "world\nfoo\nba"; got != want {
+		t.Errorf("backwards SelectedText() = %q, want %q", got, want)
+	}
+}
+
+func TestSelectionBlock(t *testing.T) {
+	term := newTestTerminal(t, 10, 3)
+	term.WriteString("abcdef\r\nghijkl\r\nmnopqr")
+
+	term.StartSelection(4, 2, SelectionBlock)
+	term.ExtendSelection(1, 0)
+	if got, want := term.SelectedText(), "bcde\nhijk\nnopq"; got != want {
+		t.Errorf("SelectedText() = %q, want %q", got, want)
+	}
+	if got := len(term.SelectedCells()); got != 12 {
+		t.Errorf("len(SelectedCells()) = %d, want 12", got)
+	}
+}
+
+func TestSelectionWordAndLine(t *testing.T) {
+	term := newTestTerminal(t, 10, 3)
+	term.WriteString("ls (/usr/bin) x")
+
+	term.StartSelection(6, 0, SelectionWord)
+	if got, want := term.SelectedText(), "/usr/bin"; got != want {
+		t.Errorf("word SelectedText() = %q, want %q", got, want)
+	}
+
+	term.StartSelection(3, 0, SelectionWord)
+	if got, want := term.SelectedText(), "("; got != want {
+		t.Errorf("delimiter SelectedText() = %q, want %q", got, want)
+	}
+
+	// Line selection spans the soft-wrapped logical line.
+	term.StartSelection(1, 1, SelectionLine)
+	if got, want := term.SelectedText(), "ls (/usr/bin) x"; got != want {
+		t.Errorf("line SelectedText() = %q, want %q", got, want)
+	}
+}
+
+func TestSelectionWideCells(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	term.WriteString("a你好b")
+
+	// Start on the second half of "你".
+	term.StartSelection(2, 0, SelectionLinear)
+	term.ExtendSelection(3, 0)
+	if got, want := term.SelectedText(), "你好"; got != want {
+		t.Errorf("SelectedText() = %q, want %q", got, want)
+	}
+	cells := term.SelectedCells()
+	if len(cells) != 2 || cells[0].Position != uv.Pos(1, 0) || cells[1].Position != uv.Pos(3, 0) {
+		t.Errorf("SelectedCells() = %+v", cells)
+	}
+}
+
+func TestSelectionStyledText(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	term.WriteString("a\x1b[1mb\x1b[m\x1b]8;;http://x\x07c\x1b]8;;\x07")
+
+	term.StartSelection(0, 0, SelectionLinear)
+	term.ExtendSelection(9, 0)
+	want := "a\x1b[1mb\x1b[m\x1b]8;;http://x\x07c\x1b]8;;\x07"
+	if got := term.SelectedStyledText(); got != want {
+		t.Errorf("SelectedStyledText() = %q, want %q", got, want)
+	}
+}
+
+func TestSelectionScrolling(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	term.WriteString("one\r\ntwo")
+
+	term.StartSelection(0, 1, SelectionLine)
+	term.WriteString("\r\nthree\r\nfour")
+
+	// The selected line scrolled into the scrollback but is still selected.
+	if got := term.SelectedText(); got != "two" {
+		t.Errorf("SelectedText() after scroll = %q", got)
+	}
+	if term.IsSelected(0, 0) {
+		t.Error("IsSelected should follow the content")
+	}
+	term.ScrollViewport(2)
+	if !term.IsSelected(0, 1) {
+		t.Error("IsSelected should match in the scrollback")
+	}
+
+	// Content dropped from the scrollback clears the selection.
+	term.SetScrollbackSize(0)
+	if _, ok := term.Selection(); ok {
+		t.Error("selection should be cleared when its content is dropped")
+	}
+
+	// Scrolling a region moves the selection with the content.
+	term = newTestTerminal(t, 10, 4)
+	term.WriteString("a\r\nb\r\nc\r\nd")
+	term.StartSelection(0, 2, SelectionLinear)
+	term.WriteString("\x1b[2;4r\x1b[2;1H\x1b[L")
+	if got := term.SelectedText(); got != "c" {
+		t.Errorf("SelectedText() after IL = %q", got)
+	}
+	if !term.IsSelected(0, 3) {
+		t.Error("selection should move with the inserted line")
+	}
+	term.WriteString("\x1b[3S")
+	if _, ok := term.Selection(); ok {
+		t.Error("selection scrolled out of the region should be cleared")
+	}
+}
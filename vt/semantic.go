@@ -0,0 +1,231 @@
+package vt
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	uv "github.com/charmbracelet/ultraviolet"
+)
+
+// Command 表示通过 shell 集成（OSC 133）标记的一个命令。它由提示符、输入和
+// 输出三个区域组成。
+//
+// 位置的 Y 是绝对行号：0 是主屏幕历史中的第一行，并且行号在内容滚动到回滚
+// 缓冲区时保持不变。使用 [Emulator.AbsoluteLine] 将屏幕行转换为绝对行号。
+// 未报告的位置的 Y 为 -1。
+type Command struct {
+	// Prompt 是提示符的开始位置 (OSC 133 ; A)。
+	Prompt uv.Position
+	// Input 是命令输入的开始位置，即提示符的结束位置 (OSC 133 ; B)。
+	Input uv.Position
+	// Output 是命令输出的开始位置 (OSC 133 ; C)。
+	Output uv.Position
+	// End 是命令的结束位置 (OSC 133 ; D)。
+	End uv.Position
+
+	// ExitCode 是命令的退出码。如果命令尚未结束或没有报告退出码，则为 -1。
+	ExitCode int
+
+	// Cwd 是命令运行时通过 OSC 7 报告的工作目录。
+	Cwd string
+}
+
+// Finished 报告命令是否已经结束。
+func (c Command) Finished() bool {
+	return c.End.Y >= 0
+}
+
+// noPosition 表示未报告的位置。
+var noPosition = uv.Pos(-1, -1)
+
+// maxCommands 是保留的命令的最大数量。
+const maxCommands = 1000
+
+// Commands 返回通过 shell 集成标记的命令，按从旧到新排列。最后一个命令可能
+// 仍在进行中。
+func (e *Emulator) Commands() []Command {
+	cmds := make([]Command, len(e.commands))
+	copy(cmds, e.commands)
+	return cmds
+}
+
+// AbsoluteLine 返回主屏幕第 y 行的绝对行号。
+func (e *Emulator) AbsoluteLine(y int) int {
+	return e.lineOffset + y
+}
+
+// CommandOutput 返回第 i 个命令的输出文本。软换行的行会被合并，行尾的空白会
+// 被删除。对于仍在运行的命令，输出截至当前光标位置。如果命令没有输出区域或
+// 其输出已经不在回滚缓冲区中，则返回空字符串。
+func (e *Emulator) CommandOutput(i int) string {
+	if i < 0 || i >= len(e.commands) {
+		return ""
+	}
+
+	cmd := e.commands[i]
+	if cmd.Output.Y < 0 {
+		return ""
+	}
+
+	end := cmd.End
+	if end.Y < 0 {
+		end = e.scrs[0].cur.Position
+		end.Y = e.AbsoluteLine(end.Y)
+	}
+	return e.absoluteText(cmd.Output, end)
+}
+
+// ScrollToCommand 滚动视口，使第 i 个命令的提示符位于视口顶部。
+func (e *Emulator) ScrollToCommand(i int) {
+	if i < 0 || i >= len(e.commands) {
+		return
+	}
+	e.setScrollOffset(e.lineOffset - e.commands[i].Prompt.Y)
+	e.flushDamage()
+}
+
+// absoluteLine 返回给定绝对行号的行及其软换行标志。如果该行不在回滚缓冲区
+// 或主屏幕中，则返回 nil。
+func (e *Emulator) absoluteLine(y int) (uv.Line, bool) {
+	y -= e.lineOffset
+	s := &e.scrs[0]
+	if y >= 0 {
+		if y >= s.Height() {
+			return nil, false
+		}
+		return s.buf.Line(y), s.IsWrapped(y)
+	}
+	i := e.scrollback.Len() + y
+	return e.scrollback.Line(i), e.scrollback.IsWrapped(i)
+}
+
+// absoluteText 返回从 start 到 end（不含）的文本，位置使用绝对行号。
+func (e *Emulator) absoluteText(start, end uv.Position) string {
+	var b strings.Builder
+	for y := start.Y; y <= end.Y; y++ {
+		line, wrapped := e.absoluteLine(y)
+		x0, x1 := 0, len(line)
+		if y == start.Y {
+			x0 = start.X
+		}
+		if y == end.Y {
+			x1 = min(x1, end.X)
+		}
+
+		var row strings.Builder
+		for x := x0; x < x1; x++ {
+			c := line[x]
+			if c.IsZero() {
+				continue
+			}
+			if c.Content == "" {
+				row.WriteByte(' ')
+			} else {
+				row.WriteString(c.Content)
+			}
+		}
+
+		if wrapped && y < end.Y {
+			b.WriteString(row.String())
+			continue
+		}
+		b.WriteString(strings.TrimRight(row.String(), " "))
+		if y < end.Y {
+			b.WriteByte('\n')
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// handleSemanticPrompt 处理 shell 集成的 OSC 133 序列。
+//
+//	OSC 133 ; A ST  提示符开始
+//	OSC 133 ; B ST  命令输入开始
+//	OSC 133 ; C ST  命令输出开始
+//	OSC 133 ; D [; exitcode] ST  命令结束
+//
+// 只有主屏幕上的标记会被记录。
+func (e *Emulator) handleSemanticPrompt(cmd int, data []byte) {
+	parts := bytes.Split(data, []byte{';'})
+	if len(parts) < 2 || len(parts[1]) == 0 || cmd != 133 {
+		// Invalid, ignore
+		return
+	}
+	if e.scr != &e.scrs[0] {
+		return
+	}
+
+	pos := e.scr.cur.Position
+	pos.Y = e.AbsoluteLine(pos.Y)
+	var cur *Command
+	if n := len(e.commands); n > 0 && !e.commands[n-1].Finished() {
+		cur = &e.commands[n-1]
+	}
+
+	switch parts[1][0] {
+	case 'A':
+		if cur != nil {
+			// 新的提示符结束之前的命令。
+			cur.End = pos
+		}
+		e.commands = append(e.commands, Command{
+			Prompt:   pos,
+			Input:    noPosition,
+			Output:   noPosition,
+			End:      noPosition,
+			ExitCode: -1,
+			Cwd:      e.cwd,
+		})
+		e.pruneCommands()
+	case 'B':
+		if cur != nil {
+			cur.Input = pos
+		}
+	case 'C':
+		if cur != nil {
+			cur.Output = pos
+			cur.Cwd = e.cwd
+		}
+	case 'D':
+		if cur != nil {
+			cur.End = pos
+			if len(parts) > 2 {
+				if code, err := strconv.Atoi(string(parts[2])); err == nil {
+					cur.ExitCode = code
+				}
+			}
+		}
+	}
+}
+
+// commandMarks 返回所有命令中已报告的位置，用于在重新换行时移动它们。
+func (e *Emulator) commandMarks() []*uv.Position {
+	var marks []*uv.Position
+	for i := range e.commands {
+		c := &e.commands[i]
+		for _, p := range []*uv.Position{&c.Prompt, &c.Input, &c.Output, &c.End} {
+			if p.Y >= 0 {
+				marks = append(marks, p)
+			}
+		}
+	}
+	return marks
+}
+
+// pruneCommands 删除已经完全滚出回滚缓冲区的命令，并限制命令的数量。
+func (e *Emulator) pruneCommands() {
+	first := e.lineOffset - e.scrollback.Len()
+	n := 0
+	for n < len(e.commands)-1 {
+		c := e.commands[n]
+		if len(e.commands)-n <= maxCommands && (!c.Finished() || c.End.Y >= first) {
+			break
+		}
+		n++
+	}
+	if n > 0 {
+		e.commands = append(e.commands[:0], e.commands[n:]...)
+	}
+}
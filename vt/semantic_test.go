@@ -0,0 +1,53 @@
+package vt
+
+import (
+	"testing"
+
+	"github.com/purpose168/charm-experimental-packages-cn/ansi"
+)
+
+func TestSemanticPrompt(t *testing.T) {
+	term := newTestTerminal(t, 10, 3)
+	run := func(cmd, output string, code string) {
+		term.WriteString(ansi.FinalTermPrompt() + "$ " + ansi.FinalTermCmdStart() + cmd + "\r\n")
+		term.WriteString(ansi.FinalTermCmdExecuted() + output + ansi.FinalTermCmdFinished(code))
+	}
+
+	term.WriteString("\x1b]7;file://host/tmp\x07")
+	run("ls", "a.txt\r\nbbbbbbbbbbbb\r\n", "0")
+	term.WriteString("\x1b]7;file://host/home\x07")
+	run("false", "", "1")
+	term.WriteString(ansi.FinalTermPrompt() + "$ " + ansi.FinalTermCmdStart())
+
+	cmds := term.Commands()
+	if len(cmds) != 3 {
+		t.Fatalf("expected 3 commands, got %d", len(cmds))
+	}
+
+	first := cmds[0]
+	if first.Prompt.Y != 0 || first.Input.X != 2 || first.Output.Y != 1 || first.End.Y != 4 {
+		t.Errorf("first command zones = %+v", first)
+	}
+	if first.ExitCode != 0 || first.Cwd != "file://host/tmp" || !first.Finished() {
+		t.Errorf("first command = %+v", first)
+	}
+	if got, want := term.CommandOutput(0), "a.txt\nbbbbbbbbbbbb"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	if cmds[1].ExitCode != 1 || cmds[1].Cwd != "file://host/home" {
+		t.Errorf("second command = %+v", cmds[1])
+	}
+	if got := term.CommandOutput(1); got != "" {
+		t.Errorf("second output = %q", got)
+	}
+	if cmds[2].Finished() || cmds[2].Output.Y != -1 {
+		t.Errorf("third command = %+v", cmds[2])
+	}
+
+	// Jump to the first prompt, which is now in the scrollback.
+	term.ScrollToCommand(0)
+	if got := term.ScrollOffset(); got != term.AbsoluteLine(0) {
+		t.Errorf("scroll offset = %d, want %d", got, term.AbsoluteLine(0))
+	}
+}
@@ -0,0 +1,68 @@
+package vt
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/purpose168/charm-experimental-packages-cn/ansi"
+	"github.com/purpose168/charm-experimental-packages-cn/ansi/sixel"
+)
+
+// handleSixel 解码 Sixel 图像并将其放置在光标位置。与启用了 Sixel 滚动的
+// xterm 一样，光标随后移动到图像下方的下一行，必要时滚动屏幕，并保持在图像
+// 的起始列。
+//
+// DCS 参数 P2 选择未设置的像素的颜色：1 表示透明，0 和 2 表示使用当前的背景
+// 色。
+func (e *Emulator) handleSixel(params ansi.Params, data []byte) bool {
+	var dec sixel.Decoder
+	img, err := dec.Decode(bytes.NewReader(data))
+	if err != nil || img == nil {
+		e.logf("无效的 Sixel 图像: %v", err)
+		return false
+	}
+
+	size := img.Bounds().Size()
+	if size.X <= 0 || size.Y <= 0 {
+		return true
+	}
+
+	if p2, _, _ := params.Param(1, 0); p2 != 1 {
+		opaque := image.NewRGBA(img.Bounds())
+		draw.Draw(opaque, opaque.Bounds(), image.NewUniform(e.sixelBackground()), image.Point{}, draw.Src)
+		draw.Draw(opaque, opaque.Bounds(), img, img.Bounds().Min, draw.Over)
+		img = opaque
+	}
+
+	x, y := e.scr.CursorPosition()
+	cols, rows := e.imageCells(size.X, size.Y)
+	e.scr.addImage(ImagePlacement{
+		Image:  img,
+		Bounds: uv.Rect(x, y, cols, rows),
+	})
+
+	for range rows {
+		e.index()
+	}
+	e.scr.setCursorX(x, false)
+
+	return true
+}
+
+// sixelBackground 返回不透明的 Sixel 图像中未设置的像素使用的颜色，即光标的
+// 背景色。
+func (e *Emulator) sixelBackground() color.Color {
+	switch c := e.scr.cursorPen().Bg.(type) {
+	case nil:
+		return e.BackgroundColor()
+	case ansi.BasicColor:
+		return e.IndexedColor(int(c))
+	case ansi.IndexedColor:
+		return e.IndexedColor(int(c))
+	default:
+		return c
+	}
+}
@@ -0,0 +1,134 @@
+package vt
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/purpose168/charm-experimental-packages-cn/ansi"
+	"github.com/purpose168/charm-experimental-packages-cn/ansi/sixel"
+)
+
+// sixelImage 返回一个编码给定大小的 Sixel 图像的 DCS 序列。
+func sixelImage(t *testing.T, width, height int) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+
+	var enc sixel.Encoder
+	var buf bytes.Buffer
+	if err := enc.Encode(&buf, img); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return ansi.SixelGraphics(0, 1, 0, buf.Bytes())
+}
+
+func TestSixel(t *testing.T) {
+	term := newTestTerminal(t, 10, 5)
+	term.SetCellSize(10, 20)
+	term.WriteString("ab")
+	term.WriteString(sixelImage(t, 25, 30))
+
+	placements := term.ImagePlacements()
+	if len(placements) != 1 {
+		t.Fatalf("expected 1 placement, got %d", len(placements))
+	}
+	if want := uv.Rect(2, 0, 3, 2); placements[0].Bounds != want {
+		t.Errorf("bounds = %v, want %v", placements[0].Bounds, want)
+	}
+	if size := placements[0].Image.Bounds().Size(); size != image.Pt(25, 30) {
+		t.Errorf("image size = %v", size)
+	}
+	if x, y := term.scr.CursorPosition(); x != 2 || y != 2 {
+		t.Errorf("cursor = (%d, %d), want (2, 2)", x, y)
+	}
+
+	// The image scrolls along with the text.
+	term.WriteString("\x1b[5;1H\n")
+	placements = term.ImagePlacements()
+	if len(placements) != 1 {
+		t.Fatalf("expected 1 placement after scroll, got %d", len(placements))
+	}
+	if want := uv.Rect(2, -1, 3, 2); placements[0].Bounds != want {
+		t.Errorf("bounds after scroll = %v, want %v", placements[0].Bounds, want)
+	}
+
+	// The viewport follows the scrollback.
+	term.ScrollViewport(1)
+	if got := term.ImagePlacements(); len(got) != 1 || got[0].Bounds.Min.Y != 0 {
+		t.Errorf("placements in viewport = %v", got)
+	}
+	term.ScrollViewport(-1)
+
+	// Once scrolled off the screen, the image is dropped.
+	term.WriteString("\n")
+	if got := term.ImagePlacements(); len(got) != 0 {
+		t.Errorf("expected no placements, got %v", got)
+	}
+	if len(term.scr.images) != 0 {
+		t.Errorf("expected image to be removed, got %d", len(term.scr.images))
+	}
+}
+
+func TestSixelClear(t *testing.T) {
+	term := newTestTerminal(t, 10, 5)
+	term.WriteString(sixelImage(t, 10, 20))
+	if got := len(term.ImagePlacements()); got != 1 {
+		t.Fatalf("expected 1 placement, got %d", got)
+	}
+
+	term.WriteString("\x1b[2J")
+	if got := len(term.ImagePlacements()); got != 0 {
+		t.Errorf("expected no placements after ED, got %d", got)
+	}
+}
+
+func TestSixelBackground(t *testing.T) {
+	// A 2x6 image where only the first column is set.
+	const data = "\"1;1;2;6#1;2;100;0;0#1~"
+	cases := []struct {
+		name  string
+		p2    int
+		alpha uint32
+	}{
+		{"opaque", 0, 0xffff},
+		{"transparent", 1, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			term := newTestTerminal(t, 10, 5)
+			term.WriteString(ansi.SixelGraphics(0, tc.p2, 0, []byte(data)))
+			placements := term.ImagePlacements()
+			if len(placements) != 1 {
+				t.Fatalf("expected 1 placement, got %d", len(placements))
+			}
+			img := placements[0].Image
+			if _, _, _, a := img.At(0, 0).RGBA(); a != 0xffff {
+				t.Errorf("set pixel alpha = %#x", a)
+			}
+			if _, _, _, a := img.At(1, 0).RGBA(); a != tc.alpha {
+				t.Errorf("unset pixel alpha = %#x, want %#x", a, tc.alpha)
+			}
+		})
+	}
+}
+
+func TestSixelReflow(t *testing.T) {
+	term := newTestTerminal(t, 10, 5)
+	term.SetCellSize(10, 20)
+	term.WriteString("0123456789ab")
+	term.WriteString(sixelImage(t, 25, 30))
+	if got := term.ImagePlacements(); len(got) != 1 || got[0].Bounds != uv.Rect(2, 1, 3, 2) {
+		t.Fatalf("placements = %v", got)
+	}
+
+	// The wrapped line is joined, and the image moves with the cell it was
+	// placed at.
+	term.Resize(20, 5)
+	if got := term.ImagePlacements(); len(got) != 1 || got[0].Bounds != uv.Rect(12, 0, 3, 2) {
+		t.Errorf("placements after reflow = %v", got)
+	}
+}
@@ -0,0 +1,774 @@
+package vt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/purpose168/charm-experimental-packages-cn/ansi"
+	"github.com/purpose168/charm-experimental-packages-cn/exp/ordered"
+)
+
+// SnapshotVersion 是 [Snapshot] 格式的当前版本。
+const SnapshotVersion = 1
+
+var (
+	// ErrSnapshotVersion 在快照的版本不受支持时返回。
+	ErrSnapshotVersion = errors.New("vt: unsupported snapshot version")
+	// ErrSnapshotFormat 在快照的内容无效时返回。
+	ErrSnapshotFormat = errors.New("vt: invalid snapshot")
+)
+
+// snapshotMagic 是二进制快照的前缀，其后是版本号和 gob 编码的快照。
+const snapshotMagic = "VTSNAP"
+
+// snapshotData 与 [Snapshot] 相同，但没有方法，以免 gob 调用
+// [Snapshot.MarshalBinary] 编码自身。
+type snapshotData Snapshot
+
+// Snapshot 是模拟器完整状态的快照，用于在分离会话后重新创建终端。
+//
+// 快照包括两个屏幕及其单元格、光标和保存的光标、主屏幕的回滚缓冲区、模式、
+// 字符集及调用到 GL 和 GR 的字符集、制表位、滚动边距、调色板和默认颜色、标题、
+// 工作目录和超链接。日志器、回调、选择、图像和 shell 集成标记不属于快照。
+//
+// 快照可以用 encoding/json 编码，也可以用 [Snapshot.MarshalBinary] 编码为带
+// 版本的二进制格式，然后用 [Emulator.Restore] 恢复；[Snapshot.WriteTo] 生成在
+// 真实终端上重新创建它的字节流。
+type Snapshot struct {
+	// Version 是快照格式的版本，参见 [SnapshotVersion]。
+	Version int `json:"version"`
+	// Width 和 Height 是终端的大小。
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// AltScreen 报告交替屏幕是否处于活动状态。
+	AltScreen bool `json:"alt_screen,omitempty"`
+	// Screens 是主屏幕和交替屏幕。
+	Screens [2]ScreenSnapshot `json:"screens"`
+	// Scrollback 是主屏幕的回滚缓冲区，按从旧到新排列。
+	Scrollback []LineSnapshot `json:"scrollback,omitempty"`
+	// LineOffset 是从主屏幕顶部滚出的总行数，用于计算绝对行号。
+	LineOffset int `json:"line_offset,omitempty"`
+	// Phantom 报告光标是否越过了最后一列，下一个字符会先换行。
+	Phantom bool `json:"phantom,omitempty"`
+
+	// Modes 是模式及其设置，按 ANSI 模式在前、模式编号递增排列。
+	Modes []ModeSnapshot `json:"modes"`
+	// Charsets 是 G0 到 G3 的字符集，用 SCS 序列的指示符表示，例如 "B"
+	// 表示 USASCII，"0" 表示 DEC 特殊图形，"%5" 表示 DEC 补充图形。96 字符集
+	// 的指示符以 "-" 开头，例如 "-A" 表示 ISO Latin-1 补充字符集。
+	Charsets [4]string `json:"charsets"`
+	// GL 和 GR 是调用到 GL 和 GR 的字符集的编号。
+	GL int `json:"gl"`
+	GR int `json:"gr"`
+	// TabStops 是制表位所在的列，从零开始。
+	TabStops []int `json:"tab_stops"`
+	// ModifyOtherKeys 是 XTerm modifyOtherKeys 的级别。
+	ModifyOtherKeys int `json:"modify_other_keys,omitempty"`
+
+	// 颜色使用 "#rrggbb" 格式，空字符串表示未设置。Palette 只包含被 OSC 4
+	// 修改过的索引颜色。
+	Palette            map[int]string `json:"palette,omitempty"`
+	SpecialColors      [5]string      `json:"special_colors"`
+	Foreground         string         `json:"foreground,omitempty"`
+	Background         string         `json:"background,omitempty"`
+	CursorColor        string         `json:"cursor_color,omitempty"`
+	DefaultForeground  string         `json:"default_foreground,omitempty"`
+	DefaultBackground  string         `json:"default_background,omitempty"`
+	DefaultCursorColor string         `json:"default_cursor_color,omitempty"`
+
+	// Title、IconName 和 Cwd 是窗口标题、图标名称和报告的工作目录。
+	Title    string `json:"title,omitempty"`
+	IconName string `json:"icon_name,omitempty"`
+	Cwd      string `json:"cwd,omitempty"`
+}
+
+// ScreenSnapshot 是一个屏幕的快照。
+type ScreenSnapshot struct {
+	// Lines 是屏幕的行，从上到下排列。
+	Lines []LineSnapshot `json:"lines"`
+	// Cursor 和 SavedCursor 是光标和 DECSC 保存的光标。
+	Cursor      CursorSnapshot `json:"cursor"`
+	SavedCursor CursorSnapshot `json:"saved_cursor"`
+	// 滚动区域的边距，从零开始，Bottom 和 Right 不包含在区域内。
+	ScrollTop    int `json:"scroll_top"`
+	ScrollBottom int `json:"scroll_bottom"`
+	ScrollLeft   int `json:"scroll_left"`
+	ScrollRight  int `json:"scroll_right"`
+	// KittyFlags 是 Kitty 键盘协议的渐进增强标志堆栈。
+	KittyFlags []int `json:"kitty_flags,omitempty"`
+}
+
+// LineSnapshot 是一行的快照。行尾的空白单元格会被省略。
+type LineSnapshot struct {
+	Cells []CellSnapshot `json:"cells,omitempty"`
+	// Wrapped 报告该行是否以自动换行（软换行）结束。
+	Wrapped bool `json:"wrapped,omitempty"`
+	// Attr 是该行的尺寸属性。回滚缓冲区中的行总是单宽的。
+	Attr LineAttr `json:"attr,omitempty"`
+}
+
+// CellSnapshot 是一个单元格的快照。宽字符之后的占位单元格的 Content 为空，
+// Width 为零。
+type CellSnapshot struct {
+	Content string `json:"content,omitempty"`
+	Width   int    `json:"width,omitempty"`
+	// Style 是单元格样式的 SGR 序列，空字符串表示默认样式。
+	Style string `json:"style,omitempty"`
+	// Link 和 LinkParams 是单元格超链接的 URL 和参数。
+	Link       string `json:"link,omitempty"`
+	LinkParams string `json:"link_params,omitempty"`
+}
+
+// CursorSnapshot 是一个光标的快照。
+type CursorSnapshot struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	// Pen 是光标样式的 SGR 序列，空字符串表示默认样式。
+	Pen string `json:"pen,omitempty"`
+	// Link 和 LinkParams 是光标超链接的 URL 和参数。
+	Link       string      `json:"link,omitempty"`
+	LinkParams string      `json:"link_params,omitempty"`
+	Style      CursorStyle `json:"style,omitempty"`
+	Steady     bool        `json:"steady,omitempty"`
+	Hidden     bool        `json:"hidden,omitempty"`
+}
+
+// ModeSnapshot 是一个模式的设置。
+type ModeSnapshot struct {
+	Mode int `json:"mode"`
+	// DEC 报告该模式是 DEC 私有模式还是 ANSI 模式。
+	DEC     bool             `json:"dec,omitempty"`
+	Setting ansi.ModeSetting `json:"setting"`
+}
+
+// mode 返回快照对应的模式。
+func (m ModeSnapshot) mode() ansi.Mode {
+	if m.DEC {
+		return ansi.DECMode(m.Mode)
+	}
+	return ansi.ANSIMode(m.Mode)
+}
+
+// MarshalBinary 将快照编码为带版本的二进制格式。它实现
+// [encoding.BinaryMarshaler]。
+func (s *Snapshot) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(snapshotMagic)
+	buf.Write(binary.AppendUvarint(nil, uint64(s.Version))) //nolint:gosec
+	if err := gob.NewEncoder(&buf).Encode((*snapshotData)(s)); err != nil {
+		return nil, fmt.Errorf("vt: encoding snapshot: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary 解码由 [Snapshot.MarshalBinary] 编码的快照。它实现
+// [encoding.BinaryUnmarshaler]。
+func (s *Snapshot) UnmarshalBinary(data []byte) error {
+	rest, ok := bytes.CutPrefix(data, []byte(snapshotMagic))
+	if !ok {
+		return ErrSnapshotFormat
+	}
+	version, n := binary.Uvarint(rest)
+	if n <= 0 {
+		return ErrSnapshotFormat
+	}
+	if version != SnapshotVersion {
+		return fmt.Errorf("%w: %d", ErrSnapshotVersion, version)
+	}
+
+	var snap snapshotData
+	if err := gob.NewDecoder(bytes.NewReader(rest[n:])).Decode(&snap); err != nil {
+		return fmt.Errorf("%w: %w", ErrSnapshotFormat, err)
+	}
+	*s = Snapshot(snap)
+	return nil
+}
+
+// Snapshot 返回模拟器当前状态的快照。
+func (e *Emulator) Snapshot() *Snapshot {
+	s := &Snapshot{
+		Version:            SnapshotVersion,
+		Width:              e.Width(),
+		Height:             e.Height(),
+		AltScreen:          e.scr == &e.scrs[1],
+		LineOffset:         e.lineOffset,
+		Phantom:            e.atPhantom,
+		GL:                 e.gl,
+		GR:                 e.gr,
+		TabStops:           tabStopList(e.tabstops, e.Width()),
+		ModifyOtherKeys:    e.modifyOtherKeys,
+		Foreground:         colorString(e.fgColor),
+		Background:         colorString(e.bgColor),
+		CursorColor:        colorString(e.curColor),
+		DefaultForeground:  colorString(e.defaultFg),
+		DefaultBackground:  colorString(e.defaultBg),
+		DefaultCursorColor: colorString(e.defaultCur),
+		Title:              e.title,
+		IconName:           e.iconName,
+		Cwd:                e.cwd,
+	}
+
+	for i := range e.scrs {
+		s.Screens[i] = snapshotScreen(&e.scrs[i])
+	}
+	for i := range e.scrollback.Len() {
+		s.Scrollback = append(s.Scrollback, snapshotLine(e.scrollback.Line(i), e.scrollback.IsWrapped(i)))
+	}
+
+	for mode, setting := range e.modes {
+		_, dec := mode.(ansi.DECMode)
+		s.Modes = append(s.Modes, ModeSnapshot{Mode: mode.Mode(), DEC: dec, Setting: setting})
+	}
+	slices.SortFunc(s.Modes, func(a, b ModeSnapshot) int {
+		if a.DEC != b.DEC {
+			if a.DEC {
+				return 1
+			}
+			return -1
+		}
+		return a.Mode - b.Mode
+	})
+
+	for i, cs := range e.charsets {
+		d, is96 := charsetDesignator(cs)
+		if is96 {
+			d = "-" + d
+		}
+		s.Charsets[i] = d
+	}
+	for i, c := range e.colors {
+		if c != nil {
+			if s.Palette == nil {
+				s.Palette = map[int]string{}
+			}
+			s.Palette[i] = colorString(c)
+		}
+	}
+	for i, c := range e.specialColors {
+		s.SpecialColors[i] = colorString(c)
+	}
+
+	return s
+}
+
+// Restore 将模拟器恢复到快照的状态。模拟器会被调整为快照的大小，当前的屏幕、
+// 回滚缓冲区和其他状态都会被替换。日志器、回调和其他配置保持不变。
+func (e *Emulator) Restore(s *Snapshot) error {
+	if s.Version != SnapshotVersion {
+		return fmt.Errorf("%w: %d", ErrSnapshotVersion, s.Version)
+	}
+	if s.Width <= 0 || s.Height <= 0 {
+		return fmt.Errorf("%w: size %dx%d", ErrSnapshotFormat, s.Width, s.Height)
+	}
+
+	e.Resize(s.Width, s.Height)
+	e.fullReset()
+	e.scrollback.Clear()
+	e.scrollOffset = 0
+
+	sp := newStyleParser()
+	for i := range e.scrs {
+		restoreScreen(&e.scrs[i], &s.Screens[i], sp)
+	}
+	e.scr = &e.scrs[0]
+	if s.AltScreen {
+		e.scr = &e.scrs[1]
+	}
+	for _, l := range s.Scrollback {
+		e.scrollback.push(restoreLine(l, s.Width, sp), l.Wrapped)
+	}
+	e.lineOffset = max(s.LineOffset, e.scrollback.Len())
+	e.atPhantom = s.Phantom
+
+	e.modes = defaultModes()
+	for _, m := range s.Modes {
+		e.modes[m.mode()] = m.Setting
+	}
+	for i, d := range s.Charsets {
+		e.charsets[i], _ = charsetByDesignator(strings.CutPrefix(d, "-"))
+	}
+	e.gl, e.gr = ordered.Clamp(s.GL, 0, 3), ordered.Clamp(s.GR, 1, 3)
+	e.tabstops.Clear()
+	for _, x := range s.TabStops {
+		if x >= 0 && x < s.Width {
+			e.tabstops.Set(x)
+		}
+	}
+	e.modifyOtherKeys = s.ModifyOtherKeys
+
+	e.colors = [256]color.Color{}
+	for i, c := range s.Palette {
+		if i >= 0 && i < len(e.colors) {
+			e.colors[i] = parseColorString(c)
+		}
+	}
+	for i, c := range s.SpecialColors {
+		e.specialColors[i] = parseColorString(c)
+	}
+	e.fgColor = parseColorString(s.Foreground)
+	e.bgColor = parseColorString(s.Background)
+	e.curColor = parseColorString(s.CursorColor)
+	if c := parseColorString(s.DefaultForeground); c != nil {
+		e.defaultFg = c
+	}
+	if c := parseColorString(s.DefaultBackground); c != nil {
+		e.defaultBg = c
+	}
+	if c := parseColorString(s.DefaultCursorColor); c != nil {
+		e.defaultCur = c
+	}
+	e.title, e.iconName, e.cwd = s.Title, s.IconName, s.Cwd
+
+	e.damageScreen()
+	e.flushDamage()
+	return nil
+}
+
+// WriteTo 将在真实终端上重新创建快照的字节流写入 w。它实现 [io.WriterTo]。
+//
+// 字节流首先重置终端（[ansi.RIS]），然后恢复颜色、标题、制表位、回滚缓冲区和
+// 屏幕内容、保存的光标、字符集、模式、滚动边距和光标。只有与重置后的默认值不同
+// 的状态才会被写入。默认颜色和光标越过最后一列的状态无法用控制序列表示；交替
+// 屏幕不活动时，它的内容不会被写入；交替屏幕活动时，主屏幕的光标会被恢复到保存
+// 的光标处。
+func (s *Snapshot) WriteTo(w io.Writer) (int64, error) {
+	var sw snapshotWriter
+	sw.write(s)
+	n, err := io.WriteString(w, sw.String())
+	return int64(n), err //nolint:wrapcheck
+}
+
+// snapshotWriter 生成重新创建快照的字节流，并跟踪已经写入的光标样式和超链接，
+// 以免重复写入。
+type snapshotWriter struct {
+	strings.Builder
+	pen   string
+	link  uv.Link
+	shape int
+}
+
+// write 写入重新创建快照 s 的字节流。
+func (w *snapshotWriter) write(s *Snapshot) {
+	w.WriteString(ansi.RIS)
+	w.shape = 1
+
+	// 颜色、标题和工作目录。
+	for _, i := range slices.Sorted(maps.Keys(s.Palette)) {
+		w.WriteString("\x1b]4;" + strconv.Itoa(i) + ";" + s.Palette[i] + "\x07")
+	}
+	for i, c := range s.SpecialColors {
+		if c != "" {
+			w.WriteString("\x1b]5;" + strconv.Itoa(i) + ";" + c + "\x07")
+		}
+	}
+	if s.Foreground != "" {
+		w.WriteString(ansi.SetForegroundColor(s.Foreground))
+	}
+	if s.Background != "" {
+		w.WriteString(ansi.SetBackgroundColor(s.Background))
+	}
+	if s.CursorColor != "" {
+		w.WriteString(ansi.SetCursorColor(s.CursorColor))
+	}
+	if s.Title != "" && s.Title == s.IconName {
+		w.WriteString(ansi.SetIconNameWindowTitle(s.Title))
+	} else {
+		if s.IconName != "" {
+			w.WriteString(ansi.SetIconName(s.IconName))
+		}
+		if s.Title != "" {
+			w.WriteString(ansi.SetWindowTitle(s.Title))
+		}
+	}
+	if s.Cwd != "" {
+		w.WriteString("\x1b]7;" + s.Cwd + "\x07")
+	}
+
+	// 制表位。
+	if !slices.Equal(s.TabStops, tabStopList(uv.DefaultTabStops(s.Width), s.Width)) {
+		w.WriteString(ansi.TabClear(3))
+		for _, x := range s.TabStops {
+			w.WriteString(ansi.CursorPosition(x+1, 1) + ansi.HorizontalTabSet)
+		}
+		w.WriteString(ansi.CursorHomePosition)
+	}
+
+	modes := defaultModes()
+	for _, m := range s.Modes {
+		modes[m.mode()] = m.Setting
+	}
+	lrm := modes.IsSet(ansi.ModeLeftRightMargin)
+	if lrm {
+		// 设置左右边距需要该模式。
+		w.WriteString(ansi.SetMode(ansi.ModeLeftRightMargin))
+	}
+
+	// 屏幕内容。有回滚缓冲区时，写入主屏幕的所有行，使回滚缓冲区中的行被滚出
+	// 屏幕顶部。
+	main := &s.Screens[0]
+	lines := append(slices.Clip(s.Scrollback), main.Lines...)
+	w.writeLines(lines, s.Width, len(s.Scrollback) > 0)
+	w.writeKittyFlags(main.KittyFlags)
+	scr := main
+	if s.AltScreen {
+		scr = &s.Screens[1]
+		if main.Cursor.Hidden {
+			w.WriteString(ansi.ResetMode(ansi.ModeTextCursorEnable))
+		}
+		w.writeMargins(main, s.Width, s.Height, lrm)
+		if modes.IsSet(ansi.ModeAltScreenSaveCursor) {
+			// 切换屏幕时会保存主屏幕的光标。
+			w.writeCursor(main.SavedCursor, 0, 0)
+			w.WriteString(ansi.SetMode(ansi.ModeAltScreenSaveCursor))
+		} else {
+			w.writeSavedCursor(main)
+			w.writeCursor(main.Cursor, 0, 0)
+			w.WriteString(ansi.SetMode(ansi.ModeAltScreen))
+		}
+		w.WriteString(ansi.CursorHomePosition)
+		w.writeLines(scr.Lines, s.Width, false)
+		w.writeKittyFlags(scr.KittyFlags)
+	}
+	w.writeSavedCursor(scr)
+
+	// 字符集。
+	for i, d := range s.Charsets {
+		if d96, is96 := strings.CutPrefix(d, "-"); is96 && i > 0 {
+			w.WriteString("\x1b" + string(rune('-'+i-1)) + d96)
+		} else if d != "" && d != "B" && !is96 {
+			w.WriteString("\x1b" + string(rune('('+i)) + d)
+		}
+	}
+	switch s.GL {
+	case 1:
+		w.WriteByte(ansi.SO)
+	case 2:
+		w.WriteString("\x1bn")
+	case 3:
+		w.WriteString("\x1bo")
+	}
+	switch s.GR {
+	case 1:
+		w.WriteString("\x1b~")
+	case 3:
+		w.WriteString("\x1b|")
+	}
+
+	// 模式。切换屏幕、保存光标和左右边距的模式已经在上面处理；同步输出帧是
+	// 暂时的。
+	var set, reset []ansi.Mode
+	for _, m := range s.Modes {
+		mode := m.mode()
+		switch mode {
+		case ansi.ModeAltScreen, ansi.ModeAltScreenSaveCursor, ansi.ModeSaveCursor, ansi.ModeSynchronizedOutput,
+			ansi.ModeLeftRightMargin:
+			continue
+		}
+		def := defaultModes()[mode]
+		switch {
+		case m.Setting == ansi.ModeSet && def != ansi.ModeSet:
+			set = append(set, mode)
+		case m.Setting == ansi.ModeReset && def == ansi.ModeSet:
+			reset = append(reset, mode)
+		}
+	}
+	if len(set) > 0 {
+		w.WriteString(ansi.SetMode(set...))
+	}
+	if len(reset) > 0 {
+		w.WriteString(ansi.ResetMode(reset...))
+	}
+
+	// 活动屏幕的滚动边距和光标。
+	w.writeMargins(scr, s.Width, s.Height, lrm)
+	var ox, oy int
+	if modes.IsSet(ansi.ModeOrigin) {
+		ox, oy = scr.ScrollLeft, scr.ScrollTop
+	}
+	w.writeCursor(scr.Cursor, ox, oy)
+
+	if s.ModifyOtherKeys != 0 {
+		w.WriteString(ansi.KeyModifierOptions(4, s.ModifyOtherKeys))
+	}
+}
+
+// writeLines 写入从光标所在行开始的行。如果 all 为 false，最后的空行会被省略。
+func (w *snapshotWriter) writeLines(lines []LineSnapshot, width int, all bool) {
+	last := len(lines) - 1
+	if !all {
+		for last >= 0 && len(lines[last].Cells) == 0 && lines[last].Attr == LineSingleWidth {
+			last--
+		}
+	}
+
+	for y := 0; y <= last; y++ {
+		col, cols := 0, lines[y].Attr.columns(width)
+		if lines[y].Attr.IsDoubleWidth() {
+			w.WriteString(lines[y].Attr.String())
+		}
+		for _, c := range lines[y].Cells {
+			if col >= cols {
+				// 双宽的行只能显示一半的列。
+				break
+			}
+			if c.Width == 0 {
+				// 宽字符的占位单元格。
+				continue
+			}
+			w.setPen(c.Style)
+			w.setLink(uv.Link{URL: c.Link, Params: c.LinkParams})
+			if c.Content == "" {
+				w.WriteByte(' ')
+			} else {
+				w.WriteString(c.Content)
+			}
+			col += c.Width
+		}
+		if y == last {
+			break
+		}
+
+		w.setPen("")
+		w.setLink(uv.Link{})
+		if lines[y].Wrapped {
+			// 填满该行，使下一行的第一个字符触发自动换行。
+			w.WriteString(strings.Repeat(" ", max(0, cols-col)))
+			continue
+		}
+		w.WriteString("\r\n")
+	}
+}
+
+// writeMargins 设置屏幕的滚动边距。只有 lrm 为 true 时才设置左右边距。
+func (w *snapshotWriter) writeMargins(scr *ScreenSnapshot, width, height int, lrm bool) {
+	if scr.ScrollTop != 0 || scr.ScrollBottom != height {
+		w.WriteString(ansi.SetTopBottomMargins(scr.ScrollTop+1, scr.ScrollBottom))
+	}
+	if lrm && (scr.ScrollLeft != 0 || scr.ScrollRight != width) {
+		w.WriteString(ansi.SetLeftRightMargins(scr.ScrollLeft+1, scr.ScrollRight))
+	}
+}
+
+// writeCursor 移动光标并设置光标的样式和超链接。光标的位置相对于 (ox, oy)。
+func (w *snapshotWriter) writeCursor(c CursorSnapshot, ox, oy int) {
+	w.WriteString(ansi.CursorPosition(c.X-ox+1, c.Y-oy+1))
+	w.setPen(c.Pen)
+	w.setLink(uv.Link{URL: c.Link, Params: c.LinkParams})
+	shape := int(c.Style)*2 + 1
+	if c.Steady {
+		shape++
+	}
+	if shape != w.shape {
+		w.WriteString(ansi.SetCursorStyle(shape))
+		w.shape = shape
+	}
+}
+
+// writeSavedCursor 恢复屏幕保存的光标。
+func (w *snapshotWriter) writeSavedCursor(scr *ScreenSnapshot) {
+	if scr.SavedCursor == (CursorSnapshot{}) {
+		return
+	}
+	w.writeCursor(scr.SavedCursor, 0, 0)
+	w.WriteString(ansi.DECSC)
+}
+
+// writeKittyFlags 将 Kitty 键盘协议的标志推入当前屏幕的堆栈。
+func (w *snapshotWriter) writeKittyFlags(flags []int) {
+	for _, f := range flags {
+		w.WriteString(ansi.PushKittyKeyboard(f))
+	}
+}
+
+// setPen 设置当前的样式。
+func (w *snapshotWriter) setPen(pen string) {
+	if pen == w.pen {
+		return
+	}
+	w.WriteString(ansi.ResetStyle)
+	w.WriteString(pen)
+	w.pen = pen
+}
+
+// setLink 设置当前的超链接。
+func (w *snapshotWriter) setLink(link uv.Link) {
+	if link == w.link {
+		return
+	}
+	if link.Params == "" {
+		w.WriteString(ansi.SetHyperlink(link.URL))
+	} else {
+		w.WriteString(ansi.SetHyperlink(link.URL, link.Params))
+	}
+	w.link = link
+}
+
+// snapshotScreen 返回屏幕的快照。
+func snapshotScreen(s *Screen) ScreenSnapshot {
+	ss := ScreenSnapshot{
+		Cursor:       snapshotCursor(s.cur),
+		SavedCursor:  snapshotCursor(s.saved),
+		ScrollTop:    s.scroll.Min.Y,
+		ScrollBottom: s.scroll.Max.Y,
+		ScrollLeft:   s.scroll.Min.X,
+		ScrollRight:  s.scroll.Max.X,
+		KittyFlags:   slices.Clone(s.kittyFlags),
+		Lines:        make([]LineSnapshot, s.Height()),
+	}
+	for y := range ss.Lines {
+		ss.Lines[y] = snapshotLine(s.buf.Line(y), s.IsWrapped(y))
+		ss.Lines[y].Attr = s.LineAttr(y)
+	}
+	return ss
+}
+
+// snapshotLine 返回行的快照，省略行尾的空白单元格。
+func snapshotLine(line uv.Line, wrapped bool) LineSnapshot {
+	n := len(line)
+	for n > 0 && line[n-1].Equal(&uv.EmptyCell) {
+		n--
+	}
+	ls := LineSnapshot{Wrapped: wrapped}
+	if n > 0 {
+		ls.Cells = make([]CellSnapshot, n)
+	}
+	for x := range n {
+		c := &line[x]
+		ls.Cells[x] = CellSnapshot{
+			Content:    c.Content,
+			Width:      c.Width,
+			Style:      styleString(c.Style),
+			Link:       c.Link.URL,
+			LinkParams: c.Link.Params,
+		}
+	}
+	return ls
+}
+
+// snapshotCursor 返回光标的快照。
+func snapshotCursor(c Cursor) CursorSnapshot {
+	return CursorSnapshot{
+		X:          c.X,
+		Y:          c.Y,
+		Pen:        styleString(c.Pen),
+		Link:       c.Link.URL,
+		LinkParams: c.Link.Params,
+		Style:      c.Style,
+		Steady:     c.Steady,
+		Hidden:     c.Hidden,
+	}
+}
+
+// restoreScreen 将屏幕恢复到快照的状态。屏幕必须已经被清除。
+func restoreScreen(s *Screen, ss *ScreenSnapshot, sp *styleParser) {
+	for y, l := range ss.Lines {
+		if y >= s.Height() {
+			break
+		}
+		for x, c := range l.Cells {
+			if x >= s.Width() {
+				break
+			}
+			if c.Width == 0 {
+				// 占位单元格由宽字符设置。
+				continue
+			}
+			cell := restoreCell(c, sp)
+			s.buf.SetCell(x, y, &cell)
+		}
+		s.setWrapped(y, l.Wrapped)
+		if l.Attr <= LineDoubleHeightBottom {
+			s.setLineAttr(y, l.Attr)
+		}
+	}
+
+	s.cur = restoreCursor(ss.Cursor, s.Bounds(), sp)
+	s.saved = restoreCursor(ss.SavedCursor, s.Bounds(), sp)
+	s.scroll = uv.Rect(ss.ScrollLeft, ss.ScrollTop, ss.ScrollRight-ss.ScrollLeft, ss.ScrollBottom-ss.ScrollTop).Intersect(s.Bounds())
+	if s.scroll.Empty() {
+		s.scroll = s.Bounds()
+	}
+	s.kittyFlags = append(s.kittyFlags[:0], ss.KittyFlags...)
+}
+
+// restoreLine 返回宽度至少为 width 的行，行尾用空白单元格填充。
+func restoreLine(l LineSnapshot, width int, sp *styleParser) uv.Line {
+	line := make(uv.Line, max(width, len(l.Cells)))
+	for x := range line {
+		if x < len(l.Cells) {
+			line[x] = restoreCell(l.Cells[x], sp)
+		} else {
+			line[x] = uv.EmptyCell
+		}
+	}
+	return line
+}
+
+// restoreCell 返回快照对应的单元格。
+func restoreCell(c CellSnapshot, sp *styleParser) uv.Cell {
+	return uv.Cell{
+		Content: c.Content,
+		Width:   c.Width,
+		Style:   sp.parse(c.Style),
+		Link:    uv.Link{URL: c.Link, Params: c.LinkParams},
+	}
+}
+
+// restoreCursor 返回快照对应的光标，其位置被限制在 bounds 内。
+func restoreCursor(c CursorSnapshot, bounds uv.Rectangle, sp *styleParser) Cursor {
+	return Cursor{
+		Pen:      sp.parse(c.Pen),
+		Link:     uv.Link{URL: c.Link, Params: c.LinkParams},
+		Position: uv.Pos(ordered.Clamp(c.X, 0, bounds.Dx()-1), ordered.Clamp(c.Y, 0, bounds.Dy()-1)),
+		Style:    c.Style,
+		Steady:   c.Steady,
+		Hidden:   c.Hidden,
+	}
+}
+
+// styleString 返回样式的 SGR 序列。默认样式返回空字符串。
+func styleString(st uv.Style) string {
+	if st.IsZero() {
+		return ""
+	}
+	return st.String()
+}
+
+// colorString 以 "#rrggbb" 格式返回颜色。nil 返回空字符串。
+func colorString(c color.Color) string {
+	if c == nil {
+		return ""
+	}
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
+
+// parseColorString 解析 [colorString] 返回的颜色。空字符串和无效的颜色返回 nil。
+func parseColorString(s string) color.Color {
+	if s == "" {
+		return nil
+	}
+	return ansi.XParseColor(s)
+}
+
+// tabStopList 返回制表位所在的列。
+func tabStopList(ts *uv.TabStops, width int) []int {
+	var stops []int
+	for x := range width {
+		if ts.IsStop(x) {
+			stops = append(stops, x)
+		}
+	}
+	return stops
+}
@@ -0,0 +1,151 @@
+package vt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// newSnapshotTerminal returns a terminal with state in every part of a
+// snapshot.
+func newSnapshotTerminal(t *testing.T, alt bool) *Emulator {
+	t.Helper()
+	term := newTestTerminal(t, 10, 4)
+	term.WriteString("\x1b]4;1;#102030\x07\x1b]5;0;#405060\x07")
+	term.WriteString("\x1b]10;#aabbcc\x07\x1b]11;#010203\x07")
+	term.WriteString("\x1b]2;title\x07\x1b]1;icon\x07\x1b]7;file://host/tmp\x07")
+	term.WriteString("\x1b[3g\x1b[1;3H\x1bH\x1b[1;7H\x1bH\x1b[H")
+
+	// Scrollback, a wrapped line, styles, wide characters and hyperlinks.
+	term.WriteString("old 1\r\nold 2\r\n")
+	term.WriteString("\x1b[1;31mred\x1b[m \x1b[38;5;200mpink\x1b[m\r\n")
+	term.WriteString("0123456789wrapped\r\n")
+	term.WriteString("\x1b]8;id=1;https://example.com\x07link\x1b]8;;\x07 世界\r\n")
+	term.WriteString("\x1b[4:3m\x1b[58;2;1;2;3mcurly\x1b[m")
+
+	term.WriteString("\x1b[2;3H\x1b[7m\x1b7\x1b[m")
+	term.WriteString("\x1b[>1u\x1b[>4;2m")
+	term.WriteString("\x1b)0\x1b*%5\x1b/A\x0e\x1b|")
+	term.WriteString("\x1b[4h\x1b[?1h\x1b[?25l\x1b[?2004h\x1b[?1000h\x1b[?1006h")
+	term.WriteString("\x1b[?69h\x1b[2;4r\x1b[2;8s\x1b[?6h")
+	term.WriteString("\x1b[3 q\x1b[32m\x1b]8;;https://pen.example\x07\x1b[2;3H")
+
+	if alt {
+		term.WriteString("\x1b[?1049halt \x1b[44mscreen\x1b[m\x1b[3;2H\x1b[5 q")
+	}
+	return term
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	for _, alt := range []bool{false, true} {
+		term := newSnapshotTerminal(t, alt)
+		want := term.Snapshot()
+
+		binary, err := want.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fromBinary Snapshot
+		if err := fromBinary.UnmarshalBinary(binary); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := json.Marshal(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fromJSON Snapshot
+		if err := json.Unmarshal(data, &fromJSON); err != nil {
+			t.Fatal(err)
+		}
+
+		for name, snap := range map[string]*Snapshot{"binary": &fromBinary, "json": &fromJSON} {
+			restored := newTestTerminal(t, 3, 3)
+			restored.WriteString("junk\x1b[?1049h")
+			if err := restored.Restore(snap); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := restored.Snapshot(); !reflect.DeepEqual(got, want) {
+				t.Errorf("alt=%v %s: restored snapshot differs\ngot:  %+v\nwant: %+v", alt, name, got, want)
+			}
+			if got, want := restored.Render(), term.Render(); got != want {
+				t.Errorf("alt=%v %s: Render() = %q, want %q", alt, name, got, want)
+			}
+
+			// The restored terminal keeps working like the original one.
+			orig := newSnapshotTerminal(t, alt)
+			orig.WriteString("xy\r\n\x1b(0q")
+			restored.WriteString("xy\r\n\x1b(0q")
+			if got, want := restored.Render(), orig.Render(); got != want {
+				t.Errorf("alt=%v %s: after writing, Render() = %q, want %q", alt, name, got, want)
+			}
+		}
+	}
+}
+
+func TestSnapshotWriteTo(t *testing.T) {
+	for _, alt := range []bool{false, true} {
+		term := newSnapshotTerminal(t, alt)
+		want := term.Snapshot()
+
+		var buf bytes.Buffer
+		if _, err := want.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		replayed := newTestTerminal(t, 10, 4)
+		replayed.Write(buf.Bytes())
+		got := replayed.Snapshot()
+
+		if alt {
+			// The main screen cursor is restored to the saved cursor.
+			want.Screens[0].Cursor = want.Screens[0].SavedCursor
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("alt=%v: replayed snapshot differs\ngot:  %+v\nwant: %+v", alt, got, want)
+		}
+		if got, want := replayed.Render(), term.Render(); got != want {
+			t.Errorf("alt=%v: Render() = %q, want %q", alt, got, want)
+		}
+	}
+}
+
+func TestSnapshotWriteToMinimal(t *testing.T) {
+	term := newTestTerminal(t, 10, 4)
+	term.WriteString("hi")
+
+	var buf bytes.Buffer
+	if _, err := term.Snapshot().WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "\x1bchi\x1b[1;3H"; got != want {
+		t.Errorf("WriteTo() = %q, want %q", got, want)
+	}
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	term := newTestTerminal(t, 10, 4)
+	snap := term.Snapshot()
+
+	snap.Version = SnapshotVersion + 1
+	if err := term.Restore(snap); !errors.Is(err, ErrSnapshotVersion) {
+		t.Errorf("Restore() error = %v, want %v", err, ErrSnapshotVersion)
+	}
+	data, err := snap.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := new(Snapshot).UnmarshalBinary(data); !errors.Is(err, ErrSnapshotVersion) {
+		t.Errorf("UnmarshalBinary() error = %v, want %v", err, ErrSnapshotVersion)
+	}
+	if err := new(Snapshot).UnmarshalBinary([]byte("garbage")); !errors.Is(err, ErrSnapshotFormat) {
+		t.Errorf("UnmarshalBinary() error = %v, want %v", err, ErrSnapshotFormat)
+	}
+
+	snap.Version, snap.Width = SnapshotVersion, 0
+	if err := term.Restore(snap); !errors.Is(err, ErrSnapshotFormat) {
+		t.Errorf("Restore() error = %v, want %v", err, ErrSnapshotFormat)
+	}
+}
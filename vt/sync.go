@@ -0,0 +1,58 @@
+package vt
+
+import (
+	"time"
+
+	"github.com/purpose168/charm-experimental-packages-cn/ansi"
+)
+
+// DefaultSyncTimeout 是同步输出帧的默认超时时间。如果应用程序在此时间内没有
+// 结束帧，帧会被视为已经结束，以免终端因为应用程序崩溃而停止更新。
+const DefaultSyncTimeout = time.Second
+
+// SyncTimeout 返回同步输出帧的超时时间。
+func (e *Emulator) SyncTimeout() time.Duration {
+	return e.syncTimeout
+}
+
+// SetSyncTimeout 设置同步输出帧的超时时间。小于或等于零的值会被忽略。
+func (e *Emulator) SetSyncTimeout(d time.Duration) {
+	if d > 0 {
+		e.syncTimeout = d
+	}
+}
+
+// SyncFrameOpen 报告同步输出（模式 2026）帧是否仍然打开。当应用程序设置该
+// 模式时帧打开，直到应用程序重置该模式或超时后结束。
+//
+// 帧打开时，[Emulator.Touched] 不会报告任何被修改的行，嵌入方应推迟重绘，
+// 以免显示绘制了一半的帧。帧结束后，帧内的所有修改会一次性报告。超时后，
+// 模式 2026 会在下一次写入时被重置；[SafeEmulator] 还会在超时的时刻重置该
+// 模式并将保留的损坏传递给 [Callbacks.Damage]。
+func (e *Emulator) SyncFrameOpen() bool {
+	return e.isModeSet(ansi.ModeSynchronizedOutput) &&
+		time.Since(e.syncStart) < e.syncTimeout
+}
+
+// setSyncMode 在同步输出模式改变时记录帧的开始时间。重复设置该模式不会
+// 延长已经打开的帧。
+func (e *Emulator) setSyncMode(old, setting ansi.ModeSetting) {
+	if setting.IsSet() && !old.IsSet() {
+		e.syncStart = time.Now()
+	}
+}
+
+// syncDeadline 返回当前同步输出帧超时的时间。
+func (e *Emulator) syncDeadline() time.Time {
+	return e.syncStart.Add(e.syncTimeout)
+}
+
+// expireSyncFrame 结束已经超时的同步输出帧：重置模式 2026，使 DECRQM 报告
+// 帧已经结束，并且再次设置该模式会打开新的帧，然后传递帧内保留的损坏。
+func (e *Emulator) expireSyncFrame() {
+	if !e.isModeSet(ansi.ModeSynchronizedOutput) || e.SyncFrameOpen() {
+		return
+	}
+	e.setMode(ansi.ModeSynchronizedOutput, ansi.ModeReset)
+	e.flushDamage()
+}
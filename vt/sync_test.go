@@ -0,0 +1,97 @@
+package vt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSynchronizedOutput(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	query := func() string {
+		return readInput(t, term, func() { term.WriteString("\x1b[?2026$p") })
+	}
+
+	if got := query(); got != "\x1b[?2026;2$y" {
+		t.Errorf("DECRQM before frame = %q", got)
+	}
+
+	term.WriteString("\x1b[?2026h")
+	if got := query(); got != "\x1b[?2026;1$y" {
+		t.Errorf("DECRQM in frame = %q", got)
+	}
+	if !term.SyncFrameOpen() {
+		t.Fatal("expected frame to be open")
+	}
+
+	term.WriteString("hello")
+	if got := term.Touched(); got != nil {
+		t.Errorf("touched lines reported while frame is open: %v", got)
+	}
+
+	term.WriteString("\x1b[?2026l")
+	if term.SyncFrameOpen() {
+		t.Error("expected frame to be closed")
+	}
+	if got := term.Touched(); len(got) == 0 || got[0] == nil {
+		t.Errorf("touched lines were not released after the frame: %v", got)
+	}
+}
+
+func TestSynchronizedOutputTimeout(t *testing.T) {
+	term := newTestTerminal(t, 10, 2)
+	term.SetSyncTimeout(10 * time.Millisecond)
+
+	term.WriteString("\x1b[?2026hhello")
+	if !term.SyncFrameOpen() {
+		t.Fatal("expected frame to be open")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if term.SyncFrameOpen() {
+		t.Error("expected frame to time out")
+	}
+	if got := term.Touched(); len(got) == 0 {
+		t.Error("touched lines were not released after the timeout")
+	}
+
+	// The mode is reset once the frame timed out, so setting it again opens a
+	// new frame.
+	got := readInput(t, term, func() { term.WriteString("\x1b[?2026$p") })
+	if got != "\x1b[?2026;2$y" {
+		t.Errorf("DECRQM after timeout = %q", got)
+	}
+	term.WriteString("\x1b[?2026h")
+	if !term.SyncFrameOpen() {
+		t.Error("expected a new frame to open after the timeout")
+	}
+}
+
+func TestSynchronizedOutputTimeoutDamage(t *testing.T) {
+	term := NewSafeEmulator(10, 2)
+	term.SetSyncTimeout(10 * time.Millisecond)
+	damaged := make(chan struct{}, 1)
+	term.SetCallbacks(Callbacks{Damage: func(Damage) {
+		select {
+		case damaged <- struct{}{}:
+		default:
+		}
+	}})
+
+	term.WriteString("\x1b[?2026hhello")
+	select {
+	case <-damaged:
+		t.Fatal("damage reported while the frame is open")
+	default:
+	}
+
+	// The held damage is reported when the frame times out, without another
+	// write.
+	select {
+	case <-damaged:
+	case <-time.After(time.Second):
+		t.Fatal("damage was not reported after the timeout")
+	}
+	if term.SyncFrameOpen() {
+		t.Error("expected frame to time out")
+	}
+}
@@ -1,6 +1,7 @@
 package vt
 
 import (
+	"unicode"
 	"unicode/utf8"
 
 	uv "github.com/charmbracelet/ultraviolet"
@@ -26,18 +27,79 @@ func (e *Emulator) flushGrapheme() {
 		return
 	}
 
-	// XXX: 我们在这里始终使用 [ansi.GraphemeWidth] 来报告准确的宽度，
-	// 由调用者决定如何处理 Unicode 与非 Unicode 模式。
-	method := ansi.GraphemeWidth
+	// 如果设置了 [ansi.ModeUnicodeCore]，字形簇占据一个单元格，宽度按字形簇
+	// 计算；否则每个字符按 wcwidth 单独占据单元格，只有零宽度的字符（例如组合
+	// 字符）与前一个字符合并。
+	clusters := e.isModeSet(ansi.ModeUnicodeCore)
 	graphemes := string(e.grapheme)
 	for len(graphemes) > 0 {
-		cluster, width := ansi.FirstGraphemeCluster(graphemes, method)
-		e.handleGrapheme(cluster, width)
+		var (
+			cluster string
+			width   int
+		)
+		if clusters {
+			cluster, width = ansi.FirstGraphemeCluster(graphemes, ansi.GraphemeWidth)
+		} else {
+			cluster, width = firstWcCluster(graphemes)
+		}
+		if width == 0 {
+			e.appendToPreviousCell(cluster)
+		} else {
+			e.handleGrapheme(cluster, width)
+		}
 		graphemes = graphemes[len(cluster):]
 	}
 	e.grapheme = e.grapheme[:0] // 重置字形缓冲区。
 }
 
+// firstWcCluster 返回 s 中的第一个字符及其后的所有零宽度字符，以及第一个字符
+// 的 wcwidth 宽度。
+func firstWcCluster(s string) (string, int) {
+	r, n := utf8.DecodeRuneInString(s)
+	width := 0
+	if !isZeroWidth(r) {
+		width = ansi.StringWidthWc(string(r))
+	}
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !isZeroWidth(r) {
+			break
+		}
+		n += size
+	}
+	return s[:n], width
+}
+
+// isZeroWidth 报告字符 r 是否是零宽度的，例如组合字符、变体选择符和零宽度
+// 连接符。
+func isZeroWidth(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) || ansi.StringWidthWc(string(r)) == 0
+}
+
+// appendToPreviousCell 将零宽度的内容（例如组合字符）追加到光标前写入的单元格，
+// 而不移动光标。如果没有这样的单元格，内容会被丢弃。
+func (e *Emulator) appendToPreviousCell(content string) {
+	x, y := e.scr.CursorPosition()
+	if !e.atPhantom {
+		x--
+	}
+	// 跳过宽字符的占位单元格。
+	for x > 0 {
+		if c := e.scr.CellAt(x, y); c == nil || c.Width != 0 {
+			break
+		}
+		x--
+	}
+
+	c := e.scr.CellAt(x, y)
+	if x < 0 || c == nil || c.Width == 0 || c.Content == "" {
+		return
+	}
+	c = c.Clone()
+	c.Content += content
+	e.scr.SetCell(x, y, c)
+}
+
 // handleGrapheme 处理 UTF-8 字形。
 func (e *Emulator) handleGrapheme(content string, width int) {
 	awm := e.isModeSet(ansi.ModeAutoWrap)
@@ -50,6 +112,8 @@ func (e *Emulator) handleGrapheme(content string, width int) {
 
 	x, y := e.scr.CursorPosition()
 	if e.atPhantom && awm {
+		// 记录该行以自动换行结束，以便在调整大小时重新换行。
+		e.scr.setWrapped(y, true)
 		// 将光标向下移动，类似于 [Terminal.linefeed]，但不尊重 [ansi.LNM] 模式。
 		// 这将重置幻影状态，即待换行状态。
 		e.index()
@@ -57,26 +121,25 @@ func (e *Emulator) handleGrapheme(content string, width int) {
 		x = 0
 	}
 
-	// 处理字符集映射
-	if len(content) == 1 { //nolint:nestif
+	// 处理字符集映射。解析器将 0x80 以上的字节按 UTF-8 解码，因此 GR 不会
+	// 收到单字节输入：锁定移位 LS1R、LS2R 和 LS3R 调用到 GR 的字符集不影响
+	// 输出，只由 DECCIR 报告。解码得到的 Latin-1 字符保持不变，只有通过单次
+	// 移位选择的字符才会映射。
+	if r, size := utf8.DecodeRuneInString(content); size == len(content) && (r < 0x80 || (r >= 0xa0 && r <= 0xff)) {
 		var charset CharSet
-		c := content[0]
 		if e.gsingle > 1 && e.gsingle < 4 {
 			charset = e.charsets[e.gsingle]
-			e.gsingle = 0
-		} else if c < 128 {
+		} else if r < 0x80 {
 			charset = e.charsets[e.gl]
-		} else {
-			charset = e.charsets[e.gr]
 		}
 
-		if charset != nil {
-			if r, ok := charset[c]; ok {
-				cell.Content = r
-				cell.Width = 1
-			}
+		if m, ok := charset[byte(r)&0x7f]; ok {
+			cell.Content = m
+			cell.Width = 1
 		}
 	}
+	// 单次移位只影响下一个字符。
+	e.gsingle = 0
 
 	if cell.Width == 1 && len(content) == 1 {
 		e.lastChar, _ = utf8.DecodeRuneInString(content)
@@ -85,7 +148,7 @@ func (e *Emulator) handleGrapheme(content string, width int) {
 	e.scr.SetCell(x, y, &cell)
 
 	// 处理行尾的幻影状态
-	e.atPhantom = awm && x >= e.scr.Width()-1
+	e.atPhantom = awm && x >= e.scr.lineWidth(y)-1
 	if !e.atPhantom {
 		x += cell.Width
 	}
@@ -0,0 +1,89 @@
+package vt
+
+import (
+	"testing"
+
+	"github.com/purpose168/charm-experimental-packages-cn/ansi"
+)
+
+const (
+	man    = "\U0001F468"
+	woman  = "\U0001F469"
+	zwj    = "‍"
+	family = man + zwj + woman
+	heart  = "❤️"
+)
+
+func TestGraphemeClustering(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   string
+		unicode bool
+		cells   []string
+		cursor  int
+	}{
+		{"zwj wcwidth", family + "x", false, []string{man + zwj, "", woman, "", "x"}, 5},
+		{"zwj grapheme", family + "x", true, []string{family, "", "x"}, 3},
+		{"emoji presentation wcwidth", heart + "x", false, []string{heart, "x"}, 2},
+		{"emoji presentation grapheme", heart + "x", true, []string{heart, "", "x"}, 3},
+		{"combining wcwidth", "éx", false, []string{"é", "x"}, 2},
+		{"combining grapheme", "éx", true, []string{"é", "x"}, 2},
+		{"combining after wide", "世́x", false, []string{"世́", "", "x"}, 3},
+		{"combining at line end", "abcdeé", false, []string{"a", "b", "c", "d", "e", "é"}, 5},
+		{"combining at line start", "́x", true, []string{"x"}, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			term := newTestTerminal(t, 6, 1)
+			if tc.unicode {
+				term.WriteString("\x1b[?2027h")
+			}
+			term.WriteString(tc.input)
+			for x, want := range tc.cells {
+				c := term.CellAt(x, 0)
+				if c == nil {
+					t.Fatalf("cell %d is nil", x)
+				}
+				if c.Content != want {
+					t.Errorf("cell %d = %q, want %q", x, c.Content, want)
+				}
+			}
+			if x, _ := term.scr.CursorPosition(); x != tc.cursor {
+				t.Errorf("cursor x = %d, want %d", x, tc.cursor)
+			}
+		})
+	}
+}
+
+func TestGraphemeClusteringMode(t *testing.T) {
+	term := newTestTerminal(t, 10, 1)
+	query := func() string {
+		return readInput(t, term, func() { term.WriteString("\x1b[?2027$p") })
+	}
+
+	if got, want := query(), "\x1b[?2027;2$y"; got != want {
+		t.Errorf("DECRQM = %q, want %q", got, want)
+	}
+	term.WriteString(heart)
+	term.WriteString("\x1b[?2027h")
+	if got, want := query(), "\x1b[?2027;1$y"; got != want {
+		t.Errorf("DECRQM = %q, want %q", got, want)
+	}
+	if got := term.WidthMethod(); got != ansi.GraphemeWidth {
+		t.Errorf("WidthMethod() = %v, want grapheme width", got)
+	}
+	term.WriteString(heart)
+
+	// Switching the mode only affects text written afterwards.
+	if c := term.CellAt(0, 0); c.Content != heart || c.Width != 1 {
+		t.Errorf("cell 0 = %q (width %d), want width 1", c.Content, c.Width)
+	}
+	if c := term.CellAt(1, 0); c.Content != heart || c.Width != 2 {
+		t.Errorf("cell 1 = %q (width %d), want width 2", c.Content, c.Width)
+	}
+
+	term.WriteString("\x1b[?2027l")
+	if got := term.WidthMethod(); got != ansi.WcWidth {
+		t.Errorf("WidthMethod() = %v, want wcwidth", got)
+	}
+}